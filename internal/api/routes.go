@@ -3,38 +3,132 @@ package api
 import (
 	"user-management/internal/handler"
 	"user-management/internal/middleware"
+	"user-management/internal/models"
+	"user-management/pkg/metrics"
 
 	"github.com/gin-contrib/pprof"
+	"github.com/gin-gonic/gin"
 )
 
-func (s *Server) SetupRoutes(authHandler *handler.AuthHandler, userHandler *handler.UserHandler) {
+func (s *Server) SetupRoutes(
+	authHandler *handler.AuthHandler,
+	userHandler *handler.UserHandler,
+	samlHandler *handler.SAMLHandler,
+	magicLinkHandler *handler.MagicLinkHandler,
+	sessionHandler *handler.SessionHandler,
+	adminHandler *handler.AdminHandler,
+	exportHandler *handler.DataExportHandler,
+	attributeHandler *handler.AttributeHandler,
+	avatarHandler *handler.AvatarHandler,
+	emailChangeHandler *handler.EmailChangeHandler,
+	phoneHandler *handler.PhoneHandler,
+	consentHandler *handler.ConsentHandler,
+	auditHandler *handler.AuditHandler,
+	webhookHandler *handler.WebhookHandler,
+) {
 	// Register pprof routes
 	if s.cfg.App.Environment != "production" {
 		pprof.Register(s.router)
 	}
 
+	// Metrics on the API port, unless they have a port of their own
+	if s.cfg.Metrics.Enabled && s.cfg.Metrics.Port == "" {
+		s.router.GET("/metrics", middleware.MetricsAuth(s.cfg.Metrics.Token), gin.WrapH(metrics.Handler()))
+	}
+
+	// Probes
+	s.router.GET("/livez", s.livez)
+	s.router.GET("/readyz", s.readyz)
+
 	api := s.router.Group("/api/v1")
 
-	// Health check
-	api.GET("/health", s.healthCheck)
+	// Kept for existing monitors; same as /readyz
+	api.GET("/health", s.readyz)
 
 	// Public routes
 	public := api.Group("/auth")
+	public.Use(s.rateLimit("auth"))
 	{
-		public.POST("/signin", authHandler.SignIn)
-		public.POST("/signup", authHandler.Signup)
+		public.POST("/signin", s.rateLimit("signin"), authHandler.SignIn)
+		public.POST("/signup", s.rateLimit("signup"), authHandler.Signup)
+		public.GET("/username-availability", authHandler.UsernameAvailability)
+		public.POST("/magic-link", magicLinkHandler.Request)
+		public.POST("/magic-link/verify", magicLinkHandler.Verify)
+		public.POST("/restore", authHandler.RestoreAccount)
+		public.POST("/email-change/confirm", emailChangeHandler.Confirm)
+		public.POST("/email-change/cancel", emailChangeHandler.Cancel)
+	}
+
+	// Signed data export downloads
+	api.GET("/exports/:id/download", exportHandler.Download)
+
+	// Locally stored blobs such as avatars
+	if s.blobDir != "" {
+		s.router.StaticFS("/blobs", gin.Dir(s.blobDir, false))
+	}
+
+	// SAML SSO routes
+	if samlHandler != nil {
+		saml := public.Group("/saml")
+		{
+			saml.GET("", samlHandler.Discover)
+			saml.GET("/:org/metadata", samlHandler.Metadata)
+			saml.GET("/:org/login", samlHandler.Login)
+			saml.POST("/:org/acs", samlHandler.ACS)
+		}
 	}
 
 	// Protected routes
 	protected := api.Group("/")
-	protected.Use(middleware.Auth(s.jwtManager))
+	protected.Use(
+		middleware.Auth(s.jwtManager, s.sessionService, s.userService),
+		middleware.StepUpEndpoint(public.BasePath()+"/signin"),
+	)
 	{
 		// User routes
 		users := protected.Group("/users")
+		users.Use(s.rateLimit("users"))
 		{
 			users.GET("", userHandler.ListUsers)
 			users.GET("/:id", userHandler.GetUser)
 			users.PUT("/:id", userHandler.UpdateUser)
+			users.DELETE("/:id", middleware.StepUp(s.cfg.Auth.StepUpMaxAge, s.cfg.Auth.StepUpMFAMaxAge), userHandler.DeleteUser)
+			users.GET("/:id/sessions", sessionHandler.ListSessions)
+			users.DELETE("/:id/sessions/:sid", sessionHandler.RevokeSession)
+			users.POST("/:id/export", middleware.StepUp(s.cfg.Auth.StepUpMaxAge, s.cfg.Auth.StepUpMFAMaxAge), exportHandler.RequestExport)
+			users.GET("/:id/export/:export_id", exportHandler.GetExport)
+			users.GET("/:id/attributes", attributeHandler.GetAttributes)
+			users.PATCH("/:id/attributes", attributeHandler.UpdateAttributes)
+			users.PUT("/:id/avatar", avatarHandler.Upload)
+			users.DELETE("/:id/avatar", avatarHandler.Remove)
+			users.POST("/:id/phone/send-code", phoneHandler.SendCode)
+			users.POST("/:id/phone/verify", phoneHandler.Verify)
+			users.GET("/:id/consents", consentHandler.List)
+			users.POST("/:id/consents", consentHandler.Record)
+		}
+
+		// Admin routes
+		admin := protected.Group("/admin")
+		admin.Use(middleware.RequireRole(models.RoleAdmin), s.rateLimit("admin"))
+		{
+			admin.POST("/users/:id/impersonate", middleware.StepUp(s.cfg.Auth.StepUpMaxAge, s.cfg.Auth.StepUpMFAMaxAge), adminHandler.Impersonate)
+			admin.POST("/users/:id/suspend", adminHandler.SuspendUser)
+			admin.POST("/users/:id/reactivate", adminHandler.ReactivateUser)
+			admin.POST("/users/:id/disable", adminHandler.DisableUser)
+			admin.POST("/users/:id/restore", adminHandler.RestoreUser)
+			admin.GET("/audit", auditHandler.List)
+			admin.GET("/audit/verify", auditHandler.Verify)
+			admin.GET("/webhooks", webhookHandler.ListSubscriptions)
+			admin.POST("/webhooks", webhookHandler.CreateSubscription)
+			admin.GET("/webhooks/:id", webhookHandler.GetSubscription)
+			admin.PUT("/webhooks/:id", webhookHandler.UpdateSubscription)
+			admin.DELETE("/webhooks/:id", webhookHandler.DeleteSubscription)
+			admin.POST("/webhooks/:id/rotate-secret", webhookHandler.RotateSecret)
+			admin.GET("/webhooks/:id/deliveries", webhookHandler.ListDeliveries)
+			admin.POST("/webhooks/:id/replay", webhookHandler.Replay)
+			admin.GET("/attributes", attributeHandler.ListDefinitions)
+			admin.PUT("/attributes/:key", attributeHandler.PutDefinition)
+			admin.DELETE("/attributes/:key", attributeHandler.DeleteDefinition)
 		}
 	}
 }
@@ -9,28 +9,52 @@ import (
 	"os/signal"
 	"syscall"
 	"time"
+	"user-management/internal/auth"
 	"user-management/internal/config"
 	"user-management/internal/handler"
 	"user-management/internal/middleware"
 	"user-management/internal/repository"
 	"user-management/internal/service"
 	"user-management/internal/utils"
+	"user-management/pkg/blobstore"
 	"user-management/pkg/cache"
 	"user-management/pkg/database"
+	"user-management/pkg/events"
+	"user-management/pkg/health"
 	"user-management/pkg/logger"
+	"user-management/pkg/mailer"
+	"user-management/pkg/metrics"
+	"user-management/pkg/ratelimit"
+	"user-management/pkg/sms"
+	"user-management/pkg/tracing"
 
 	"github.com/gin-gonic/gin"
 	"golang.org/x/crypto/bcrypt"
 )
 
+const migrationsDir = "internal/migration/migrations"
+
 type Server struct {
-	cfg        *config.Config
-	router     *gin.Engine
-	server     *http.Server
-	db         *database.Database
-	cache      cache.Cache
-	jwtManager *utils.JWTManager
-	logger     *logger.Logger
+	cfg            *config.Config
+	router         *gin.Engine
+	server         *http.Server
+	metricsServer  *http.Server
+	db             *database.Database
+	cache          cache.Cache
+	jwtManager     *utils.JWTManager
+	sessionService service.SessionService
+	userService    service.UserService
+	purger         *service.AccountPurger
+	outboxPruner   *service.OutboxPruner
+	exportService  service.DataExportService
+	webhookService service.WebhookService
+	eventRelay     *service.EventRelay
+	publisher      events.EventPublisher
+	tracer         *tracing.Provider
+	limiter        ratelimit.Limiter
+	health         *health.Registry
+	blobDir        string
+	logger         *logger.Logger
 }
 
 func NewServer(cfg *config.Config) *Server {
@@ -48,8 +72,19 @@ func NewServer(cfg *config.Config) *Server {
 
 	// Apply global middleware
 	router.Use(
-		middleware.Recovery(logger),
-		middleware.Logging(logger),
+		middleware.RequestID(),
+		middleware.Recovery(cfg.Server.ProblemDetails),
+		middleware.RequestContext(),
+	)
+	if cfg.Tracing.Enabled {
+		router.Use(middleware.Tracing(cfg.Tracing.ServiceName))
+	}
+	router.Use(middleware.Logging(logger))
+	if cfg.Metrics.Enabled {
+		router.Use(middleware.Metrics())
+	}
+	router.Use(
+		middleware.Errors(cfg.Server.ProblemDetails),
 		middleware.CORS(cfg.Server.CORS),
 		gin.Recovery(),
 	)
@@ -57,11 +92,34 @@ func NewServer(cfg *config.Config) *Server {
 	return &Server{
 		cfg:    cfg,
 		router: router,
+		health: health.NewRegistry(cfg.Health.CheckTimeout),
 		logger: logger,
 	}
 }
 
 func (s *Server) Setup() error {
+	// Forwarding headers are only believed from known proxies, so clients cannot choose the IP that
+	// rate limits and audit entries see
+	if err := s.router.SetTrustedProxies(s.cfg.Server.TrustedProxies); err != nil {
+		return fmt.Errorf("invalid trusted proxies: %w", err)
+	}
+
+	// Initialize tracing first so the database and cache can be instrumented
+	if s.cfg.Tracing.Enabled {
+		tracer, err := tracing.NewProvider(context.Background(), tracing.Options{
+			ServiceName:    s.cfg.Tracing.ServiceName,
+			ServiceVersion: s.cfg.App.Version,
+			Environment:    s.cfg.App.Environment,
+			Exporter:       s.cfg.Tracing.Exporter,
+			Endpoint:       s.cfg.Tracing.OTLPEndpoint,
+			SampleRatio:    s.cfg.Tracing.SampleRatio,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to initialize tracing: %w", err)
+		}
+		s.tracer = tracer
+	}
+
 	// Initialize database
 	db, err := database.NewDatabase(&s.cfg.Database)
 	if err != nil {
@@ -69,6 +127,12 @@ func (s *Server) Setup() error {
 	}
 	s.db = db
 
+	if s.cfg.Metrics.Enabled {
+		if err := s.db.RegisterMetrics(metrics.Registry); err != nil {
+			return fmt.Errorf("failed to register database metrics: %w", err)
+		}
+	}
+
 	// Initialize Cache
 	redisCache, err := cache.NewRedisCache(s.cfg.Redis.URL)
 	if err != nil {
@@ -76,27 +140,144 @@ func (s *Server) Setup() error {
 	}
 	s.cache = redisCache
 
+	if s.tracer != nil {
+		if err := s.db.InstrumentTracing(); err != nil {
+			return fmt.Errorf("failed to instrument database: %w", err)
+		}
+		if err := redisCache.InstrumentTracing(); err != nil {
+			return fmt.Errorf("failed to instrument cache: %w", err)
+		}
+	}
+
+	// Initialize event publisher
+	s.publisher, err = s.buildEventPublisher(redisCache)
+	if err != nil {
+		return fmt.Errorf("failed to initialize event publisher: %w", err)
+	}
+
 	// Run migrations
-	if err := s.db.RunMigrations("internal/migration/migrations"); err != nil {
+	if err := s.db.RunMigrations(migrationsDir); err != nil {
 		return fmt.Errorf("failed to run migrations: %w", err)
 	}
 
+	if err := s.registerHealthChecks(redisCache); err != nil {
+		return fmt.Errorf("failed to register health checks: %w", err)
+	}
+
 	// Initialize JWT manager
 	s.jwtManager = utils.NewJWTManager(&s.cfg.JWT)
 	passwordManager := utils.NewPasswordManager(bcrypt.DefaultCost)
 
+	// Initialize mail sender
+	mailSender := s.buildMailSender()
+	smsSender := s.buildSMSSender()
+
+	// Initialize blob storage
+	blobStore, err := s.buildBlobStore()
+	if err != nil {
+		return fmt.Errorf("failed to initialize blob storage: %w", err)
+	}
+
 	// Initialize repository
 	userRepo := repository.NewUserRepository(s.db.DB)
+	magicLinkRepo := repository.NewMagicLinkRepository(s.db.DB)
+	sessionRepo := repository.NewSessionRepository(s.db.DB)
+	exportRepo := repository.NewDataExportRepository(s.db.DB)
+	attributeRepo := repository.NewAttributeDefinitionRepository(s.db.DB)
+	emailChangeRepo := repository.NewEmailChangeRepository(s.db.DB)
+	auditRepo := repository.NewAuditRepository(s.db.DB)
+	webhookRepo := repository.NewWebhookRepository(s.db.DB)
+	outboxRepo := repository.NewOutboxRepository(s.db.DB)
+	consentRepo := repository.NewConsentRepository(s.db.DB)
+
+	// Initialize authenticators
+	authenticator := s.buildAuthenticator(userRepo, passwordManager)
+	directories := auth.DomainManagers{authenticator}
+
+	var samlProvider *auth.SAMLProvider
+	if s.cfg.Auth.SAML.Enabled {
+		samlProvider, err = auth.NewSAMLProvider(context.Background(), &s.cfg.Auth.SAML, s.cache)
+		if err != nil {
+			return fmt.Errorf("failed to initialize saml: %w", err)
+		}
+		directories = append(directories, samlProvider)
+	}
 
 	// Initialize services
-	userService := service.NewUserService(userRepo, s.jwtManager, passwordManager, s.cache)
+	emailPolicy, err := service.NewEmailPolicy(&s.cfg.Email)
+	if err != nil {
+		return fmt.Errorf("failed to initialize email policy: %w", err)
+	}
+	auditService := service.NewAuditService(auditRepo, &s.cfg.Audit)
+	s.sessionService = service.NewSessionService(sessionRepo, s.cache, auditService, s.cfg.JWT.RefreshExpiration)
+	avatarService := service.NewAvatarService(userRepo, blobStore, s.cache, s.cfg.Avatar.MaxBytes)
+	emailChangeService := service.NewEmailChangeService(
+		&s.cfg.Auth.EmailChange, emailChangeRepo, userRepo, s.sessionService, mailSender, s.cache, emailPolicy,
+	)
+	userService := service.NewUserService(
+		userRepo, s.jwtManager, passwordManager, authenticator, directories, s.sessionService, s.cache, avatarService,
+		emailChangeService, emailPolicy, auditService, s.cfg.Deletion.GracePeriod,
+	)
+	if s.tracer != nil {
+		userService = service.NewTracedUserService(userService)
+	}
+	s.userService = userService
+	s.purger = service.NewAccountPurger(userRepo, avatarService, &s.cfg.Deletion, s.logger)
+	s.exportService = service.NewDataExportService(
+		exportRepo, userRepo, &s.cfg.Export, s.logger,
+		service.NewProfileExportSource(userRepo),
+		service.NewIdentityExportSource(userRepo),
+		service.NewConsentExportSource(consentRepo),
+		service.NewSessionExportSource(sessionRepo),
+		service.NewLoginHistoryExportSource(sessionRepo),
+		service.NewAuditExportSource(auditRepo),
+	)
+	magicLinkService := service.NewMagicLinkService(
+		&s.cfg.Auth.MagicLink, magicLinkRepo, userRepo, userService, mailSender, s.cache, emailPolicy,
+	)
+	attributeService := service.NewAttributeService(attributeRepo, userRepo, s.cache, auditService)
+	consentService := service.NewConsentService(&s.cfg.Consent, consentRepo, auditService)
+	phoneService := service.NewPhoneVerificationService(&s.cfg.Auth.Phone, userRepo, smsSender, s.cache)
+	s.webhookService = service.NewWebhookService(webhookRepo, auditService, &s.cfg.Webhook, s.logger)
+	s.outboxPruner = service.NewOutboxPruner(outboxRepo, &s.cfg.Events, s.logger)
+	if s.publisher != nil {
+		s.eventRelay = service.NewEventRelay(outboxRepo, s.publisher, &s.cfg.Events, s.logger)
+	}
 
 	// Initialize handlers
 	authHandler := handler.NewAuthHandler(userService, s.jwtManager)
-	userHandler := handler.NewUserHandler(userService)
+	userHandler := handler.NewUserHandler(userService, attributeService, s.cfg.Auth.StepUpMaxAge, s.cfg.Auth.StepUpMFAMaxAge)
+	avatarHandler := handler.NewAvatarHandler(avatarService, s.cfg.Avatar.MaxBytes)
+	emailChangeHandler := handler.NewEmailChangeHandler(emailChangeService)
+	phoneHandler := handler.NewPhoneHandler(phoneService)
+	consentHandler := handler.NewConsentHandler(consentService)
+	sessionHandler := handler.NewSessionHandler(s.sessionService)
+	adminHandler := handler.NewAdminHandler(userService)
+	auditHandler := handler.NewAuditHandler(auditService)
+	webhookHandler := handler.NewWebhookHandler(s.webhookService)
+	exportHandler := handler.NewDataExportHandler(s.exportService)
+	attributeHandler := handler.NewAttributeHandler(attributeService)
+	magicLinkHandler := handler.NewMagicLinkHandler(
+		magicLinkService, s.cfg.Auth.MagicLink.TTL, s.cfg.App.Environment == "production",
+	)
+
+	var samlHandler *handler.SAMLHandler
+	if samlProvider != nil {
+		samlHandler = handler.NewSAMLHandler(userService, samlProvider)
+	}
+
+	if s.cfg.RateLimit.Enabled {
+		s.limiter = ratelimit.NewFallbackLimiter(
+			ratelimit.NewCacheLimiter(s.cache),
+			ratelimit.NewMemoryLimiter(),
+			func(err error) {
+				s.logger.Warn().Err(err).Msg("Redis rate limiter failed, using in-memory limits")
+			},
+		)
+	}
 
 	// Setup routes
-	s.SetupRoutes(authHandler, userHandler)
+	s.SetupRoutes(authHandler, userHandler, samlHandler, magicLinkHandler, sessionHandler, adminHandler, exportHandler, attributeHandler, avatarHandler, emailChangeHandler, phoneHandler, consentHandler, auditHandler, webhookHandler)
 
 	// Create HTTP server with timeouts
 	s.server = &http.Server{
@@ -107,27 +288,162 @@ func (s *Server) Setup() error {
 		IdleTimeout:  s.cfg.Server.IdleTimeout,
 	}
 
+	if s.cfg.Metrics.Enabled && s.cfg.Metrics.Port != "" {
+		s.metricsServer = &http.Server{
+			Addr:              ":" + s.cfg.Metrics.Port,
+			Handler:           metrics.Handler(),
+			ReadHeaderTimeout: s.cfg.Server.ReadTimeout,
+		}
+	}
+
 	return nil
 }
 
-func (s *Server) healthCheck(c *gin.Context) {
-	// Check database health
-	if err := s.db.HealthCheck(); err != nil {
-		c.JSON(http.StatusServiceUnavailable, gin.H{
-			"status": "unhealthy",
-			"error":  "database connection failed",
+// buildAuthenticator routes sign-ins to the local password check or to LDAP, based on the email domain
+func (s *Server) buildAuthenticator(userRepo repository.UserRepository, passwordManager *utils.PasswordManager) *auth.Router {
+	router := auth.NewRouter(auth.NewPasswordAuthenticator(userRepo, passwordManager))
+
+	if s.cfg.Auth.LDAP.Enabled {
+		ldapAuthenticator := auth.NewLDAPAuthenticator(&s.cfg.Auth.LDAP)
+		for _, domain := range s.cfg.Auth.LDAP.Domains {
+			router.Register(domain, ldapAuthenticator)
+		}
+		s.logger.Info().Strs("domains", s.cfg.Auth.LDAP.Domains).Msg("LDAP authentication enabled")
+	}
+
+	return router
+}
+
+// rateLimit returns the middleware for a configured policy, or a no-op when the policy is not configured
+func (s *Server) rateLimit(name string) gin.HandlerFunc {
+	policy, ok := s.cfg.RateLimit.Policies[name]
+	if s.limiter == nil || !ok {
+		return func(c *gin.Context) { c.Next() }
+	}
+	return middleware.RateLimit(s.limiter, name, policy, s.logger)
+}
+
+func (s *Server) buildMailSender() mailer.Sender {
+	if s.cfg.Mail.Driver == "smtp" {
+		return mailer.NewSMTPSender(
+			s.cfg.Mail.SMTPHost,
+			s.cfg.Mail.SMTPPort,
+			s.cfg.Mail.SMTPUsername,
+			s.cfg.Mail.SMTPPassword,
+			s.cfg.Mail.From,
+		)
+	}
+
+	s.logger.Warn().Msg("Using in-memory mail sender, emails will not be delivered")
+	return mailer.NewMemorySender()
+}
+
+func (s *Server) buildSMSSender() sms.SMSSender {
+	if s.cfg.SMS.Driver == "webhook" {
+		return sms.NewWebhookSender(s.cfg.SMS.WebhookURL, s.cfg.SMS.WebhookSecret, s.cfg.SMS.WebhookTimeout)
+	}
+
+	s.logger.Warn().Msg("Using console SMS sender, text messages will be printed instead of delivered")
+	return sms.NewConsoleSender(os.Stdout)
+}
+
+// buildEventPublisher returns nil when publishing is disabled
+func (s *Server) buildEventPublisher(redisCache *cache.RedisCache) (events.EventPublisher, error) {
+	cfg := s.cfg.Events
+	switch cfg.Driver {
+	case "nats":
+		return events.NewNATSPublisher(cfg.NATSURL, cfg.NATSPrefix, cfg.NATSJetStream)
+	case "kafka":
+		return events.NewKafkaPublisher(cfg.KafkaBrokers, cfg.KafkaTopic), nil
+	case "redis":
+		return events.NewRedisStreamPublisher(redisCache.Client(), cfg.RedisStream, cfg.RedisStreamMaxLen), nil
+	case "memory":
+		s.logger.Warn().Msg("Using in-memory event publisher, events will not leave the process")
+		return events.NewMemoryPublisher(), nil
+	default:
+		return nil, nil
+	}
+}
+
+func (s *Server) buildBlobStore() (blobstore.BlobStore, error) {
+	if s.cfg.Blob.Driver == "s3" {
+		return blobstore.NewS3Store(blobstore.S3Config{
+			Endpoint:  s.cfg.Blob.S3Endpoint,
+			Region:    s.cfg.Blob.S3Region,
+			Bucket:    s.cfg.Blob.S3Bucket,
+			AccessKey: s.cfg.Blob.S3AccessKey,
+			SecretKey: s.cfg.Blob.S3SecretKey,
+			PathStyle: s.cfg.Blob.S3PathStyle,
+			PublicURL: s.cfg.Blob.PublicURL,
 		})
-		return
 	}
 
+	store, err := blobstore.NewLocalStore(s.cfg.Blob.LocalDir, s.cfg.Blob.PublicURL)
+	if err != nil {
+		return nil, err
+	}
+	s.blobDir = store.Root()
+	return store, nil
+}
+
+// registerHealthChecks adds the dependencies /readyz waits for
+func (s *Server) registerHealthChecks(redisCache *cache.RedisCache) error {
+	latest, err := database.LatestMigration(migrationsDir)
+	if err != nil {
+		return err
+	}
+
+	s.health.OnFailure = func(name string, err error) {
+		s.logger.Warn().Err(err).Str("check", name).Msg("Readiness check failed")
+	}
+
+	s.health.Register(health.CheckerFunc("database", s.db.HealthCheck))
+	s.health.Register(health.CheckerFunc("redis", redisCache.Ping))
+	// A newer schema is fine: it means the next release already migrated during a rollout
+	s.health.Register(health.CheckerFunc("migrations", func(ctx context.Context) error {
+		version, err := s.db.MigrationVersion(ctx)
+		if err != nil {
+			return err
+		}
+		if version < latest {
+			return fmt.Errorf("schema version %d is behind %d", version, latest)
+		}
+		return nil
+	}))
+	return nil
+}
+
+// livez only tells whether the process serves requests; it never touches dependencies
+func (s *Server) livez(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
-		"status":    "healthy",
-		"timestamp": time.Now().UTC(),
-		"version":   s.cfg.App.Version,
+		"status":  health.StatusUp,
+		"version": s.cfg.App.Version,
 	})
 }
 
+// readyz reports whether the service can take traffic, with the result of every check
+func (s *Server) readyz(c *gin.Context) {
+	report := s.health.Check(c.Request.Context())
+
+	status := http.StatusOK
+	if !report.Ready() {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, report)
+}
+
 func (s *Server) Run() error {
+	// Start background jobs
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	go s.purger.Run(jobsCtx)
+	go s.exportService.Run(jobsCtx)
+	go s.webhookService.Run(jobsCtx)
+	go s.outboxPruner.Run(jobsCtx)
+	if s.eventRelay != nil {
+		go s.eventRelay.Run(jobsCtx)
+	}
+
 	// Start server in goroutine
 	go func() {
 		s.logger.Info().Str("port", s.cfg.Server.Port).Msg("🚀 Server starting")
@@ -136,11 +452,28 @@ func (s *Server) Run() error {
 		}
 	}()
 
+	if s.metricsServer != nil {
+		go func() {
+			s.logger.Info().Str("port", s.cfg.Metrics.Port).Msg("Metrics server starting")
+			if err := s.metricsServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				s.logger.Error().Err(err).Msg("Metrics server failed")
+			}
+		}()
+	}
+
 	// Wait for interrupt signal
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 	s.logger.Info().Msg("Shutdown signal received")
+	stopJobs()
+
+	// Report unready while still serving, so load balancers stop routing before the listener closes
+	s.health.SetShuttingDown()
+	if delay := s.cfg.Health.ShutdownDelay; delay > 0 {
+		s.logger.Info().Dur("delay", delay).Msg("Draining before shutdown")
+		time.Sleep(delay)
+	}
 
 	// Graceful shutdown
 	ctx, cancel := context.WithTimeout(context.Background(), s.cfg.Server.ShutdownTimeout)
@@ -151,6 +484,19 @@ func (s *Server) Run() error {
 		return err
 	}
 
+	if s.metricsServer != nil {
+		if err := s.metricsServer.Shutdown(ctx); err != nil {
+			s.logger.Error().Err(err).Msg("Failed to shut down metrics server")
+		}
+	}
+
+	// Close event publisher
+	if s.publisher != nil {
+		if err := s.publisher.Close(); err != nil {
+			s.logger.Error().Err(err).Msg("Failed to close event publisher")
+		}
+	}
+
 	// Close database connection
 	if err := s.db.Close(); err != nil {
 		s.logger.Error().Err(err).Msg("Failed to close database connection")
@@ -161,6 +507,13 @@ func (s *Server) Run() error {
 		s.logger.Error().Err(err).Msg("Failed to close cache")
 	}
 
+	// Flush remaining spans
+	if s.tracer != nil {
+		if err := s.tracer.Shutdown(ctx); err != nil {
+			s.logger.Error().Err(err).Msg("Failed to shut down tracing")
+		}
+	}
+
 	s.logger.Info().Msg(" Server shutdown completed")
 	return nil
 }
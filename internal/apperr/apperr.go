@@ -0,0 +1,121 @@
+// Package apperr defines errors that know how they are presented to API clients.
+//
+// Every Error carries a machine-readable code, an HTTP status and a message that is safe to return.
+// Services return them as sentinels, optionally refined with WithMessage or WithDetails, and the HTTP
+// layer renders them without inspecting error strings. Anything else is reported as an internal error.
+package apperr
+
+import (
+	"errors"
+	"net/http"
+	"user-management/internal/utils"
+)
+
+type Error struct {
+	Status  int
+	Code    string
+	Message string
+	// Details is extra public information, e.g. which field failed validation
+	Details string
+
+	parent *Error
+	cause  error
+}
+
+// New defines a sentinel error
+func New(status int, code, message string) *Error {
+	return &Error{Status: status, Code: code, Message: message}
+}
+
+func (e *Error) Error() string {
+	msg := e.Message
+	if e.Details != "" {
+		msg += ": " + e.Details
+	}
+	if e.cause != nil {
+		msg += ": " + e.cause.Error()
+	}
+	return msg
+}
+
+// Unwrap returns the underlying cause, which is never shown to clients
+func (e *Error) Unwrap() error {
+	return e.cause
+}
+
+// Is matches the error itself and every error it was derived from
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	if !ok {
+		return false
+	}
+	for p := e; p != nil; p = p.parent {
+		if p == t {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *Error) derive() *Error {
+	derived := *e
+	derived.parent = e
+	return &derived
+}
+
+// WithMessage returns a copy with a different public message that still matches e
+func (e *Error) WithMessage(message string) *Error {
+	derived := e.derive()
+	derived.Message = message
+	return derived
+}
+
+// WithDetails returns a copy with public details that still matches e
+func (e *Error) WithDetails(details string) *Error {
+	derived := e.derive()
+	derived.Details = details
+	return derived
+}
+
+// Wrap returns a copy that records cause for logging and errors.Is/As, without exposing it
+func (e *Error) Wrap(cause error) *Error {
+	derived := e.derive()
+	derived.cause = cause
+	return derived
+}
+
+// Generic errors used when no domain-specific error applies
+var (
+	ErrInternal      = New(http.StatusInternalServerError, utils.ErrCodeInternalServerError, "An unexpected error occurred")
+	ErrUnauthorized  = New(http.StatusUnauthorized, utils.ErrCodeUnauthorized, "Authentication required")
+	ErrForbidden     = New(http.StatusForbidden, utils.ErrCodeForbidden, "You don't have permission to perform this action")
+	ErrNotFound      = New(http.StatusNotFound, utils.ErrCodeNotFound, "Resource not found")
+	ErrConflict      = New(http.StatusConflict, utils.ErrCodeConflict, "The request conflicts with the current state")
+	ErrValidation    = New(http.StatusBadRequest, utils.ErrCodeValidationError, "Invalid request payload")
+	ErrInvalidID     = New(http.StatusBadRequest, utils.ErrCodeInvalidID, "Invalid ID format")
+	ErrInvalidCursor = New(http.StatusBadRequest, utils.ErrCodeInvalidCursor, "Invalid cursor format")
+	ErrRateLimited   = New(http.StatusTooManyRequests, utils.ErrCodeRateLimitExceeded, "Too many requests, please try again later")
+)
+
+// Validation reports a request that failed binding or validation; binding messages are safe to return
+func Validation(err error) *Error {
+	return ErrValidation.WithDetails(err.Error())
+}
+
+// From returns the Error in err's chain, or ErrInternal wrapping err
+func From(err error) *Error {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+	return ErrInternal.Wrap(err)
+}
+
+// Or returns err when it already is an Error, and fallback wrapping err otherwise
+func Or(err error, fallback *Error) error {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return err
+	}
+	return fallback.Wrap(err)
+}
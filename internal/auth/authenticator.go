@@ -0,0 +1,90 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+	"user-management/internal/models"
+)
+
+var (
+	ErrInvalidCredentials = errors.New("invalid credentials")
+)
+
+// Identity is the result of a successful authentication
+type Identity struct {
+	Email    string
+	Provider string
+	Groups   []string
+	// Role is the role mapped by the backend; empty when the backend does not manage roles
+	Role string
+	// User is set by backends that resolve the local user themselves
+	User *models.User
+	// AuthTime is when the user actually authenticated; zero means now
+	AuthTime time.Time
+	// Methods are the authentication method references (amr) used
+	Methods []string
+	// Issuer and Subject identify the account at an external backend, e.g. the directory URL and entry DN
+	Issuer  string
+	Subject string
+}
+
+// Authenticator verifies a user's credentials against a backend
+type Authenticator interface {
+	Authenticate(ctx context.Context, email, password string) (*Identity, error)
+}
+
+// DomainManager is implemented by authenticators and SSO providers that hand whole email domains to an
+// external directory or identity provider
+type DomainManager interface {
+	Managed(email string) bool
+}
+
+// DomainManagers reports an email as managed when any of its members manages it
+type DomainManagers []DomainManager
+
+func (m DomainManagers) Managed(email string) bool {
+	for _, manager := range m {
+		if manager.Managed(email) {
+			return true
+		}
+	}
+	return false
+}
+
+// Router selects an authenticator based on the email domain, falling back to a default
+type Router struct {
+	fallback Authenticator
+	domains  map[string]Authenticator
+}
+
+func NewRouter(fallback Authenticator) *Router {
+	return &Router{
+		fallback: fallback,
+		domains:  make(map[string]Authenticator),
+	}
+}
+
+// Register routes every email in the given domain to the authenticator
+func (r *Router) Register(domain string, authenticator Authenticator) {
+	r.domains[strings.ToLower(strings.TrimSpace(domain))] = authenticator
+}
+
+func (r *Router) For(email string) Authenticator {
+	if idx := strings.LastIndex(email, "@"); idx >= 0 {
+		if authenticator, ok := r.domains[strings.ToLower(email[idx+1:])]; ok {
+			return authenticator
+		}
+	}
+	return r.fallback
+}
+
+// Managed reports whether email belongs to a domain handled by a directory rather than local passwords
+func (r *Router) Managed(email string) bool {
+	return r.For(email) != r.fallback
+}
+
+func (r *Router) Authenticate(ctx context.Context, email, password string) (*Identity, error) {
+	return r.For(email).Authenticate(ctx, email, password)
+}
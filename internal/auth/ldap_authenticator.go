@@ -0,0 +1,153 @@
+package auth
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"strings"
+	"user-management/internal/config"
+	"user-management/internal/models"
+	"user-management/internal/utils"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// LDAPAuthenticator authenticates with a search for the user entry followed by a bind as that entry
+type LDAPAuthenticator struct {
+	cfg *config.LDAPConfig
+}
+
+func NewLDAPAuthenticator(cfg *config.LDAPConfig) *LDAPAuthenticator {
+	return &LDAPAuthenticator{cfg: cfg}
+}
+
+func (a *LDAPAuthenticator) Authenticate(ctx context.Context, email, password string) (*Identity, error) {
+	// An empty password would result in an unauthenticated bind, which most servers accept
+	if password == "" {
+		return nil, ErrInvalidCredentials
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	conn, err := a.dial()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	// Bind with the service account to look up the user entry
+	if a.cfg.BindDN != "" {
+		if err := conn.Bind(a.cfg.BindDN, a.cfg.BindPassword); err != nil {
+			return nil, fmt.Errorf("failed to bind ldap service account: %w", err)
+		}
+	}
+
+	entry, err := a.findEntry(conn, email)
+	if err != nil {
+		return nil, err
+	}
+
+	// Verify the password by binding as the user
+	if err := conn.Bind(entry.DN, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, fmt.Errorf("failed to bind ldap user: %w", err)
+	}
+
+	directoryEmail := entry.GetAttributeValue(a.cfg.EmailAttribute)
+	if directoryEmail == "" {
+		directoryEmail = email
+	}
+
+	groups := entry.GetAttributeValues(a.cfg.GroupAttribute)
+
+	return &Identity{
+		Email:    strings.ToLower(strings.TrimSpace(directoryEmail)),
+		Provider: models.AuthProviderLDAP,
+		Groups:   groups,
+		Role:     a.mapRole(groups),
+		Methods:  []string{utils.AMRPassword},
+		Issuer:   a.cfg.URL,
+		Subject:  entry.DN,
+	}, nil
+}
+
+func (a *LDAPAuthenticator) dial() (*ldap.Conn, error) {
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: a.cfg.InsecureSkipVerify, //nolint:gosec // opt-in for test directories
+		MinVersion:         tls.VersionTLS12,
+	}
+
+	conn, err := ldap.DialURL(a.cfg.URL, ldap.DialWithTLSConfig(tlsConfig))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to ldap: %w", err)
+	}
+
+	if a.cfg.Timeout > 0 {
+		conn.SetTimeout(a.cfg.Timeout)
+	}
+
+	if a.cfg.StartTLS {
+		if err := conn.StartTLS(tlsConfig); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("failed to start tls: %w", err)
+		}
+	}
+
+	return conn, nil
+}
+
+func (a *LDAPAuthenticator) findEntry(conn *ldap.Conn, email string) (*ldap.Entry, error) {
+	timeLimit := int(a.cfg.Timeout.Seconds())
+
+	request := ldap.NewSearchRequest(
+		a.cfg.BaseDN,
+		ldap.ScopeWholeSubtree,
+		ldap.NeverDerefAliases,
+		2,
+		timeLimit,
+		false,
+		fmt.Sprintf(a.cfg.UserFilter, ldap.EscapeFilter(email)),
+		[]string{"dn", a.cfg.EmailAttribute, a.cfg.GroupAttribute},
+		nil,
+	)
+
+	result, err := conn.Search(request)
+	if err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultNoSuchObject) {
+			return nil, ErrInvalidCredentials
+		}
+		// More entries than the size limit means the address is ambiguous, the same as two entries
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultSizeLimitExceeded) || errors.Is(err, ldap.ErrSizeLimitExceeded) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, fmt.Errorf("failed to search ldap: %w", err)
+	}
+
+	// Zero or ambiguous matches are treated as unknown users
+	if len(result.Entries) != 1 {
+		return nil, ErrInvalidCredentials
+	}
+
+	return result.Entries[0], nil
+}
+
+// mapRole picks the most privileged role among the user's mapped groups
+func (a *LDAPAuthenticator) mapRole(groups []string) string {
+	role := a.cfg.DefaultRole
+	for _, group := range groups {
+		for groupDN, mapped := range a.cfg.GroupRoles {
+			if !strings.EqualFold(group, groupDN) {
+				continue
+			}
+			if models.RoleRank(mapped) > models.RoleRank(role) {
+				role = mapped
+			}
+		}
+	}
+	return role
+}
@@ -0,0 +1,230 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+	"user-management/internal/config"
+	"user-management/internal/models"
+
+	"github.com/jimlambrt/gldap"
+)
+
+const (
+	testBaseDN       = "ou=people,dc=example,dc=org"
+	testBindDN       = "cn=svc,dc=example,dc=org"
+	testBindPassword = "svc-secret"
+	testAdminsGroup  = "cn=admins,ou=groups,dc=example,dc=org"
+)
+
+type directoryUser struct {
+	dn       string
+	password string
+	mail     string
+	groups   []string
+}
+
+// startDirectory serves the users from an in-memory LDAP server and returns its URL
+func startDirectory(t *testing.T, users ...directoryUser) string {
+	t.Helper()
+
+	passwords := map[string]string{testBindDN: testBindPassword}
+	for _, u := range users {
+		passwords[u.dn] = u.password
+	}
+
+	mux, err := gldap.NewMux()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_ = mux.Bind(func(w *gldap.ResponseWriter, r *gldap.Request) {
+		resp := r.NewBindResponse(gldap.WithResponseCode(gldap.ResultInvalidCredentials))
+		defer func() { _ = w.Write(resp) }()
+
+		m, err := r.GetSimpleBindMessage()
+		if err != nil {
+			return
+		}
+		if want, ok := passwords[m.UserName]; ok && want != "" && string(m.Password) == want {
+			resp.SetResultCode(gldap.ResultSuccess)
+		}
+	})
+
+	_ = mux.Search(func(w *gldap.ResponseWriter, r *gldap.Request) {
+		resp := r.NewSearchDoneResponse(gldap.WithResponseCode(gldap.ResultSuccess))
+		defer func() { _ = w.Write(resp) }()
+
+		m, err := r.GetSearchMessage()
+		if err != nil {
+			resp.SetResultCode(gldap.ResultOperationsError)
+			return
+		}
+		// Like a real directory, refuse to return more entries than the requested size limit
+		var matches []directoryUser
+		for _, u := range users {
+			if m.Filter == fmt.Sprintf("(mail=%s)", u.mail) {
+				matches = append(matches, u)
+			}
+		}
+		if m.SizeLimit > 0 && int64(len(matches)) > m.SizeLimit {
+			matches = matches[:m.SizeLimit]
+			resp.SetResultCode(gldap.ResultSizeLimitExceeded)
+		}
+		for _, u := range matches {
+			_ = w.Write(r.NewSearchResponseEntry(u.dn, gldap.WithAttributes(map[string][]string{
+				"mail":     {u.mail},
+				"memberOf": u.groups,
+			})))
+		}
+	})
+
+	server, err := gldap.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := server.Router(mux); err != nil {
+		t.Fatal(err)
+	}
+
+	addr := freeAddr(t)
+	go func() { _ = server.Run(addr) }()
+	t.Cleanup(func() { _ = server.Stop() })
+
+	for deadline := time.Now().Add(5 * time.Second); !server.Ready(); {
+		if time.Now().After(deadline) {
+			t.Fatal("ldap server did not start")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	return "ldap://" + addr
+}
+
+func freeAddr(t *testing.T) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	return listener.Addr().String()
+}
+
+func newTestLDAPAuthenticator(url string) *LDAPAuthenticator {
+	return NewLDAPAuthenticator(&config.LDAPConfig{
+		URL:            url,
+		Timeout:        2 * time.Second,
+		BindDN:         testBindDN,
+		BindPassword:   testBindPassword,
+		BaseDN:         testBaseDN,
+		UserFilter:     "(mail=%s)",
+		EmailAttribute: "mail",
+		GroupAttribute: "memberOf",
+		GroupRoles:     map[string]string{testAdminsGroup: models.RoleAdmin},
+		DefaultRole:    models.RoleUser,
+	})
+}
+
+func TestLDAPAuthenticator(t *testing.T) {
+	alice := directoryUser{
+		dn:       "uid=alice," + testBaseDN,
+		password: "alice-secret",
+		mail:     "Alice@Corp.Example",
+		groups:   []string{testAdminsGroup},
+	}
+	bob := directoryUser{
+		dn:       "uid=bob," + testBaseDN,
+		password: "bob-secret",
+		mail:     "bob@corp.example",
+	}
+	twin := directoryUser{dn: "uid=twin1," + testBaseDN, password: "x", mail: "twin@corp.example"}
+	twin2 := directoryUser{dn: "uid=twin2," + testBaseDN, password: "x", mail: "twin@corp.example"}
+	shared := func(n int) directoryUser {
+		return directoryUser{dn: fmt.Sprintf("uid=shared%d,%s", n, testBaseDN), password: "x", mail: "shared@corp.example"}
+	}
+
+	authenticator := newTestLDAPAuthenticator(startDirectory(t, alice, bob, twin, twin2, shared(1), shared(2), shared(3)))
+	ctx := context.Background()
+
+	t.Run("maps groups to the most privileged role", func(t *testing.T) {
+		identity, err := authenticator.Authenticate(ctx, alice.mail, alice.password)
+		if err != nil {
+			t.Fatalf("Authenticate: %v", err)
+		}
+		if identity.Email != "alice@corp.example" {
+			t.Errorf("email = %q, want normalized directory email", identity.Email)
+		}
+		if identity.Provider != models.AuthProviderLDAP {
+			t.Errorf("provider = %q", identity.Provider)
+		}
+		if identity.Role != models.RoleAdmin {
+			t.Errorf("role = %q, want %q", identity.Role, models.RoleAdmin)
+		}
+		if identity.Subject != alice.dn {
+			t.Errorf("subject = %q, want the entry DN", identity.Subject)
+		}
+	})
+
+	t.Run("uses the default role without mapped groups", func(t *testing.T) {
+		identity, err := authenticator.Authenticate(ctx, bob.mail, bob.password)
+		if err != nil {
+			t.Fatalf("Authenticate: %v", err)
+		}
+		if identity.Role != models.RoleUser {
+			t.Errorf("role = %q, want %q", identity.Role, models.RoleUser)
+		}
+	})
+
+	rejected := []struct {
+		name, email, password string
+	}{
+		{"wrong password", bob.mail, "nope"},
+		{"empty password", bob.mail, ""},
+		{"unknown user", "carol@corp.example", "whatever"},
+		{"ambiguous entries", twin.mail, "x"},
+		{"entries beyond the size limit", "shared@corp.example", "x"},
+		{"filter injection", "*", "x"},
+	}
+	for _, tc := range rejected {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := authenticator.Authenticate(ctx, tc.email, tc.password)
+			if !errors.Is(err, ErrInvalidCredentials) {
+				t.Fatalf("err = %v, want ErrInvalidCredentials", err)
+			}
+		})
+	}
+
+	t.Run("service account failure is not reported as bad credentials", func(t *testing.T) {
+		broken := newTestLDAPAuthenticator(authenticator.cfg.URL)
+		broken.cfg.BindPassword = "wrong"
+		_, err := broken.Authenticate(ctx, bob.mail, bob.password)
+		if err == nil || errors.Is(err, ErrInvalidCredentials) {
+			t.Fatalf("err = %v, want a service account error", err)
+		}
+	})
+}
+
+func TestRouter(t *testing.T) {
+	fallback := &PasswordAuthenticator{}
+	directory := newTestLDAPAuthenticator("ldap://127.0.0.1:1")
+
+	router := NewRouter(fallback)
+	router.Register(" Corp.Example ", directory)
+
+	if router.For("bob@CORP.example") != directory {
+		t.Error("directory domain not routed to the directory")
+	}
+	if router.For("bob@elsewhere.example") != fallback {
+		t.Error("other domain not routed to the fallback")
+	}
+	if !router.Managed("bob@corp.example") || router.Managed("bob@elsewhere.example") {
+		t.Error("Managed does not match the routing")
+	}
+	if router.Managed("no-at-sign") {
+		t.Error("address without domain reported as managed")
+	}
+}
@@ -0,0 +1,44 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"user-management/internal/models"
+	"user-management/internal/repository"
+	"user-management/internal/utils"
+)
+
+// PasswordAuthenticator checks credentials against the bcrypt hash stored locally
+type PasswordAuthenticator struct {
+	repo            repository.UserRepository
+	passwordManager *utils.PasswordManager
+}
+
+func NewPasswordAuthenticator(repo repository.UserRepository, passwordManager *utils.PasswordManager) *PasswordAuthenticator {
+	return &PasswordAuthenticator{
+		repo:            repo,
+		passwordManager: passwordManager,
+	}
+}
+
+func (a *PasswordAuthenticator) Authenticate(ctx context.Context, email, password string) (*Identity, error) {
+	user, err := a.repo.FindByIdentifier(ctx, email)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+
+	if user == nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	if err := a.passwordManager.Compare(user.Password, password); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	return &Identity{
+		Email:    user.Email,
+		Provider: models.AuthProviderLocal,
+		User:     user,
+		Methods:  []string{utils.AMRPassword},
+	}, nil
+}
@@ -0,0 +1,368 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+	"user-management/internal/apperr"
+	"user-management/internal/config"
+	"user-management/internal/models"
+	"user-management/internal/utils"
+	"user-management/pkg/cache"
+
+	"github.com/crewjam/saml"
+	"github.com/crewjam/saml/samlsp"
+)
+
+var (
+	ErrSAMLAuthFailed         = apperr.New(http.StatusUnauthorized, utils.ErrCodeAuthFailed, "SAML authentication failed")
+	ErrSAMLConnectionNotFound = apperr.ErrNotFound.WithMessage("SAML connection not found")
+	ErrSAMLRequestNotFound    = ErrSAMLAuthFailed.WithDetails("request not found or expired")
+	ErrSAMLEmailMissing       = ErrSAMLAuthFailed.WithDetails("assertion has no email")
+	ErrSAMLDomainMismatch     = ErrSAMLAuthFailed.WithDetails("assertion email is outside the organization domains")
+)
+
+// multiFactorContexts are the AuthnContextClassRef values that assert more than one factor. Values are
+// compared exactly, since a substring such as "mfa" also appears in unrelated or single-factor contexts.
+var multiFactorContexts = map[string]bool{
+	"https://refeds.org/profile/mfa":                                     true,
+	"http://schemas.microsoft.com/claims/multipleauthn":                  true,
+	"urn:oasis:names:tc:SAML:2.0:ac:classes:MobileTwoFactorContract":     true,
+	"urn:oasis:names:tc:SAML:2.0:ac:classes:MobileTwoFactorUnregistered": true,
+	"urn:oasis:names:tc:SAML:2.0:ac:classes:TimeSyncToken":               true,
+}
+
+// Attribute names commonly used by identity providers for the email address
+var defaultEmailAttributes = []string{
+	"email",
+	"mail",
+	"http://schemas.xmlsoap.org/ws/2005/05/identity/claims/emailaddress",
+	"urn:oid:0.9.2342.19200300.100.1.3",
+}
+
+type samlConnection struct {
+	cfg config.SAMLConnection
+	sp  *saml.ServiceProvider
+}
+
+// SAMLProvider acts as a SAML 2.0 service provider towards one identity provider per organization
+type SAMLProvider struct {
+	cfg         *config.SAMLConfig
+	cache       cache.Cache
+	connections map[string]*samlConnection
+	domains     map[string]string
+}
+
+func NewSAMLProvider(ctx context.Context, cfg *config.SAMLConfig, cache cache.Cache) (*SAMLProvider, error) {
+	keyPair, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load saml key pair: %w", err)
+	}
+
+	certificate, err := x509.ParseCertificate(keyPair.Certificate[0])
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse saml certificate: %w", err)
+	}
+
+	signer, ok := keyPair.PrivateKey.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("saml private key cannot sign")
+	}
+
+	provider := &SAMLProvider{
+		cfg:         cfg,
+		cache:       cache,
+		connections: make(map[string]*samlConnection),
+		domains:     make(map[string]string),
+	}
+
+	for _, connCfg := range cfg.Connections {
+		idpMetadata, err := loadIDPMetadata(ctx, connCfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load idp metadata for %s: %w", connCfg.Organization, err)
+		}
+
+		org := strings.ToLower(connCfg.Organization)
+		metadataURL, err := url.Parse(fmt.Sprintf("%s/auth/saml/%s/metadata", cfg.RootURL, org))
+		if err != nil {
+			return nil, fmt.Errorf("invalid saml root url: %w", err)
+		}
+		acsURL, err := url.Parse(fmt.Sprintf("%s/auth/saml/%s/acs", cfg.RootURL, org))
+		if err != nil {
+			return nil, fmt.Errorf("invalid saml root url: %w", err)
+		}
+
+		provider.connections[org] = &samlConnection{
+			cfg: connCfg,
+			sp: &saml.ServiceProvider{
+				EntityID:    cfg.EntityID,
+				Key:         signer,
+				Certificate: certificate,
+				MetadataURL: *metadataURL,
+				AcsURL:      *acsURL,
+				IDPMetadata: idpMetadata,
+			},
+		}
+
+		for _, domain := range connCfg.Domains {
+			provider.domains[strings.ToLower(strings.TrimSpace(domain))] = org
+		}
+	}
+
+	return provider, nil
+}
+
+func loadIDPMetadata(ctx context.Context, connCfg config.SAMLConnection) (*saml.EntityDescriptor, error) {
+	if connCfg.MetadataFile != "" {
+		data, err := os.ReadFile(connCfg.MetadataFile)
+		if err != nil {
+			return nil, err
+		}
+		return samlsp.ParseMetadata(data)
+	}
+
+	metadataURL, err := url.Parse(connCfg.MetadataURL)
+	if err != nil {
+		return nil, err
+	}
+
+	fetchCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	return samlsp.FetchMetadata(fetchCtx, http.DefaultClient, *metadataURL)
+}
+
+// OrganizationForEmail resolves the organization whose identity provider owns the email domain
+func (p *SAMLProvider) OrganizationForEmail(email string) (string, error) {
+	idx := strings.LastIndex(email, "@")
+	if idx < 0 {
+		return "", ErrSAMLConnectionNotFound
+	}
+
+	org, ok := p.domains[strings.ToLower(email[idx+1:])]
+	if !ok {
+		return "", ErrSAMLConnectionNotFound
+	}
+	return org, nil
+}
+
+// Managed reports whether email belongs to an organization domain, whose accounts sign in through its IdP
+func (p *SAMLProvider) Managed(email string) bool {
+	_, err := p.OrganizationForEmail(email)
+	return err == nil
+}
+
+// Metadata returns the service provider metadata XML for the organization
+func (p *SAMLProvider) Metadata(org string) ([]byte, error) {
+	conn, err := p.connection(org)
+	if err != nil {
+		return nil, err
+	}
+
+	return xml.MarshalIndent(conn.sp.Metadata(), "", "  ")
+}
+
+// LoginURL builds the HTTP-Redirect AuthnRequest URL and remembers the request ID for the ACS.
+// forceAuthn asks the IdP to re-authenticate the user even if it has an SSO session, for step-up.
+func (p *SAMLProvider) LoginURL(ctx context.Context, org string, forceAuthn bool) (string, error) {
+	conn, err := p.connection(org)
+	if err != nil {
+		return "", err
+	}
+
+	request, err := conn.sp.MakeAuthenticationRequest(
+		conn.sp.GetSSOBindingLocation(saml.HTTPRedirectBinding),
+		saml.HTTPRedirectBinding,
+		saml.HTTPPostBinding,
+	)
+	if err != nil {
+		return "", fmt.Errorf("failed to create authn request: %w", err)
+	}
+	if forceAuthn {
+		request.ForceAuthn = &forceAuthn
+	}
+
+	relayState, err := utils.GenerateToken()
+	if err != nil {
+		return "", err
+	}
+
+	if err := p.cache.Set(ctx, relayStateKey(org, relayState), request.ID, p.cfg.RequestTTL); err != nil {
+		return "", fmt.Errorf("failed to store saml request: %w", err)
+	}
+
+	redirectURL, err := request.Redirect(relayState, conn.sp)
+	if err != nil {
+		return "", fmt.Errorf("failed to build redirect url: %w", err)
+	}
+
+	return redirectURL.String(), nil
+}
+
+// ParseResponse validates the signed assertion posted to the ACS and maps it to an identity
+func (p *SAMLProvider) ParseResponse(ctx context.Context, org string, r *http.Request) (*Identity, error) {
+	conn, err := p.connection(org)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.ParseForm(); err != nil {
+		return nil, fmt.Errorf("failed to parse saml form: %w", err)
+	}
+
+	// The request ID is single-use, so a captured response cannot be replayed
+	cached, err := p.cache.GetDel(ctx, relayStateKey(org, r.PostForm.Get("RelayState")))
+	if err != nil {
+		return nil, ErrSAMLRequestNotFound
+	}
+
+	var requestID string
+	if err := json.Unmarshal([]byte(cached), &requestID); err != nil {
+		return nil, ErrSAMLRequestNotFound
+	}
+
+	assertion, err := conn.sp.ParseResponse(r, []string{requestID})
+	if err != nil {
+		return nil, fmt.Errorf("invalid saml response: %w", err)
+	}
+
+	return conn.identity(assertion)
+}
+
+func (p *SAMLProvider) connection(org string) (*samlConnection, error) {
+	conn, ok := p.connections[strings.ToLower(org)]
+	if !ok {
+		return nil, ErrSAMLConnectionNotFound
+	}
+	return conn, nil
+}
+
+func (c *samlConnection) identity(assertion *saml.Assertion) (*Identity, error) {
+	attributes := make(map[string][]string)
+	for _, statement := range assertion.AttributeStatements {
+		for _, attr := range statement.Attributes {
+			for _, value := range attr.Values {
+				attributes[attr.Name] = append(attributes[attr.Name], value.Value)
+				if attr.FriendlyName != "" {
+					attributes[attr.FriendlyName] = append(attributes[attr.FriendlyName], value.Value)
+				}
+			}
+		}
+	}
+
+	email := c.email(assertion, attributes)
+	if email == "" {
+		return nil, ErrSAMLEmailMissing
+	}
+
+	// An identity provider may only assert users of its own organization
+	if !c.ownsEmail(email) {
+		return nil, ErrSAMLDomainMismatch
+	}
+
+	var groups []string
+	if c.cfg.GroupAttribute != "" {
+		groups = attributes[c.cfg.GroupAttribute]
+	}
+
+	authTime, methods := authnContext(assertion)
+
+	return &Identity{
+		Email:    email,
+		Provider: models.AuthProviderSAML,
+		Groups:   groups,
+		Role:     c.mapRole(groups),
+		AuthTime: authTime,
+		Methods:  methods,
+		Issuer:   assertion.Issuer.Value,
+		Subject:  subject(assertion),
+	}, nil
+}
+
+func subject(assertion *saml.Assertion) string {
+	if assertion.Subject == nil || assertion.Subject.NameID == nil {
+		return ""
+	}
+	return assertion.Subject.NameID.Value
+}
+
+// authnContext reports when and how the IdP authenticated the user
+func authnContext(assertion *saml.Assertion) (time.Time, []string) {
+	methods := []string{utils.AMRFederated}
+	if len(assertion.AuthnStatements) == 0 {
+		return time.Time{}, methods
+	}
+
+	statement := assertion.AuthnStatements[0]
+	if ref := statement.AuthnContext.AuthnContextClassRef; ref != nil && isMultiFactorContext(ref.Value) {
+		methods = append(methods, utils.AMRMFA)
+	}
+
+	return statement.AuthnInstant, methods
+}
+
+func isMultiFactorContext(classRef string) bool {
+	return multiFactorContexts[classRef]
+}
+
+func (c *samlConnection) email(assertion *saml.Assertion, attributes map[string][]string) string {
+	names := defaultEmailAttributes
+	if c.cfg.EmailAttribute != "" {
+		names = []string{c.cfg.EmailAttribute}
+	}
+
+	for _, name := range names {
+		if values := attributes[name]; len(values) > 0 && values[0] != "" {
+			return strings.ToLower(strings.TrimSpace(values[0]))
+		}
+	}
+
+	// Fall back to the subject when the IdP uses the email name ID format
+	if assertion.Subject != nil && assertion.Subject.NameID != nil &&
+		assertion.Subject.NameID.Format == string(saml.EmailAddressNameIDFormat) {
+		return strings.ToLower(strings.TrimSpace(assertion.Subject.NameID.Value))
+	}
+
+	return ""
+}
+
+func (c *samlConnection) ownsEmail(email string) bool {
+	idx := strings.LastIndex(email, "@")
+	if idx < 0 {
+		return false
+	}
+	domain := email[idx+1:]
+	for _, allowed := range c.cfg.Domains {
+		if strings.EqualFold(domain, strings.TrimSpace(allowed)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *samlConnection) mapRole(groups []string) string {
+	role := c.cfg.DefaultRole
+	if role == "" {
+		role = models.RoleUser
+	}
+	for _, group := range groups {
+		if mapped, ok := c.cfg.GroupRoles[group]; ok && models.RoleRank(mapped) > models.RoleRank(role) {
+			role = mapped
+		}
+	}
+	return role
+}
+
+func relayStateKey(org, relayState string) string {
+	return fmt.Sprintf("saml:request:%s:%s", strings.ToLower(org), relayState)
+}
@@ -0,0 +1,343 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"encoding/xml"
+	"errors"
+	"html"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"user-management/internal/config"
+	"user-management/internal/models"
+
+	"github.com/crewjam/saml"
+	"github.com/crewjam/saml/samlsp"
+)
+
+// memoryCache is a minimal cache.Cache for tests
+type memoryCache struct {
+	mu     sync.Mutex
+	values map[string]string
+}
+
+func newMemoryCache() *memoryCache {
+	return &memoryCache{values: make(map[string]string)}
+}
+
+func (m *memoryCache) Get(_ context.Context, key string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	value, ok := m.values[key]
+	if !ok {
+		return "", errors.New("cache miss")
+	}
+	return value, nil
+}
+
+func (m *memoryCache) Set(_ context.Context, key string, value interface{}, _ time.Duration) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.values[key] = string(data)
+	return nil
+}
+
+func (m *memoryCache) Delete(_ context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.values, key)
+	return nil
+}
+
+func (m *memoryCache) GetDel(_ context.Context, key string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	value, ok := m.values[key]
+	if !ok {
+		return "", errors.New("cache miss")
+	}
+	delete(m.values, key)
+	return value, nil
+}
+
+func (m *memoryCache) Increment(context.Context, string, time.Duration) (int64, error) {
+	return 0, errors.New("not implemented")
+}
+
+func (m *memoryCache) Close() error { return nil }
+
+// newTestCertificate generates a self-signed RSA certificate
+func newTestCertificate(t *testing.T, commonName string) (*rsa.PrivateKey, *x509.Certificate) {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certificate, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key, certificate
+}
+
+func writePEM(t *testing.T, path, blockType string, der []byte) {
+	t.Helper()
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+type staticServiceProvider struct {
+	metadata *saml.EntityDescriptor
+}
+
+func (s *staticServiceProvider) GetServiceProvider(*http.Request, string) (*saml.EntityDescriptor, error) {
+	return s.metadata, nil
+}
+
+type staticSession struct {
+	session *saml.Session
+}
+
+func (s *staticSession) GetSession(http.ResponseWriter, *http.Request, *saml.IdpAuthnRequest) *saml.Session {
+	return s.session
+}
+
+type samlFixture struct {
+	provider *SAMLProvider
+	idp      *saml.IdentityProvider
+	session  *staticSession
+}
+
+func newSAMLFixture(t *testing.T) *samlFixture {
+	t.Helper()
+	dir := t.TempDir()
+
+	// Identity provider, described by a metadata file as an administrator would configure it
+	idpKey, idpCert := newTestCertificate(t, "idp.acme.example")
+	idpBase, _ := url.Parse("https://idp.acme.example")
+	session := &staticSession{}
+	idp := &saml.IdentityProvider{
+		Key:             idpKey,
+		Certificate:     idpCert,
+		MetadataURL:     *idpBase.JoinPath("metadata"),
+		SSOURL:          *idpBase.JoinPath("sso"),
+		SessionProvider: session,
+	}
+	idpMetadata, err := xml.Marshal(idp.Metadata())
+	if err != nil {
+		t.Fatal(err)
+	}
+	metadataFile := filepath.Join(dir, "idp.xml")
+	if err := os.WriteFile(metadataFile, idpMetadata, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	// Service provider key pair
+	spKey, spCert := newTestCertificate(t, "sp.example")
+	certFile, keyFile := filepath.Join(dir, "sp.crt"), filepath.Join(dir, "sp.key")
+	writePEM(t, certFile, "CERTIFICATE", spCert.Raw)
+	writePEM(t, keyFile, "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(spKey))
+
+	provider, err := NewSAMLProvider(context.Background(), &config.SAMLConfig{
+		RootURL:    "https://sp.example/api/v1",
+		EntityID:   "https://sp.example",
+		CertFile:   certFile,
+		KeyFile:    keyFile,
+		RequestTTL: time.Minute,
+		Connections: []config.SAMLConnection{{
+			Organization:   "acme",
+			Domains:        []string{"acme.example"},
+			MetadataFile:   metadataFile,
+			GroupAttribute: "eduPersonAffiliation",
+			GroupRoles:     map[string]string{"admins": models.RoleAdmin},
+		}},
+	}, newMemoryCache())
+	if err != nil {
+		t.Fatalf("NewSAMLProvider: %v", err)
+	}
+
+	spMetadataXML, err := provider.Metadata("acme")
+	if err != nil {
+		t.Fatal(err)
+	}
+	spMetadata, err := samlsp.ParseMetadata(spMetadataXML)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idp.ServiceProviderProvider = &staticServiceProvider{metadata: spMetadata}
+
+	return &samlFixture{provider: provider, idp: idp, session: session}
+}
+
+var formFieldPattern = regexp.MustCompile(`name="(SAMLResponse|RelayState)" value="([^"]*)"`)
+
+// signIn runs an SP-initiated login through the IdP and returns the form the browser would post to the ACS
+func (f *samlFixture) signIn(t *testing.T, email string, groups ...string) url.Values {
+	t.Helper()
+
+	f.session.session = &saml.Session{
+		ID:           "session-1",
+		CreateTime:   time.Now(),
+		ExpireTime:   time.Now().Add(time.Hour),
+		Index:        "1",
+		NameID:       email,
+		NameIDFormat: string(saml.EmailAddressNameIDFormat),
+		UserEmail:    email,
+		Groups:       groups,
+	}
+
+	loginURL, err := f.provider.LoginURL(context.Background(), "acme", false)
+	if err != nil {
+		t.Fatalf("LoginURL: %v", err)
+	}
+
+	recorder := httptest.NewRecorder()
+	f.idp.ServeSSO(recorder, httptest.NewRequest(http.MethodGet, loginURL, nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("idp responded %d: %s", recorder.Code, recorder.Body.String())
+	}
+
+	form := url.Values{}
+	for _, match := range formFieldPattern.FindAllStringSubmatch(recorder.Body.String(), -1) {
+		form.Set(match[1], html.UnescapeString(match[2]))
+	}
+	if form.Get("SAMLResponse") == "" || form.Get("RelayState") == "" {
+		t.Fatalf("idp response has no form: %s", recorder.Body.String())
+	}
+	return form
+}
+
+func (f *samlFixture) postACS(form url.Values) (*Identity, error) {
+	request := httptest.NewRequest(http.MethodPost, "https://sp.example/api/v1/auth/saml/acme/acs", strings.NewReader(form.Encode()))
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return f.provider.ParseResponse(context.Background(), "acme", request)
+}
+
+func TestSAMLProviderSignIn(t *testing.T) {
+	f := newSAMLFixture(t)
+
+	identity, err := f.postACS(f.signIn(t, "Ada@Acme.Example", "admins"))
+	if err != nil {
+		t.Fatalf("ParseResponse: %v", err)
+	}
+	if identity.Email != "ada@acme.example" {
+		t.Errorf("email = %q", identity.Email)
+	}
+	if identity.Provider != models.AuthProviderSAML {
+		t.Errorf("provider = %q", identity.Provider)
+	}
+	if identity.Role != models.RoleAdmin {
+		t.Errorf("role = %q, want %q", identity.Role, models.RoleAdmin)
+	}
+	if identity.Issuer != f.idp.Metadata().EntityID || identity.Subject != "Ada@Acme.Example" {
+		t.Errorf("issuer, subject = %q, %q", identity.Issuer, identity.Subject)
+	}
+}
+
+func TestSAMLProviderRejectsReplay(t *testing.T) {
+	f := newSAMLFixture(t)
+	form := f.signIn(t, "ada@acme.example")
+
+	if _, err := f.postACS(form); err != nil {
+		t.Fatalf("first ParseResponse: %v", err)
+	}
+	if _, err := f.postACS(form); !errors.Is(err, ErrSAMLRequestNotFound) {
+		t.Fatalf("replayed response: err = %v, want ErrSAMLRequestNotFound", err)
+	}
+}
+
+func TestSAMLProviderRejectsForeignDomain(t *testing.T) {
+	f := newSAMLFixture(t)
+
+	if _, err := f.postACS(f.signIn(t, "mallory@evil.example")); !errors.Is(err, ErrSAMLDomainMismatch) {
+		t.Fatalf("err = %v, want ErrSAMLDomainMismatch", err)
+	}
+}
+
+func TestSAMLProviderRejectsUntrustedSigner(t *testing.T) {
+	f := newSAMLFixture(t)
+
+	// Same issuer, but a key that is not in the configured metadata
+	f.idp.Key, f.idp.Certificate = newTestCertificate(t, "idp.acme.example")
+
+	if _, err := f.postACS(f.signIn(t, "ada@acme.example")); err == nil {
+		t.Fatal("response signed by an unknown key accepted")
+	}
+}
+
+func TestSAMLProviderUnknownOrganization(t *testing.T) {
+	f := newSAMLFixture(t)
+
+	if _, err := f.provider.LoginURL(context.Background(), "globex", false); !errors.Is(err, ErrSAMLConnectionNotFound) {
+		t.Fatalf("err = %v, want ErrSAMLConnectionNotFound", err)
+	}
+	if _, err := f.provider.OrganizationForEmail("ada@globex.example"); !errors.Is(err, ErrSAMLConnectionNotFound) {
+		t.Fatalf("err = %v, want ErrSAMLConnectionNotFound", err)
+	}
+}
+
+func TestSAMLProviderManagesOrganizationDomains(t *testing.T) {
+	f := newSAMLFixture(t)
+
+	if !f.provider.Managed("Ada@ACME.example") {
+		t.Error("organization domain not reported as managed")
+	}
+	if f.provider.Managed("ada@globex.example") || f.provider.Managed("no-at-sign") {
+		t.Error("foreign address reported as managed")
+	}
+
+	directories := DomainManagers{NewRouter(&PasswordAuthenticator{}), f.provider}
+	if !directories.Managed("ada@acme.example") || directories.Managed("ada@globex.example") {
+		t.Error("DomainManagers does not combine its members")
+	}
+}
+
+func TestIsMultiFactorContext(t *testing.T) {
+	tests := map[string]bool{
+		"https://refeds.org/profile/mfa":                                    true,
+		"http://schemas.microsoft.com/claims/multipleauthn":                 true,
+		"urn:oasis:names:tc:SAML:2.0:ac:classes:MobileTwoFactorContract":    true,
+		"urn:oasis:names:tc:SAML:2.0:ac:classes:PasswordProtectedTransport": false,
+		"urn:oasis:names:tc:SAML:2.0:ac:classes:Password":                   false,
+		"urn:example:nomfa":                    false,
+		"urn:example:multifactor-not-enforced": false,
+		"HTTPS://REFEDS.ORG/PROFILE/MFA":       false,
+		"":                                     false,
+	}
+	for classRef, want := range tests {
+		if got := isMultiFactorContext(classRef); got != want {
+			t.Errorf("isMultiFactorContext(%q) = %v, want %v", classRef, got, want)
+		}
+	}
+}
@@ -1,10 +1,13 @@
 package config
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
+	"net"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
@@ -12,11 +15,27 @@ import (
 )
 
 type Config struct {
-	Server   ServerConfig   `yaml:"server"`
-	Database DatabaseConfig `yaml:"database"`
-	Redis    RedisConfig    `yaml:"redis"`
-	JWT      JWTConfig      `yaml:"jwt"`
-	App      AppConfig      `yaml:"app"`
+	Server    ServerConfig    `yaml:"server"`
+	Database  DatabaseConfig  `yaml:"database"`
+	Redis     RedisConfig     `yaml:"redis"`
+	JWT       JWTConfig       `yaml:"jwt"`
+	Auth      AuthConfig      `yaml:"auth"`
+	Mail      MailConfig      `yaml:"mail"`
+	Email     EmailConfig     `yaml:"email"`
+	SMS       SMSConfig       `yaml:"sms"`
+	Deletion  DeletionConfig  `yaml:"deletion"`
+	Export    ExportConfig    `yaml:"export"`
+	Consent   ConsentConfig   `yaml:"consent"`
+	Blob      BlobConfig      `yaml:"blob"`
+	Avatar    AvatarConfig    `yaml:"avatar"`
+	Audit     AuditConfig     `yaml:"audit"`
+	Webhook   WebhookConfig   `yaml:"webhook"`
+	Events    EventsConfig    `yaml:"events"`
+	RateLimit RateLimitConfig `yaml:"rate_limit"`
+	Metrics   MetricsConfig   `yaml:"metrics"`
+	Tracing   TracingConfig   `yaml:"tracing"`
+	Health    HealthConfig    `yaml:"health"`
+	App       AppConfig       `yaml:"app"`
 }
 
 type ServerConfig struct {
@@ -25,7 +44,13 @@ type ServerConfig struct {
 	ReadTimeout     time.Duration `yaml:"read_timeout" env:"READ_TIMEOUT" env-default:"5s"`
 	WriteTimeout    time.Duration `yaml:"write_timeout" env:"WRITE_TIMEOUT" env-default:"10s"`
 	IdleTimeout     time.Duration `yaml:"idle_timeout" env:"IDLE_TIMEOUT" env-default:"60s"`
-	CORS            CORSConfig    `yaml:"cors"`
+	// ProblemDetails renders every error as RFC 7807 application/problem+json.
+	// Clients can also ask for it per request with Accept: application/problem+json.
+	ProblemDetails bool       `yaml:"problem_details" env:"PROBLEM_DETAILS" env-default:"false"`
+	CORS           CORSConfig `yaml:"cors"`
+	// TrustedProxies lists the proxy IPs and CIDRs whose X-Forwarded-For and X-Real-IP headers are believed.
+	// Without it the client IP is the address of the connection, so clients cannot pick their own.
+	TrustedProxies []string `yaml:"trusted_proxies" env:"TRUSTED_PROXIES"`
 }
 
 type RedisConfig struct {
@@ -52,12 +77,230 @@ type DatabaseConfig struct {
 }
 
 type JWTConfig struct {
-	Secret            string        `yaml:"secret" env:"JWT_SECRET" env-default:"your-super-secret-jwt-key-change-in-production"`
-	AccessExpiration  time.Duration `yaml:"expiration" env:"JWT_EXPIRY" env-default:"24h"`
-	RefreshExpiration time.Duration `yaml:"refresh_expiration" env:"JWT_REFRESH_EXPIRY" env-default:"168h"`
-	Issuer            string        `yaml:"issuer" env:"JWT_ISSUER" env-default:"user-management"`
+	Secret                  string        `yaml:"secret" env:"JWT_SECRET" env-default:"your-super-secret-jwt-key-change-in-production"`
+	AccessExpiration        time.Duration `yaml:"expiration" env:"JWT_EXPIRY" env-default:"24h"`
+	RefreshExpiration       time.Duration `yaml:"refresh_expiration" env:"JWT_REFRESH_EXPIRY" env-default:"168h"`
+	ImpersonationExpiration time.Duration `yaml:"impersonation_expiration" env:"JWT_IMPERSONATION_EXPIRY" env-default:"15m"`
+	Issuer                  string        `yaml:"issuer" env:"JWT_ISSUER" env-default:"user-management"`
 }
 
+type AuthConfig struct {
+	LDAP         LDAPConfig        `yaml:"ldap"`
+	SAML         SAMLConfig        `yaml:"saml"`
+	MagicLink    MagicLinkConfig   `yaml:"magic_link"`
+	EmailChange  EmailChangeConfig `yaml:"email_change"`
+	Phone        PhoneConfig       `yaml:"phone"`
+	StepUpMaxAge time.Duration     `yaml:"step_up_max_age" env:"AUTH_STEP_UP_MAX_AGE" env-default:"5m"`
+	// StepUpMFAMaxAge is the longer window in which a sign-in with MFA still satisfies step-up
+	StepUpMFAMaxAge time.Duration `yaml:"step_up_mfa_max_age" env:"AUTH_STEP_UP_MFA_MAX_AGE" env-default:"1h"`
+}
+
+type LDAPConfig struct {
+	Enabled            bool              `yaml:"enabled" env:"LDAP_ENABLED" env-default:"false"`
+	URL                string            `yaml:"url" env:"LDAP_URL"`
+	StartTLS           bool              `yaml:"start_tls" env:"LDAP_START_TLS" env-default:"false"`
+	InsecureSkipVerify bool              `yaml:"insecure_skip_verify" env:"LDAP_INSECURE_SKIP_VERIFY" env-default:"false"`
+	Timeout            time.Duration     `yaml:"timeout" env:"LDAP_TIMEOUT" env-default:"5s"`
+	BindDN             string            `yaml:"bind_dn" env:"LDAP_BIND_DN"`
+	BindPassword       string            `yaml:"bind_password" env:"LDAP_BIND_PASSWORD"`
+	BaseDN             string            `yaml:"base_dn" env:"LDAP_BASE_DN"`
+	UserFilter         string            `yaml:"user_filter" env:"LDAP_USER_FILTER" env-default:"(mail=%s)"`
+	EmailAttribute     string            `yaml:"email_attribute" env:"LDAP_EMAIL_ATTRIBUTE" env-default:"mail"`
+	GroupAttribute     string            `yaml:"group_attribute" env:"LDAP_GROUP_ATTRIBUTE" env-default:"memberOf"`
+	GroupRoles         map[string]string `yaml:"group_roles" env:"LDAP_GROUP_ROLES"`
+	DefaultRole        string            `yaml:"default_role" env:"LDAP_DEFAULT_ROLE" env-default:"user"`
+	Domains            []string          `yaml:"domains" env:"LDAP_DOMAINS"`
+}
+
+type SAMLConfig struct {
+	Enabled         bool             `yaml:"enabled" env:"SAML_ENABLED" env-default:"false"`
+	RootURL         string           `yaml:"root_url" env:"SAML_ROOT_URL"`
+	EntityID        string           `yaml:"entity_id" env:"SAML_ENTITY_ID"`
+	CertFile        string           `yaml:"cert_file" env:"SAML_CERT_FILE"`
+	KeyFile         string           `yaml:"key_file" env:"SAML_KEY_FILE"`
+	RequestTTL      time.Duration    `yaml:"request_ttl" env:"SAML_REQUEST_TTL" env-default:"10m"`
+	ConnectionsFile string           `yaml:"connections_file" env:"SAML_CONNECTIONS_FILE"`
+	Connections     []SAMLConnection `yaml:"connections"`
+}
+
+// SAMLConnection describes the identity provider of one organization
+type SAMLConnection struct {
+	Organization   string            `yaml:"organization" json:"organization"`
+	Domains        []string          `yaml:"domains" json:"domains"`
+	MetadataURL    string            `yaml:"metadata_url" json:"metadata_url"`
+	MetadataFile   string            `yaml:"metadata_file" json:"metadata_file"`
+	EmailAttribute string            `yaml:"email_attribute" json:"email_attribute"`
+	GroupAttribute string            `yaml:"group_attribute" json:"group_attribute"`
+	GroupRoles     map[string]string `yaml:"group_roles" json:"group_roles"`
+	DefaultRole    string            `yaml:"default_role" json:"default_role"`
+}
+
+type MagicLinkConfig struct {
+	URL         string        `yaml:"url" env:"MAGIC_LINK_URL" env-default:"http://localhost:3000/auth/magic-link"`
+	TTL         time.Duration `yaml:"ttl" env:"MAGIC_LINK_TTL" env-default:"15m"`
+	RateLimit   int           `yaml:"rate_limit" env:"MAGIC_LINK_RATE_LIMIT" env-default:"5"`
+	RateWindow  time.Duration `yaml:"rate_window" env:"MAGIC_LINK_RATE_WINDOW" env-default:"1h"`
+	BindBrowser bool          `yaml:"bind_browser" env:"MAGIC_LINK_BIND_BROWSER" env-default:"true"`
+}
+
+type EmailChangeConfig struct {
+	ConfirmURL   string        `yaml:"confirm_url" env:"EMAIL_CHANGE_CONFIRM_URL" env-default:"http://localhost:3000/account/email/confirm"`
+	CancelURL    string        `yaml:"cancel_url" env:"EMAIL_CHANGE_CANCEL_URL" env-default:"http://localhost:3000/account/email/cancel"`
+	TTL          time.Duration `yaml:"ttl" env:"EMAIL_CHANGE_TTL" env-default:"24h"`
+	RevertWindow time.Duration `yaml:"revert_window" env:"EMAIL_CHANGE_REVERT_WINDOW" env-default:"168h"`
+}
+
+type PhoneConfig struct {
+	CodeTTL     time.Duration `yaml:"code_ttl" env:"PHONE_CODE_TTL" env-default:"10m"`
+	MaxAttempts int           `yaml:"max_attempts" env:"PHONE_CODE_MAX_ATTEMPTS" env-default:"5"`
+	// RateLimit caps the codes sent to one number, IPRateLimit those requested from one IP, per RateWindow
+	RateLimit   int           `yaml:"rate_limit" env:"PHONE_CODE_RATE_LIMIT" env-default:"3"`
+	IPRateLimit int           `yaml:"ip_rate_limit" env:"PHONE_CODE_IP_RATE_LIMIT" env-default:"10"`
+	RateWindow  time.Duration `yaml:"rate_window" env:"PHONE_CODE_RATE_WINDOW" env-default:"1h"`
+}
+
+type MailConfig struct {
+	Driver       string `yaml:"driver" env:"MAIL_DRIVER" env-default:"memory"`
+	From         string `yaml:"from" env:"MAIL_FROM" env-default:"no-reply@localhost"`
+	SMTPHost     string `yaml:"smtp_host" env:"SMTP_HOST"`
+	SMTPPort     string `yaml:"smtp_port" env:"SMTP_PORT" env-default:"587"`
+	SMTPUsername string `yaml:"smtp_username" env:"SMTP_USERNAME"`
+	SMTPPassword string `yaml:"smtp_password" env:"SMTP_PASSWORD"`
+}
+
+type EmailConfig struct {
+	// CanonicalRules maps a domain to its provider rule, e.g. "gmail.com" to "dots,plus"
+	CanonicalRules        map[string]string `yaml:"canonical_rules" env:"EMAIL_CANONICAL_RULES"`
+	AllowedDomains        []string          `yaml:"allowed_domains" env:"EMAIL_ALLOWED_DOMAINS"`
+	DeniedDomains         []string          `yaml:"denied_domains" env:"EMAIL_DENIED_DOMAINS"`
+	BlockDisposable       bool              `yaml:"block_disposable" env:"EMAIL_BLOCK_DISPOSABLE" env-default:"true"`
+	DisposableDomainsFile string            `yaml:"disposable_domains_file" env:"EMAIL_DISPOSABLE_DOMAINS_FILE"`
+}
+
+type SMSConfig struct {
+	Driver         string        `yaml:"driver" env:"SMS_DRIVER" env-default:"console"`
+	WebhookURL     string        `yaml:"webhook_url" env:"SMS_WEBHOOK_URL"`
+	WebhookSecret  string        `yaml:"webhook_secret" env:"SMS_WEBHOOK_SECRET"`
+	WebhookTimeout time.Duration `yaml:"webhook_timeout" env:"SMS_WEBHOOK_TIMEOUT" env-default:"10s"`
+}
+
+type DeletionConfig struct {
+	GracePeriod   time.Duration `yaml:"grace_period" env:"ACCOUNT_DELETION_GRACE_PERIOD" env-default:"720h"`
+	PurgeInterval time.Duration `yaml:"purge_interval" env:"ACCOUNT_PURGE_INTERVAL" env-default:"1h"`
+	PurgeMode     string        `yaml:"purge_mode" env:"ACCOUNT_PURGE_MODE" env-default:"anonymize"`
+}
+
+type ExportConfig struct {
+	DownloadURL  string        `yaml:"download_url" env:"DATA_EXPORT_DOWNLOAD_URL" env-default:"http://localhost:8080/api/v1/exports"`
+	LinkTTL      time.Duration `yaml:"link_ttl" env:"DATA_EXPORT_LINK_TTL" env-default:"24h"`
+	PollInterval time.Duration `yaml:"poll_interval" env:"DATA_EXPORT_POLL_INTERVAL" env-default:"30s"`
+	ClaimTimeout time.Duration `yaml:"claim_timeout" env:"DATA_EXPORT_CLAIM_TIMEOUT" env-default:"15m"`
+	MaxAttempts  int           `yaml:"max_attempts" env:"DATA_EXPORT_MAX_ATTEMPTS" env-default:"3"`
+	SigningKey   string        `yaml:"signing_key" env:"DATA_EXPORT_SIGNING_KEY"`
+}
+
+type ConsentConfig struct {
+	// Purposes are the consents users can give or withdraw, e.g. "terms" or "marketing". None by default,
+	// which leaves recording off until a deployment lists its own purposes.
+	Purposes []string `yaml:"purposes" env:"CONSENT_PURPOSES"`
+}
+
+type BlobConfig struct {
+	Driver      string `yaml:"driver" env:"BLOB_DRIVER" env-default:"local"`
+	LocalDir    string `yaml:"local_dir" env:"BLOB_LOCAL_DIR" env-default:"./data/blobs"`
+	PublicURL   string `yaml:"public_url" env:"BLOB_PUBLIC_URL" env-default:"http://localhost:8080/blobs"`
+	S3Endpoint  string `yaml:"s3_endpoint" env:"S3_ENDPOINT"`
+	S3Region    string `yaml:"s3_region" env:"S3_REGION" env-default:"us-east-1"`
+	S3Bucket    string `yaml:"s3_bucket" env:"S3_BUCKET"`
+	S3AccessKey string `yaml:"s3_access_key" env:"S3_ACCESS_KEY_ID"`
+	S3SecretKey string `yaml:"s3_secret_key" env:"S3_SECRET_ACCESS_KEY"`
+	S3PathStyle bool   `yaml:"s3_path_style" env:"S3_FORCE_PATH_STYLE" env-default:"false"`
+}
+
+type AvatarConfig struct {
+	MaxBytes int64 `yaml:"max_bytes" env:"AVATAR_MAX_BYTES" env-default:"5242880"`
+}
+
+type AuditConfig struct {
+	// HashChain links every entry to the previous one so edits and deletions can be detected
+	HashChain bool `yaml:"hash_chain" env:"AUDIT_HASH_CHAIN" env-default:"false"`
+	// PseudonymKey keys the HMAC that replaces personal data such as emails and names in entries
+	PseudonymKey string `yaml:"pseudonym_key" env:"AUDIT_PSEUDONYM_KEY"`
+}
+
+type WebhookConfig struct {
+	Timeout      time.Duration `yaml:"timeout" env:"WEBHOOK_TIMEOUT" env-default:"10s"`
+	MaxAttempts  int           `yaml:"max_attempts" env:"WEBHOOK_MAX_ATTEMPTS" env-default:"10"`
+	BackoffBase  time.Duration `yaml:"backoff_base" env:"WEBHOOK_BACKOFF_BASE" env-default:"30s"`
+	BackoffMax   time.Duration `yaml:"backoff_max" env:"WEBHOOK_BACKOFF_MAX" env-default:"6h"`
+	PollInterval time.Duration `yaml:"poll_interval" env:"WEBHOOK_POLL_INTERVAL" env-default:"5s"`
+	BatchSize    int           `yaml:"batch_size" env:"WEBHOOK_BATCH_SIZE" env-default:"50"`
+	// AllowPrivateTargets lets subscriptions reach loopback and private addresses, for local development
+	AllowPrivateTargets bool `yaml:"allow_private_targets" env:"WEBHOOK_ALLOW_PRIVATE_TARGETS" env-default:"false"`
+}
+
+type EventsConfig struct {
+	// Driver selects the broker: none, memory, nats, kafka or redis
+	Driver       string        `yaml:"driver" env:"EVENTS_DRIVER" env-default:"none"`
+	Source       string        `yaml:"source" env:"EVENTS_SOURCE" env-default:"/user-management"`
+	PollInterval time.Duration `yaml:"poll_interval" env:"EVENTS_POLL_INTERVAL" env-default:"1s"`
+	BatchSize    int           `yaml:"batch_size" env:"EVENTS_BATCH_SIZE" env-default:"100"`
+	// PublishLease bounds how long an instance may take to publish a claimed batch before another may claim it
+	PublishLease time.Duration `yaml:"publish_lease" env:"EVENTS_PUBLISH_LEASE" env-default:"1m"`
+	// Retention is how long outbox events are kept once they are published and their deliveries have finished
+	Retention time.Duration `yaml:"retention" env:"EVENTS_RETENTION" env-default:"168h"`
+
+	NATSURL       string `yaml:"nats_url" env:"NATS_URL" env-default:"nats://localhost:4222"`
+	NATSPrefix    string `yaml:"nats_subject_prefix" env:"NATS_SUBJECT_PREFIX" env-default:"users"`
+	NATSJetStream bool   `yaml:"nats_jetstream" env:"NATS_JETSTREAM" env-default:"true"`
+
+	KafkaBrokers []string `yaml:"kafka_brokers" env:"KAFKA_BROKERS"`
+	KafkaTopic   string   `yaml:"kafka_topic" env:"KAFKA_TOPIC" env-default:"user-events"`
+
+	RedisStream       string `yaml:"redis_stream" env:"REDIS_STREAM" env-default:"user-events"`
+	RedisStreamMaxLen int64  `yaml:"redis_stream_max_len" env:"REDIS_STREAM_MAX_LEN" env-default:"100000"`
+}
+
+type RateLimitConfig struct {
+	Enabled bool `yaml:"enabled" env:"RATE_LIMIT_ENABLED" env-default:"true"`
+	// Policies maps a route group to its limit, e.g. "auth=60/1m:ip;users=600/1m:user"
+	Policies map[string]RateLimitPolicy `yaml:"policies" env:"RATE_LIMIT_POLICIES"`
+}
+
+// RateLimitPolicy allows Limit requests per Window for each key
+type RateLimitPolicy struct {
+	Limit  int           `yaml:"limit"`
+	Window time.Duration `yaml:"window"`
+	// KeyBy is ip or user
+	KeyBy string `yaml:"key_by"`
+}
+
+type MetricsConfig struct {
+	Enabled bool `yaml:"enabled" env:"METRICS_ENABLED" env-default:"true"`
+	// Port serves /metrics on a separate listener; when empty it is served on the API port and requires Token
+	Port  string `yaml:"port" env:"METRICS_PORT" env-default:"9090"`
+	Token string `yaml:"token" env:"METRICS_TOKEN"`
+}
+
+type TracingConfig struct {
+	Enabled bool `yaml:"enabled" env:"TRACING_ENABLED" env-default:"false"`
+	// Exporter is otlp, stdout or memory
+	Exporter    string `yaml:"exporter" env:"TRACING_EXPORTER" env-default:"otlp"`
+	ServiceName string `yaml:"service_name" env:"OTEL_SERVICE_NAME" env-default:"user-management"`
+	// OTLPEndpoint is the OTLP/HTTP URL, e.g. http://collector:4318; empty uses the OTEL_EXPORTER_OTLP_* variables
+	OTLPEndpoint string  `yaml:"otlp_endpoint" env:"TRACING_OTLP_ENDPOINT"`
+	SampleRatio  float64 `yaml:"sample_ratio" env:"TRACING_SAMPLE_RATIO" env-default:"1"`
+}
+
+type HealthConfig struct {
+	// CheckTimeout bounds each readiness check
+	CheckTimeout time.Duration `yaml:"check_timeout" env:"HEALTH_CHECK_TIMEOUT" env-default:"2s"`
+	// ShutdownDelay keeps serving while /readyz reports unready, so load balancers stop routing first
+	ShutdownDelay time.Duration `yaml:"shutdown_delay" env:"HEALTH_SHUTDOWN_DELAY" env-default:"5s"`
+}
+
+const defaultRateLimitPolicies = "auth=60/1m:ip;signin=10/1m:ip;signup=5/1h:ip;users=600/1m:user;admin=300/1m:user"
+
 type AppConfig struct {
 	Environment string `yaml:"environment" env:"APP_ENV" env-default:"development"`
 	LogLevel    string `yaml:"log_level" env:"LOG_LEVEL" env-default:"info"`
@@ -100,6 +343,13 @@ func (c *Config) Validate() error {
 	if c.Server.Port == "" {
 		return errors.New("server.port is required")
 	}
+	for _, proxy := range c.Server.TrustedProxies {
+		if net.ParseIP(proxy) == nil {
+			if _, _, err := net.ParseCIDR(proxy); err != nil {
+				return fmt.Errorf("invalid TRUSTED_PROXIES entry %q, expected an IP address or CIDR", proxy)
+			}
+		}
+	}
 
 	// --- Database ---
 	db := c.Database
@@ -122,6 +372,238 @@ func (c *Config) Validate() error {
 		return errors.New("insecure JWT secret used in production")
 	}
 
+	// --- Auth ---
+	ldapCfg := c.Auth.LDAP
+	if ldapCfg.Enabled {
+		if ldapCfg.URL == "" || ldapCfg.BaseDN == "" {
+			return errors.New("LDAP_URL and LDAP_BASE_DN are required when LDAP is enabled")
+		}
+		if len(ldapCfg.Domains) == 0 {
+			return errors.New("LDAP_DOMAINS is required when LDAP is enabled")
+		}
+		if !strings.Contains(ldapCfg.UserFilter, "%s") {
+			return errors.New("LDAP_USER_FILTER must contain a %s placeholder for the email")
+		}
+	}
+
+	samlCfg := c.Auth.SAML
+	if samlCfg.Enabled {
+		if samlCfg.RootURL == "" || samlCfg.CertFile == "" || samlCfg.KeyFile == "" {
+			return errors.New("SAML_ROOT_URL, SAML_CERT_FILE and SAML_KEY_FILE are required when SAML is enabled")
+		}
+		for _, conn := range samlCfg.Connections {
+			if conn.Organization == "" {
+				return errors.New("every SAML connection requires an organization")
+			}
+			if conn.MetadataURL == "" && conn.MetadataFile == "" {
+				return fmt.Errorf("SAML connection %s requires metadata_url or metadata_file", conn.Organization)
+			}
+		}
+	}
+
+	if c.Auth.EmailChange.TTL <= 0 || c.Auth.EmailChange.RevertWindow < c.Auth.EmailChange.TTL {
+		return errors.New("EMAIL_CHANGE_TTL must be positive and EMAIL_CHANGE_REVERT_WINDOW at least as long")
+	}
+
+	if c.Auth.StepUpMaxAge <= 0 || c.Auth.StepUpMFAMaxAge < c.Auth.StepUpMaxAge {
+		return errors.New("AUTH_STEP_UP_MAX_AGE must be positive and AUTH_STEP_UP_MFA_MAX_AGE at least as long")
+	}
+
+	if c.Auth.Phone.CodeTTL <= 0 || c.Auth.Phone.MaxAttempts <= 0 {
+		return errors.New("PHONE_CODE_TTL and PHONE_CODE_MAX_ATTEMPTS must be positive")
+	}
+
+	// --- Mail ---
+	switch c.Mail.Driver {
+	case "memory":
+		// The memory driver drops every email, so nobody could verify or reset an account
+		if c.App.Environment == "production" {
+			return errors.New("MAIL_DRIVER=memory is not allowed in production")
+		}
+	case "smtp":
+		if c.Mail.SMTPHost == "" {
+			return errors.New("SMTP_HOST is required when MAIL_DRIVER is smtp")
+		}
+	default:
+		return fmt.Errorf("invalid MAIL_DRIVER: %s", c.Mail.Driver)
+	}
+
+	// --- SMS ---
+	switch c.SMS.Driver {
+	case "console":
+		// The console driver prints one-time codes to stdout, where anyone reading the logs can use them
+		if c.App.Environment == "production" {
+			return errors.New("SMS_DRIVER=console is not allowed in production")
+		}
+	case "webhook":
+		if c.SMS.WebhookURL == "" {
+			return errors.New("SMS_WEBHOOK_URL is required when SMS_DRIVER is webhook")
+		}
+	default:
+		return fmt.Errorf("invalid SMS_DRIVER: %s", c.SMS.Driver)
+	}
+
+	// --- Deletion ---
+	switch c.Deletion.PurgeMode {
+	case "anonymize", "delete":
+	default:
+		return fmt.Errorf("invalid ACCOUNT_PURGE_MODE: %s", c.Deletion.PurgeMode)
+	}
+
+	if c.Deletion.PurgeInterval <= 0 {
+		return errors.New("ACCOUNT_PURGE_INTERVAL must be positive")
+	}
+
+	// --- Export ---
+	if c.Export.LinkTTL <= 0 {
+		return errors.New("DATA_EXPORT_LINK_TTL must be positive")
+	}
+
+	if c.Export.PollInterval <= 0 {
+		return errors.New("DATA_EXPORT_POLL_INTERVAL must be positive")
+	}
+
+	if c.Export.ClaimTimeout <= 0 || c.Export.MaxAttempts <= 0 {
+		return errors.New("DATA_EXPORT_CLAIM_TIMEOUT and DATA_EXPORT_MAX_ATTEMPTS must be positive")
+	}
+
+	// Download links must not be forgeable by anyone holding the token key, and rotating one must not
+	// invalidate the other
+	if c.App.Environment == "production" &&
+		(c.Export.SigningKey == "" || c.Export.SigningKey == c.JWT.Secret) {
+		return errors.New("DATA_EXPORT_SIGNING_KEY must be set to its own key in production")
+	}
+
+	// --- Audit ---
+	// Pseudonyms outlive purged accounts, so a leaked token key must not let anyone reverse them
+	if c.App.Environment == "production" &&
+		(c.Audit.PseudonymKey == "" || c.Audit.PseudonymKey == c.JWT.Secret) {
+		return errors.New("AUDIT_PSEUDONYM_KEY must be set to its own key in production")
+	}
+
+	// --- Blob storage ---
+	switch c.Blob.Driver {
+	case "local":
+		if c.Blob.LocalDir == "" {
+			return errors.New("BLOB_LOCAL_DIR is required when BLOB_DRIVER=local")
+		}
+	case "s3":
+		if c.Blob.S3Endpoint == "" || c.Blob.S3Bucket == "" {
+			return errors.New("S3_ENDPOINT and S3_BUCKET are required when BLOB_DRIVER=s3")
+		}
+		if c.Blob.S3AccessKey == "" || c.Blob.S3SecretKey == "" {
+			return errors.New("S3_ACCESS_KEY_ID and S3_SECRET_ACCESS_KEY are required when BLOB_DRIVER=s3")
+		}
+	default:
+		return fmt.Errorf("invalid BLOB_DRIVER: %s", c.Blob.Driver)
+	}
+
+	if c.Avatar.MaxBytes <= 0 {
+		return errors.New("AVATAR_MAX_BYTES must be positive")
+	}
+
+	// --- Webhooks ---
+	if c.Webhook.Timeout <= 0 || c.Webhook.PollInterval <= 0 {
+		return errors.New("WEBHOOK_TIMEOUT and WEBHOOK_POLL_INTERVAL must be positive")
+	}
+
+	if c.Webhook.MaxAttempts <= 0 || c.Webhook.BatchSize <= 0 {
+		return errors.New("WEBHOOK_MAX_ATTEMPTS and WEBHOOK_BATCH_SIZE must be positive")
+	}
+
+	if c.Webhook.BackoffBase <= 0 || c.Webhook.BackoffMax < c.Webhook.BackoffBase {
+		return errors.New("WEBHOOK_BACKOFF_BASE must be positive and no greater than WEBHOOK_BACKOFF_MAX")
+	}
+
+	if c.Webhook.AllowPrivateTargets && c.App.Environment == "production" {
+		return errors.New("WEBHOOK_ALLOW_PRIVATE_TARGETS is not allowed in production")
+	}
+
+	// --- Events ---
+	switch c.Events.Driver {
+	case "none":
+	case "memory":
+		// The relay would mark events as published and the pruner would delete them
+		if c.App.Environment == "production" {
+			return errors.New("EVENTS_DRIVER=memory is not allowed in production")
+		}
+	case "nats":
+		if c.Events.NATSURL == "" || c.Events.NATSPrefix == "" {
+			return errors.New("NATS_URL and NATS_SUBJECT_PREFIX are required when EVENTS_DRIVER=nats")
+		}
+	case "kafka":
+		if len(c.Events.KafkaBrokers) == 0 || c.Events.KafkaTopic == "" {
+			return errors.New("KAFKA_BROKERS and KAFKA_TOPIC are required when EVENTS_DRIVER=kafka")
+		}
+	case "redis":
+		if c.Events.RedisStream == "" || c.Events.RedisStreamMaxLen <= 0 {
+			return errors.New("REDIS_STREAM and a positive REDIS_STREAM_MAX_LEN are required when EVENTS_DRIVER=redis")
+		}
+	default:
+		return fmt.Errorf("invalid EVENTS_DRIVER: %s", c.Events.Driver)
+	}
+
+	if c.Events.Driver != "none" && (c.Events.PollInterval <= 0 || c.Events.BatchSize <= 0 || c.Events.Source == "") {
+		return errors.New("EVENTS_SOURCE, a positive EVENTS_POLL_INTERVAL and EVENTS_BATCH_SIZE are required to publish events")
+	}
+	if c.Events.Driver != "none" && c.Events.PublishLease <= 0 {
+		return errors.New("EVENTS_PUBLISH_LEASE must be positive")
+	}
+
+	if c.Events.Retention <= 0 {
+		return errors.New("EVENTS_RETENTION must be positive")
+	}
+
+	// --- Rate limiting ---
+	for name, policy := range c.RateLimit.Policies {
+		if policy.Limit <= 0 || policy.Window < time.Second {
+			return fmt.Errorf("rate limit policy %q needs a positive limit and a window of at least 1s", name)
+		}
+
+		switch policy.KeyBy {
+		case "ip", "user":
+		default:
+			return fmt.Errorf("rate limit policy %q has invalid key %q, expected ip or user", name, policy.KeyBy)
+		}
+	}
+
+	// --- Metrics ---
+	if c.Metrics.Enabled {
+		if c.Metrics.Port == "" && c.Metrics.Token == "" {
+			return errors.New("METRICS_TOKEN is required when /metrics is served on the API port")
+		}
+
+		if c.Metrics.Port == c.Server.Port {
+			return errors.New("METRICS_PORT must differ from PORT, leave it empty to serve /metrics on the API port")
+		}
+	}
+
+	// --- Tracing ---
+	if c.Tracing.Enabled {
+		switch c.Tracing.Exporter {
+		case "otlp", "stdout":
+		case "memory":
+			// Spans would only pile up in the process, where nobody can see them
+			if c.App.Environment == "production" {
+				return errors.New("TRACING_EXPORTER=memory is not allowed in production")
+			}
+		default:
+			return fmt.Errorf("invalid TRACING_EXPORTER: %s", c.Tracing.Exporter)
+		}
+
+		if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+			return errors.New("TRACING_SAMPLE_RATIO must be between 0 and 1")
+		}
+	}
+
+	// --- Health ---
+	if c.Health.CheckTimeout <= 0 {
+		return errors.New("HEALTH_CHECK_TIMEOUT must be positive")
+	}
+	if c.Health.ShutdownDelay < 0 {
+		return errors.New("HEALTH_SHUTDOWN_DELAY must not be negative")
+	}
+
 	// --- App ---
 	switch c.App.Environment {
 	case "development", "staging", "production":
@@ -144,6 +626,8 @@ func loadFromEnv(cfg *Config) error {
 	cfg.Server.ReadTimeout, _ = time.ParseDuration(getEnv("READ_TIMEOUT", "5s"))
 	cfg.Server.WriteTimeout, _ = time.ParseDuration(getEnv("WRITE_TIMEOUT", "10s"))
 	cfg.Server.IdleTimeout, _ = time.ParseDuration(getEnv("IDLE_TIMEOUT", "60s"))
+	cfg.Server.ProblemDetails = getEnvBool("PROBLEM_DETAILS", false)
+	cfg.Server.TrustedProxies = getEnvSlice("TRUSTED_PROXIES", nil)
 
 	// CORS
 	cfg.Server.CORS.AllowOrigins = getEnvSlice("CORS_ALLOW_ORIGINS", []string{"*"})
@@ -151,7 +635,7 @@ func loadFromEnv(cfg *Config) error {
 		"GET", "POST", "PUT", "DELETE", "OPTIONS",
 	})
 	cfg.Server.CORS.AllowHeaders = getEnvSlice("CORS_ALLOW_HEADERS", []string{
-		"Origin", "Content-Type", "Accept", "Authorization", "X-Requested-With",
+		"Origin", "Content-Type", "Accept", "Authorization", "X-Requested-With", "X-Request-ID",
 	})
 
 	cfg.Database.Host = getEnv("DB_HOST", "localhost")
@@ -172,8 +656,189 @@ func loadFromEnv(cfg *Config) error {
 	cfg.JWT.Secret = getEnv("JWT_SECRET", "your-super-secret-jwt-key-change-in-production")
 	cfg.JWT.AccessExpiration, _ = time.ParseDuration(getEnv("JWT_ACCESS_EXPIRY", "24h"))
 	cfg.JWT.RefreshExpiration, _ = time.ParseDuration(getEnv("JWT_REFRESH_EXPIRY", "168h"))
+	cfg.JWT.ImpersonationExpiration, _ = time.ParseDuration(getEnv("JWT_IMPERSONATION_EXPIRY", "15m"))
 	cfg.JWT.Issuer = getEnv("JWT_ISSUER", "user-management")
 
+	// LDAP
+	cfg.Auth.LDAP.Enabled = getEnvBool("LDAP_ENABLED", false)
+	cfg.Auth.LDAP.URL = getEnv("LDAP_URL", "")
+	cfg.Auth.LDAP.StartTLS = getEnvBool("LDAP_START_TLS", false)
+	cfg.Auth.LDAP.InsecureSkipVerify = getEnvBool("LDAP_INSECURE_SKIP_VERIFY", false)
+	cfg.Auth.LDAP.Timeout, _ = time.ParseDuration(getEnv("LDAP_TIMEOUT", "5s"))
+	cfg.Auth.LDAP.BindDN = getEnv("LDAP_BIND_DN", "")
+	cfg.Auth.LDAP.BindPassword = getEnv("LDAP_BIND_PASSWORD", "")
+	cfg.Auth.LDAP.BaseDN = getEnv("LDAP_BASE_DN", "")
+	cfg.Auth.LDAP.UserFilter = getEnv("LDAP_USER_FILTER", "(mail=%s)")
+	cfg.Auth.LDAP.EmailAttribute = getEnv("LDAP_EMAIL_ATTRIBUTE", "mail")
+	cfg.Auth.LDAP.GroupAttribute = getEnv("LDAP_GROUP_ATTRIBUTE", "memberOf")
+	// Group DNs contain commas, so entries are separated by ";" (e.g. "cn=admins,dc=corp,dc=com:admin")
+	cfg.Auth.LDAP.GroupRoles = getEnvMap("LDAP_GROUP_ROLES", ";", ":")
+	cfg.Auth.LDAP.DefaultRole = getEnv("LDAP_DEFAULT_ROLE", "user")
+	cfg.Auth.LDAP.Domains = getEnvSlice("LDAP_DOMAINS", nil)
+
+	// SAML
+	cfg.Auth.SAML.Enabled = getEnvBool("SAML_ENABLED", false)
+	cfg.Auth.SAML.RootURL = strings.TrimSuffix(getEnv("SAML_ROOT_URL", ""), "/")
+	cfg.Auth.SAML.EntityID = getEnv("SAML_ENTITY_ID", "")
+	cfg.Auth.SAML.CertFile = getEnv("SAML_CERT_FILE", "")
+	cfg.Auth.SAML.KeyFile = getEnv("SAML_KEY_FILE", "")
+	cfg.Auth.SAML.RequestTTL, _ = time.ParseDuration(getEnv("SAML_REQUEST_TTL", "10m"))
+	cfg.Auth.SAML.ConnectionsFile = getEnv("SAML_CONNECTIONS_FILE", "")
+	if cfg.Auth.SAML.ConnectionsFile != "" {
+		connections, err := loadSAMLConnections(cfg.Auth.SAML.ConnectionsFile)
+		if err != nil {
+			return err
+		}
+		cfg.Auth.SAML.Connections = connections
+	}
+
+	cfg.Auth.StepUpMaxAge, _ = time.ParseDuration(getEnv("AUTH_STEP_UP_MAX_AGE", "5m"))
+	cfg.Auth.StepUpMFAMaxAge, _ = time.ParseDuration(getEnv("AUTH_STEP_UP_MFA_MAX_AGE", "1h"))
+
+	// Magic link
+	cfg.Auth.MagicLink.URL = getEnv("MAGIC_LINK_URL", "http://localhost:3000/auth/magic-link")
+	cfg.Auth.MagicLink.TTL, _ = time.ParseDuration(getEnv("MAGIC_LINK_TTL", "15m"))
+	cfg.Auth.MagicLink.RateLimit, _ = strconv.Atoi(getEnv("MAGIC_LINK_RATE_LIMIT", "5"))
+	cfg.Auth.MagicLink.RateWindow, _ = time.ParseDuration(getEnv("MAGIC_LINK_RATE_WINDOW", "1h"))
+	cfg.Auth.MagicLink.BindBrowser = getEnvBool("MAGIC_LINK_BIND_BROWSER", true)
+
+	// Email change
+	cfg.Auth.EmailChange.ConfirmURL = getEnv("EMAIL_CHANGE_CONFIRM_URL", "http://localhost:3000/account/email/confirm")
+	cfg.Auth.EmailChange.CancelURL = getEnv("EMAIL_CHANGE_CANCEL_URL", "http://localhost:3000/account/email/cancel")
+	cfg.Auth.EmailChange.TTL, _ = time.ParseDuration(getEnv("EMAIL_CHANGE_TTL", "24h"))
+	cfg.Auth.EmailChange.RevertWindow, _ = time.ParseDuration(getEnv("EMAIL_CHANGE_REVERT_WINDOW", "168h"))
+
+	// Phone verification
+	cfg.Auth.Phone.CodeTTL, _ = time.ParseDuration(getEnv("PHONE_CODE_TTL", "10m"))
+	cfg.Auth.Phone.MaxAttempts, _ = strconv.Atoi(getEnv("PHONE_CODE_MAX_ATTEMPTS", "5"))
+	cfg.Auth.Phone.RateLimit, _ = strconv.Atoi(getEnv("PHONE_CODE_RATE_LIMIT", "3"))
+	cfg.Auth.Phone.IPRateLimit, _ = strconv.Atoi(getEnv("PHONE_CODE_IP_RATE_LIMIT", "10"))
+	cfg.Auth.Phone.RateWindow, _ = time.ParseDuration(getEnv("PHONE_CODE_RATE_WINDOW", "1h"))
+
+	// Mail
+	cfg.Mail.Driver = getEnv("MAIL_DRIVER", "memory")
+	cfg.Mail.From = getEnv("MAIL_FROM", "no-reply@localhost")
+	cfg.Mail.SMTPHost = getEnv("SMTP_HOST", "")
+	cfg.Mail.SMTPPort = getEnv("SMTP_PORT", "587")
+	cfg.Mail.SMTPUsername = getEnv("SMTP_USERNAME", "")
+	cfg.Mail.SMTPPassword = getEnv("SMTP_PASSWORD", "")
+
+	// Email addresses
+	cfg.Email.CanonicalRules = getEnvMap("EMAIL_CANONICAL_RULES", ";", ":")
+	if len(cfg.Email.CanonicalRules) == 0 {
+		cfg.Email.CanonicalRules = map[string]string{
+			"gmail.com":      "dots,plus",
+			"googlemail.com": "dots,plus,domain=gmail.com",
+		}
+	}
+	cfg.Email.AllowedDomains = getEnvSlice("EMAIL_ALLOWED_DOMAINS", nil)
+	cfg.Email.DeniedDomains = getEnvSlice("EMAIL_DENIED_DOMAINS", nil)
+	cfg.Email.BlockDisposable = getEnvBool("EMAIL_BLOCK_DISPOSABLE", true)
+	cfg.Email.DisposableDomainsFile = getEnv("EMAIL_DISPOSABLE_DOMAINS_FILE", "")
+
+	// SMS
+	cfg.SMS.Driver = getEnv("SMS_DRIVER", "console")
+	cfg.SMS.WebhookURL = getEnv("SMS_WEBHOOK_URL", "")
+	cfg.SMS.WebhookSecret = getEnv("SMS_WEBHOOK_SECRET", "")
+	cfg.SMS.WebhookTimeout, _ = time.ParseDuration(getEnv("SMS_WEBHOOK_TIMEOUT", "10s"))
+
+	// Account deletion
+	cfg.Deletion.GracePeriod, _ = time.ParseDuration(getEnv("ACCOUNT_DELETION_GRACE_PERIOD", "720h"))
+	cfg.Deletion.PurgeInterval, _ = time.ParseDuration(getEnv("ACCOUNT_PURGE_INTERVAL", "1h"))
+	cfg.Deletion.PurgeMode = getEnv("ACCOUNT_PURGE_MODE", "anonymize")
+
+	// Data export
+	cfg.Export.DownloadURL = strings.TrimSuffix(getEnv("DATA_EXPORT_DOWNLOAD_URL", "http://localhost:8080/api/v1/exports"), "/")
+	cfg.Export.LinkTTL, _ = time.ParseDuration(getEnv("DATA_EXPORT_LINK_TTL", "24h"))
+	cfg.Export.PollInterval, _ = time.ParseDuration(getEnv("DATA_EXPORT_POLL_INTERVAL", "30s"))
+	cfg.Export.ClaimTimeout, _ = time.ParseDuration(getEnv("DATA_EXPORT_CLAIM_TIMEOUT", "15m"))
+	cfg.Export.MaxAttempts, _ = strconv.Atoi(getEnv("DATA_EXPORT_MAX_ATTEMPTS", "3"))
+	cfg.Export.SigningKey = getEnv("DATA_EXPORT_SIGNING_KEY", cfg.JWT.Secret)
+
+	// Blob storage
+	cfg.Blob.Driver = getEnv("BLOB_DRIVER", "local")
+	cfg.Blob.LocalDir = getEnv("BLOB_LOCAL_DIR", "./data/blobs")
+	cfg.Blob.PublicURL = getEnv("BLOB_PUBLIC_URL", "")
+	if cfg.Blob.PublicURL == "" && cfg.Blob.Driver == "local" {
+		cfg.Blob.PublicURL = "http://localhost:8080/blobs"
+	}
+	cfg.Blob.S3Endpoint = getEnv("S3_ENDPOINT", "")
+	cfg.Blob.S3Region = getEnv("S3_REGION", "us-east-1")
+	cfg.Blob.S3Bucket = getEnv("S3_BUCKET", "")
+	cfg.Blob.S3AccessKey = getEnv("S3_ACCESS_KEY_ID", "")
+	cfg.Blob.S3SecretKey = getEnv("S3_SECRET_ACCESS_KEY", "")
+	cfg.Blob.S3PathStyle = getEnvBool("S3_FORCE_PATH_STYLE", false)
+
+	// Avatars
+	cfg.Avatar.MaxBytes, _ = strconv.ParseInt(getEnv("AVATAR_MAX_BYTES", "5242880"), 10, 64)
+
+	// Consent
+	cfg.Consent.Purposes = getEnvSlice("CONSENT_PURPOSES", nil)
+
+	// Audit log
+	cfg.Audit.HashChain = getEnvBool("AUDIT_HASH_CHAIN", false)
+	cfg.Audit.PseudonymKey = getEnv("AUDIT_PSEUDONYM_KEY", cfg.JWT.Secret)
+
+	// Webhooks
+	cfg.Webhook.Timeout, _ = time.ParseDuration(getEnv("WEBHOOK_TIMEOUT", "10s"))
+	cfg.Webhook.MaxAttempts, _ = strconv.Atoi(getEnv("WEBHOOK_MAX_ATTEMPTS", "10"))
+	cfg.Webhook.BackoffBase, _ = time.ParseDuration(getEnv("WEBHOOK_BACKOFF_BASE", "30s"))
+	cfg.Webhook.BackoffMax, _ = time.ParseDuration(getEnv("WEBHOOK_BACKOFF_MAX", "6h"))
+	cfg.Webhook.PollInterval, _ = time.ParseDuration(getEnv("WEBHOOK_POLL_INTERVAL", "5s"))
+	cfg.Webhook.BatchSize, _ = strconv.Atoi(getEnv("WEBHOOK_BATCH_SIZE", "50"))
+	cfg.Webhook.AllowPrivateTargets = getEnvBool("WEBHOOK_ALLOW_PRIVATE_TARGETS", false)
+
+	// Event publishing
+	cfg.Events.Driver = getEnv("EVENTS_DRIVER", "none")
+	cfg.Events.Source = getEnv("EVENTS_SOURCE", "/user-management")
+	cfg.Events.PollInterval, _ = time.ParseDuration(getEnv("EVENTS_POLL_INTERVAL", "1s"))
+	cfg.Events.BatchSize, _ = strconv.Atoi(getEnv("EVENTS_BATCH_SIZE", "100"))
+	cfg.Events.PublishLease, _ = time.ParseDuration(getEnv("EVENTS_PUBLISH_LEASE", "1m"))
+	cfg.Events.Retention, _ = time.ParseDuration(getEnv("EVENTS_RETENTION", "168h"))
+	cfg.Events.NATSURL = getEnv("NATS_URL", "nats://localhost:4222")
+	cfg.Events.NATSPrefix = getEnv("NATS_SUBJECT_PREFIX", "users")
+	cfg.Events.NATSJetStream = getEnvBool("NATS_JETSTREAM", true)
+	cfg.Events.KafkaBrokers = getEnvSlice("KAFKA_BROKERS", nil)
+	cfg.Events.KafkaTopic = getEnv("KAFKA_TOPIC", "user-events")
+	cfg.Events.RedisStream = getEnv("REDIS_STREAM", "user-events")
+	cfg.Events.RedisStreamMaxLen, _ = strconv.ParseInt(getEnv("REDIS_STREAM_MAX_LEN", "100000"), 10, 64)
+
+	// Rate limiting
+	cfg.RateLimit.Enabled = getEnvBool("RATE_LIMIT_ENABLED", true)
+	cfg.RateLimit.Policies = make(map[string]RateLimitPolicy)
+	for name, value := range getEnvMap("RATE_LIMIT_POLICIES", ";", "=") {
+		policy, err := parseRateLimitPolicy(value)
+		if err != nil {
+			return fmt.Errorf("invalid RATE_LIMIT_POLICIES entry %q: %w", name, err)
+		}
+		cfg.RateLimit.Policies[name] = policy
+	}
+	if os.Getenv("RATE_LIMIT_POLICIES") == "" {
+		for _, entry := range strings.Split(defaultRateLimitPolicies, ";") {
+			name, value, _ := strings.Cut(entry, "=")
+			cfg.RateLimit.Policies[name], _ = parseRateLimitPolicy(value)
+		}
+	}
+
+	// Metrics
+	cfg.Metrics.Enabled = getEnvBool("METRICS_ENABLED", true)
+	cfg.Metrics.Port = getEnv("METRICS_PORT", "9090")
+	if value, ok := os.LookupEnv("METRICS_PORT"); ok && value == "" {
+		cfg.Metrics.Port = ""
+	}
+	cfg.Metrics.Token = getEnv("METRICS_TOKEN", "")
+
+	// Tracing
+	cfg.Tracing.Enabled = getEnvBool("TRACING_ENABLED", false)
+	cfg.Tracing.Exporter = getEnv("TRACING_EXPORTER", "otlp")
+	cfg.Tracing.ServiceName = getEnv("OTEL_SERVICE_NAME", "user-management")
+	cfg.Tracing.OTLPEndpoint = getEnv("TRACING_OTLP_ENDPOINT", "")
+	cfg.Tracing.SampleRatio, _ = strconv.ParseFloat(getEnv("TRACING_SAMPLE_RATIO", "1"), 64)
+
+	// Health
+	cfg.Health.CheckTimeout, _ = time.ParseDuration(getEnv("HEALTH_CHECK_TIMEOUT", "2s"))
+	cfg.Health.ShutdownDelay, _ = time.ParseDuration(getEnv("HEALTH_SHUTDOWN_DELAY", "5s"))
+
 	cfg.App.Environment = getEnv("APP_ENV", "development")
 	cfg.App.LogLevel = getEnv("LOG_LEVEL", "info")
 	cfg.App.Version = getEnv("APP_VERSION", "1.0.0")
@@ -194,3 +859,70 @@ func getEnvSlice(key string, defaultVal []string) []string {
 	}
 	return defaultVal
 }
+
+func getEnvBool(key string, defaultVal bool) bool {
+	if value := os.Getenv(key); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return defaultVal
+		}
+		return parsed
+	}
+	return defaultVal
+}
+
+// getEnvMap parses "key<kvSep>value" pairs separated by entrySep. The value is
+// taken after the last kvSep so keys may themselves contain the separator.
+func getEnvMap(key, entrySep, kvSep string) map[string]string {
+	result := make(map[string]string)
+	value := os.Getenv(key)
+	if value == "" {
+		return result
+	}
+	for _, entry := range strings.Split(value, entrySep) {
+		idx := strings.LastIndex(entry, kvSep)
+		if idx <= 0 {
+			continue
+		}
+		result[strings.TrimSpace(entry[:idx])] = strings.TrimSpace(entry[idx+len(kvSep):])
+	}
+	return result
+}
+
+// parseRateLimitPolicy parses "<limit>/<window>[:<key>]", e.g. "10/1m:ip". The key defaults to ip.
+func parseRateLimitPolicy(value string) (RateLimitPolicy, error) {
+	spec, keyBy, found := strings.Cut(value, ":")
+	if !found {
+		keyBy = "ip"
+	}
+
+	limit, window, found := strings.Cut(spec, "/")
+	if !found {
+		return RateLimitPolicy{}, errors.New("expected <limit>/<window>[:<key>]")
+	}
+
+	policy := RateLimitPolicy{KeyBy: strings.TrimSpace(keyBy)}
+	var err error
+	if policy.Limit, err = strconv.Atoi(strings.TrimSpace(limit)); err != nil {
+		return RateLimitPolicy{}, fmt.Errorf("invalid limit: %w", err)
+	}
+	if policy.Window, err = time.ParseDuration(strings.TrimSpace(window)); err != nil {
+		return RateLimitPolicy{}, fmt.Errorf("invalid window: %w", err)
+	}
+	return policy, nil
+}
+
+// loadSAMLConnections reads the per-organization identity provider list from a JSON file
+func loadSAMLConnections(path string) ([]SAMLConnection, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read SAML connections file: %w", err)
+	}
+
+	var connections []SAMLConnection
+	if err := json.Unmarshal(data, &connections); err != nil {
+		return nil, fmt.Errorf("failed to parse SAML connections file: %w", err)
+	}
+
+	return connections, nil
+}
@@ -0,0 +1,212 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func loadTestConfig(t *testing.T, env map[string]string) *Config {
+	t.Helper()
+	for key, value := range env {
+		t.Setenv(key, value)
+	}
+	cfg := &Config{}
+	if err := loadFromEnv(cfg); err != nil {
+		t.Fatalf("loadFromEnv: %v", err)
+	}
+	return cfg
+}
+
+func productionEnv(overrides map[string]string) map[string]string {
+	env := map[string]string{
+		"APP_ENV":                 "production",
+		"DB_PASS":                 "db-secret",
+		"JWT_SECRET":              "a-long-random-production-secret",
+		"DATA_EXPORT_SIGNING_KEY": "a-separate-export-signing-key",
+		"AUDIT_PSEUDONYM_KEY":     "a-separate-audit-pseudonym-key",
+		"MAIL_DRIVER":             "smtp",
+		"SMTP_HOST":               "smtp.example.com",
+		"SMS_DRIVER":              "webhook",
+		"SMS_WEBHOOK_URL":         "https://sms.example.com/send",
+	}
+	for key, value := range overrides {
+		env[key] = value
+	}
+	return env
+}
+
+func TestValidateMailDriver(t *testing.T) {
+	t.Run("memory is the development default", func(t *testing.T) {
+		cfg := loadTestConfig(t, map[string]string{"APP_ENV": "development"})
+		if cfg.Mail.Driver != "memory" {
+			t.Fatalf("default MAIL_DRIVER = %q", cfg.Mail.Driver)
+		}
+		if err := cfg.Validate(); err != nil {
+			t.Fatalf("Validate: %v", err)
+		}
+	})
+
+	t.Run("memory is refused in production", func(t *testing.T) {
+		cfg := loadTestConfig(t, productionEnv(map[string]string{"MAIL_DRIVER": "memory"}))
+		err := cfg.Validate()
+		if err == nil || !strings.Contains(err.Error(), "MAIL_DRIVER") {
+			t.Fatalf("Validate = %v, want a MAIL_DRIVER error", err)
+		}
+	})
+
+	t.Run("smtp requires a host", func(t *testing.T) {
+		cfg := loadTestConfig(t, productionEnv(map[string]string{"SMTP_HOST": ""}))
+		err := cfg.Validate()
+		if err == nil || !strings.Contains(err.Error(), "SMTP_HOST") {
+			t.Fatalf("Validate = %v, want a SMTP_HOST error", err)
+		}
+	})
+
+	t.Run("smtp is accepted in production", func(t *testing.T) {
+		cfg := loadTestConfig(t, productionEnv(nil))
+		if err := cfg.Validate(); err != nil {
+			t.Fatalf("Validate: %v", err)
+		}
+	})
+}
+
+func TestValidateExportSigningKey(t *testing.T) {
+	t.Run("falls back to the JWT secret outside production", func(t *testing.T) {
+		cfg := loadTestConfig(t, map[string]string{"APP_ENV": "development", "JWT_SECRET": "dev-secret"})
+		if cfg.Export.SigningKey != "dev-secret" {
+			t.Fatalf("signing key = %q, want the JWT secret", cfg.Export.SigningKey)
+		}
+		if err := cfg.Validate(); err != nil {
+			t.Fatalf("Validate: %v", err)
+		}
+	})
+
+	for name, key := range map[string]string{
+		"missing in production":    "",
+		"JWT secret in production": "a-long-random-production-secret",
+	} {
+		t.Run(name, func(t *testing.T) {
+			cfg := loadTestConfig(t, productionEnv(map[string]string{"DATA_EXPORT_SIGNING_KEY": key}))
+			err := cfg.Validate()
+			if err == nil || !strings.Contains(err.Error(), "DATA_EXPORT_SIGNING_KEY") {
+				t.Fatalf("Validate = %v, want a DATA_EXPORT_SIGNING_KEY error", err)
+			}
+		})
+	}
+}
+
+func TestValidateAuditPseudonymKey(t *testing.T) {
+	cfg := loadTestConfig(t, map[string]string{"APP_ENV": "development", "JWT_SECRET": "dev-secret"})
+	if cfg.Audit.PseudonymKey != "dev-secret" {
+		t.Fatalf("pseudonym key = %q, want the JWT secret", cfg.Audit.PseudonymKey)
+	}
+
+	for name, key := range map[string]string{
+		"missing in production":    "",
+		"JWT secret in production": "a-long-random-production-secret",
+	} {
+		t.Run(name, func(t *testing.T) {
+			cfg := loadTestConfig(t, productionEnv(map[string]string{"AUDIT_PSEUDONYM_KEY": key}))
+			err := cfg.Validate()
+			if err == nil || !strings.Contains(err.Error(), "AUDIT_PSEUDONYM_KEY") {
+				t.Fatalf("Validate = %v, want an AUDIT_PSEUDONYM_KEY error", err)
+			}
+		})
+	}
+}
+
+func TestValidateTrustedProxies(t *testing.T) {
+	cfg := loadTestConfig(t, map[string]string{"APP_ENV": "development", "TRUSTED_PROXIES": "10.0.0.0/8,192.0.2.1"})
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+
+	cfg = loadTestConfig(t, map[string]string{"APP_ENV": "development", "TRUSTED_PROXIES": "10.0.0.0/8,proxy.internal"})
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "TRUSTED_PROXIES") {
+		t.Fatalf("Validate = %v, want a TRUSTED_PROXIES error", err)
+	}
+}
+
+func TestValidateTracingExporter(t *testing.T) {
+	cfg := loadTestConfig(t, map[string]string{"APP_ENV": "development", "TRACING_ENABLED": "true", "TRACING_EXPORTER": "memory"})
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+
+	cfg = loadTestConfig(t, productionEnv(map[string]string{"TRACING_ENABLED": "true", "TRACING_EXPORTER": "memory"}))
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "TRACING_EXPORTER") {
+		t.Fatalf("Validate = %v, want a TRACING_EXPORTER error", err)
+	}
+}
+
+func TestValidateStepUpMaxAge(t *testing.T) {
+	cfg := loadTestConfig(t, map[string]string{"APP_ENV": "development"})
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+
+	cfg = loadTestConfig(t, map[string]string{"APP_ENV": "development", "AUTH_STEP_UP_MFA_MAX_AGE": "1m"})
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "AUTH_STEP_UP_MFA_MAX_AGE") {
+		t.Fatalf("Validate = %v, want an AUTH_STEP_UP_MFA_MAX_AGE error", err)
+	}
+}
+
+func TestValidateSMSDriver(t *testing.T) {
+	cfg := loadTestConfig(t, map[string]string{"APP_ENV": "development"})
+	if cfg.SMS.Driver != "console" {
+		t.Fatalf("default SMS_DRIVER = %q", cfg.SMS.Driver)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+
+	cfg = loadTestConfig(t, productionEnv(map[string]string{"SMS_DRIVER": "console"}))
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "SMS_DRIVER") {
+		t.Fatalf("Validate = %v, want a SMS_DRIVER error", err)
+	}
+}
+
+func TestValidateWebhookPrivateTargets(t *testing.T) {
+	cfg := loadTestConfig(t, map[string]string{"APP_ENV": "development", "WEBHOOK_ALLOW_PRIVATE_TARGETS": "true"})
+	if !cfg.Webhook.AllowPrivateTargets {
+		t.Fatal("WEBHOOK_ALLOW_PRIVATE_TARGETS not loaded")
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+
+	cfg = loadTestConfig(t, productionEnv(map[string]string{"WEBHOOK_ALLOW_PRIVATE_TARGETS": "true"}))
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "WEBHOOK_ALLOW_PRIVATE_TARGETS") {
+		t.Fatalf("Validate = %v, want a WEBHOOK_ALLOW_PRIVATE_TARGETS error", err)
+	}
+}
+
+func TestValidateEventsDriver(t *testing.T) {
+	cfg := loadTestConfig(t, map[string]string{"APP_ENV": "development", "EVENTS_DRIVER": "memory"})
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+
+	cfg = loadTestConfig(t, productionEnv(map[string]string{"EVENTS_DRIVER": "none"}))
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+
+	cfg = loadTestConfig(t, productionEnv(map[string]string{"EVENTS_DRIVER": "memory"}))
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "EVENTS_DRIVER") {
+		t.Fatalf("Validate = %v, want an EVENTS_DRIVER error", err)
+	}
+}
+
+func TestValidateRateLimitKey(t *testing.T) {
+	cfg := loadTestConfig(t, map[string]string{"APP_ENV": "development", "RATE_LIMIT_POLICIES": "auth=10/1m:ip;api=100/1m:user"})
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+
+	// No middleware authenticates API keys yet, so such a policy would silently key on the IP
+	cfg = loadTestConfig(t, map[string]string{"APP_ENV": "development", "RATE_LIMIT_POLICIES": "api=100/1m:api_key"})
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), `invalid key "api_key"`) {
+		t.Fatalf("Validate = %v, want an invalid key error", err)
+	}
+}
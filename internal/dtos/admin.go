@@ -0,0 +1,44 @@
+package dtos
+
+import (
+	"time"
+	"user-management/internal/models"
+
+	"github.com/google/uuid"
+)
+
+type ImpersonationResponse struct {
+	AccessToken    string    `json:"access_token"`
+	ExpiresAt      time.Time `json:"expires_at"`
+	UserID         uuid.UUID `json:"user_id"`
+	ImpersonatorID uuid.UUID `json:"impersonator_id"`
+}
+
+// ChangeStatusRequest is built by the admin handlers from the suspend, disable and reactivate requests
+type ChangeStatusRequest struct {
+	Status string
+	Reason string
+	Until  *time.Time
+}
+
+type SuspendUserRequest struct {
+	Reason string     `json:"reason" binding:"required,max=500"`
+	Until  *time.Time `json:"until"`
+}
+
+type DisableUserRequest struct {
+	Reason string `json:"reason" binding:"required,max=500"`
+}
+
+type AuditLogResponse struct {
+	Events     []models.AuditEvent `json:"events"`
+	Pagination Pagination          `json:"pagination"`
+}
+
+// AuditVerificationResponse reports the result of checking the audit hash chain
+type AuditVerificationResponse struct {
+	Checked        int   `json:"checked"`
+	Chained        int   `json:"chained"`
+	Valid          bool  `json:"valid"`
+	FirstInvalidID int64 `json:"first_invalid_id,omitempty"`
+}
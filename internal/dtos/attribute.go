@@ -0,0 +1,24 @@
+package dtos
+
+import (
+	"encoding/json"
+	"user-management/internal/models"
+)
+
+type AttributeDefinitionRequest struct {
+	Description     string                 `json:"description" binding:"max=255"`
+	Schema          map[string]interface{} `json:"schema" binding:"required"`
+	ReadPermission  string                 `json:"read_permission" binding:"omitempty,oneof=public self admin"`
+	WritePermission string                 `json:"write_permission" binding:"omitempty,oneof=self admin"`
+}
+
+type AttributeDefinitionListResponse struct {
+	Definitions []models.AttributeDefinition `json:"definitions"`
+}
+
+// UpdateAttributesRequest maps attribute keys to new values; null removes the attribute
+type UpdateAttributesRequest map[string]json.RawMessage
+
+type AttributesResponse struct {
+	Attributes map[string]interface{} `json:"attributes"`
+}
@@ -1,8 +1,11 @@
 package dtos
 
+// SignInRequest identifies the account by email, username or verified phone number
 type SignInRequest struct {
-	Email    string `json:"email" binding:"required,email,max=255"`
-	Password string `json:"password" binding:"required,min=8,max=72"`
+	Email       string `json:"email" binding:"required_without_all=Username PhoneNumber,omitempty,email,max=255"`
+	Username    string `json:"username" binding:"omitempty,max=32,excludesall=@"`
+	PhoneNumber string `json:"phone_number" binding:"omitempty,e164"`
+	Password    string `json:"password" binding:"required,min=8,max=72"`
 }
 
 type SignInResponse struct {
@@ -11,6 +14,42 @@ type SignInResponse struct {
 }
 
 type SignUpRequest struct {
+	Email    string `json:"email" binding:"required,email,max=255"`
+	Username string `json:"username" binding:"omitempty,max=32"`
+	Password string `json:"password" binding:"required,min=8,max=72"`
+}
+
+type UsernameAvailabilityResponse struct {
+	Username  string `json:"username"`
+	Available bool   `json:"available"`
+	// Reason explains why an unavailable username cannot be used: invalid, reserved or taken
+	Reason string `json:"reason,omitempty"`
+}
+
+type MagicLinkRequest struct {
+	Email string `json:"email" binding:"required,email,max=255"`
+}
+
+type MagicLinkVerifyRequest struct {
+	Token string `json:"token" binding:"required,len=64,hexadecimal"`
+}
+
+// StepUpRequirement tells the client how to re-authenticate before retrying a sensitive request: any sign-in
+// within MaxAge seconds, or one with MFA within MFAMaxAge seconds
+type StepUpRequirement struct {
+	Reason    string   `json:"reason"`
+	MaxAge    int      `json:"max_age"`
+	MFAMaxAge int      `json:"mfa_max_age"`
+	AMR       []string `json:"amr"`
+	Endpoint  string   `json:"endpoint"`
+}
+
+type RestoreAccountRequest struct {
 	Email    string `json:"email" binding:"required,email,max=255"`
 	Password string `json:"password" binding:"required,min=8,max=72"`
 }
+
+// EmailChangeTokenRequest carries the token from an email change confirmation or cancel link
+type EmailChangeTokenRequest struct {
+	Token string `json:"token" binding:"required,len=64,hexadecimal"`
+}
@@ -4,6 +4,24 @@ type ErrorResponse struct {
 	Error   string `json:"error"`
 	Message string `json:"message"`
 	Details string `json:"details,omitempty"`
+	// Challenge is set on STEP_UP_REQUIRED errors
+	Challenge *StepUpRequirement `json:"challenge,omitempty"`
+	// RequestID matches the X-Request-ID response header and the request_id of the log lines
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// ProblemDetails is the RFC 7807 form of ErrorResponse, served as application/problem+json.
+// Code, Details, Challenge and RequestID are extension members with the same meaning as in ErrorResponse.
+type ProblemDetails struct {
+	Type      string             `json:"type"`
+	Title     string             `json:"title"`
+	Status    int                `json:"status"`
+	Detail    string             `json:"detail,omitempty"`
+	Instance  string             `json:"instance,omitempty"`
+	Code      string             `json:"code"`
+	Details   string             `json:"details,omitempty"`
+	Challenge *StepUpRequirement `json:"challenge,omitempty"`
+	RequestID string             `json:"request_id,omitempty"`
 }
 
 type SuccessResponse struct {
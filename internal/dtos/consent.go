@@ -0,0 +1,40 @@
+package dtos
+
+import (
+	"time"
+	"user-management/internal/models"
+)
+
+type RecordConsentRequest struct {
+	Purpose string `json:"purpose" binding:"required,max=64"`
+	Version string `json:"version" binding:"required,max=32"`
+	Granted *bool  `json:"granted" binding:"required"`
+}
+
+type ConsentResponse struct {
+	Purpose   string    `json:"purpose"`
+	Version   string    `json:"version"`
+	Granted   bool      `json:"granted"`
+	DecidedAt time.Time `json:"decided_at"`
+}
+
+type ConsentListResponse struct {
+	Consents []ConsentResponse `json:"consents"`
+}
+
+func ConsentTransformer(consent *models.Consent) ConsentResponse {
+	return ConsentResponse{
+		Purpose:   consent.Purpose,
+		Version:   consent.Version,
+		Granted:   consent.Granted,
+		DecidedAt: consent.CreatedAt,
+	}
+}
+
+func ConsentsTransformer(consents []models.Consent) []ConsentResponse {
+	result := make([]ConsentResponse, 0, len(consents))
+	for i := range consents {
+		result = append(result, ConsentTransformer(&consents[i]))
+	}
+	return result
+}
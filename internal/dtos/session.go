@@ -0,0 +1,40 @@
+package dtos
+
+import (
+	"time"
+	"user-management/internal/models"
+
+	"github.com/google/uuid"
+)
+
+type SessionResponse struct {
+	ID         uuid.UUID `json:"id"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	Current    bool      `json:"current"`
+}
+
+type SessionListResponse struct {
+	Sessions []SessionResponse `json:"sessions"`
+}
+
+func SessionTransformer(session models.Session, currentSessionID string) SessionResponse {
+	return SessionResponse{
+		ID:         session.ID,
+		UserAgent:  session.UserAgent,
+		IP:         session.IP,
+		CreatedAt:  session.CreatedAt,
+		LastSeenAt: session.LastSeenAt,
+		Current:    session.ID.String() == currentSessionID,
+	}
+}
+
+func SessionsTransformer(sessions []models.Session, currentSessionID string) []SessionResponse {
+	resp := make([]SessionResponse, 0)
+	for _, session := range sessions {
+		resp = append(resp, SessionTransformer(session, currentSessionID))
+	}
+	return resp
+}
@@ -1,43 +1,105 @@
 package dtos
 
 import (
+	"time"
 	"user-management/internal/models"
 
 	"github.com/google/uuid"
 )
 
+// UpdateUserRequest changes only the fields that are present. Profile fields are
+// cleared by sending an empty string.
 type UpdateUserRequest struct {
-	Email    string `json:"email" binding:"omitempty,email,max=255"`
-	Password string `json:"password" binding:"omitempty,min=8,max=72"`
+	Email       string  `json:"email" binding:"omitempty,email,max=255"`
+	Username    *string `json:"username" binding:"omitempty,max=32"`
+	Password    string  `json:"password" binding:"omitempty,min=8,max=72"`
+	DisplayName *string `json:"display_name" binding:"omitempty,max=100"`
+	GivenName   *string `json:"given_name" binding:"omitempty,max=100"`
+	FamilyName  *string `json:"family_name" binding:"omitempty,max=100"`
+	AvatarURL   *string `json:"avatar_url" binding:"omitempty,max=2048,eq=|http_url"`
+	Locale      *string `json:"locale" binding:"omitempty,max=35,eq=|bcp47_language_tag"`
+	Timezone    *string `json:"timezone" binding:"omitempty,max=64,eq=|timezone"`
+	PhoneNumber *string `json:"phone_number" binding:"omitempty,eq=|e164"`
 }
+
 type UserListResponse struct {
 	Users      []UserResponse `json:"users"`
 	Pagination Pagination     `json:"pagination"`
 }
 
 type UserResponse struct {
-	ID    uuid.UUID `json:"id"`
-	Email string    `json:"email"`
+	ID           uuid.UUID `json:"id"`
+	Email        string    `json:"email"`
+	Username     string    `json:"username,omitempty"`
+	PendingEmail string    `json:"pending_email,omitempty"`
+	Status       string    `json:"status"`
+	DisplayName  string    `json:"display_name"`
+	GivenName    string    `json:"given_name"`
+	FamilyName   string    `json:"family_name"`
+	AvatarURL    string    `json:"avatar_url"`
+	// AvatarVariants maps variant names (lg, md, sm) to URLs for uploaded avatars
+	AvatarVariants map[string]interface{} `json:"avatar_variants,omitempty"`
+	Locale         string                 `json:"locale"`
+	Timezone       string                 `json:"timezone"`
+	PhoneNumber    string                 `json:"phone_number,omitempty"`
+	PhoneVerified  bool                   `json:"phone_verified,omitempty"`
+	CreatedAt      time.Time              `json:"created_at"`
+	UpdatedAt      time.Time              `json:"updated_at"`
+	// Attributes holds the custom attributes the caller may read; handlers fill it in
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
 }
 
 func UserTransformer(user models.User) UserResponse {
 	return UserResponse{
-		ID:    user.ID,
-		Email: user.Email,
+		ID:             user.ID,
+		Email:          user.Email,
+		Username:       stringValue(user.Username),
+		Status:         user.EffectiveStatus(time.Now()),
+		DisplayName:    user.DisplayName,
+		GivenName:      user.GivenName,
+		FamilyName:     user.FamilyName,
+		AvatarURL:      user.AvatarURL,
+		AvatarVariants: user.AvatarVariants,
+		Locale:         user.Locale,
+		Timezone:       user.Timezone,
+		PhoneNumber:    user.PhoneNumber,
+		PhoneVerified:  user.PhoneVerifiedAt != nil,
+		CreatedAt:      user.CreatedAt,
+		UpdatedAt:      user.UpdatedAt,
 	}
 }
 
+// UsersTransformer builds list entries, which leave out the phone number
 func UsersTransformer(users []models.User) []UserResponse {
 	resp := make([]UserResponse, 0)
 	for _, user := range users {
-		resp = append(resp, UserTransformer(user))
+		item := UserTransformer(user)
+		item.PhoneNumber = ""
+		item.PhoneVerified = false
+		resp = append(resp, item)
 	}
 	return resp
 }
 
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
 func SafeUser(user *models.User) models.User {
 	if user == nil {
 		return models.User{}
 	}
 	return *user
 }
+
+type VerifyPhoneRequest struct {
+	Code string `json:"code" binding:"required,len=6,numeric"`
+}
+
+type AccountDeletionResponse struct {
+	DeletedAt time.Time `json:"deleted_at"`
+	PurgeAt   time.Time `json:"purge_at"`
+}
@@ -0,0 +1,72 @@
+package dtos
+
+import (
+	"time"
+	"user-management/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// WebhookSubscriptionRequest creates or replaces a subscription. Leaving event_types empty subscribes to every event.
+type WebhookSubscriptionRequest struct {
+	URL         string   `json:"url" binding:"required,max=2048,http_url"`
+	EventTypes  []string `json:"event_types" binding:"omitempty,dive,oneof=user.created user.updated user.deleted user.restored"`
+	Description string   `json:"description" binding:"max=255"`
+	Active      *bool    `json:"active"`
+}
+
+type WebhookSubscriptionResponse struct {
+	ID          uuid.UUID `json:"id"`
+	URL         string    `json:"url"`
+	EventTypes  []string  `json:"event_types"`
+	Description string    `json:"description"`
+	Active      bool      `json:"active"`
+	// Secret is only returned when the subscription is created or its secret is rotated
+	Secret    string    `json:"secret,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type WebhookSubscriptionListResponse struct {
+	Subscriptions []WebhookSubscriptionResponse `json:"subscriptions"`
+}
+
+type WebhookDeliveryListResponse struct {
+	Deliveries []models.WebhookDelivery `json:"deliveries"`
+	Pagination Pagination               `json:"pagination"`
+}
+
+// WebhookReplayRequest selects the deliveries to queue again: the listed ones, or every one with the status
+type WebhookReplayRequest struct {
+	DeliveryIDs []uuid.UUID `json:"delivery_ids" binding:"required_without=Status,max=100"`
+	Status      string      `json:"status" binding:"omitempty,oneof=delivered dead"`
+}
+
+type WebhookReplayResponse struct {
+	Queued int64 `json:"queued"`
+}
+
+func WebhookSubscriptionTransformer(subscription *models.WebhookSubscription, secret string) WebhookSubscriptionResponse {
+	eventTypes := []string(subscription.EventTypes)
+	if eventTypes == nil {
+		eventTypes = []string{}
+	}
+	return WebhookSubscriptionResponse{
+		ID:          subscription.ID,
+		URL:         subscription.URL,
+		EventTypes:  eventTypes,
+		Description: subscription.Description,
+		Active:      subscription.Active,
+		Secret:      secret,
+		CreatedAt:   subscription.CreatedAt,
+		UpdatedAt:   subscription.UpdatedAt,
+	}
+}
+
+func WebhookSubscriptionsTransformer(subscriptions []models.WebhookSubscription) []WebhookSubscriptionResponse {
+	resp := make([]WebhookSubscriptionResponse, 0, len(subscriptions))
+	for i := range subscriptions {
+		resp = append(resp, WebhookSubscriptionTransformer(&subscriptions[i], ""))
+	}
+	return resp
+}
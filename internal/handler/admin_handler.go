@@ -0,0 +1,114 @@
+package handler
+
+import (
+	"net/http"
+	"user-management/internal/apperr"
+	"user-management/internal/dtos"
+	"user-management/internal/models"
+	"user-management/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AdminHandler struct {
+	userService service.UserService
+}
+
+func NewAdminHandler(userService service.UserService) *AdminHandler {
+	return &AdminHandler{
+		userService: userService,
+	}
+}
+
+func (h *AdminHandler) Impersonate(c *gin.Context) {
+	targetID, ok := pathUUID(c, "id")
+	if !ok {
+		return
+	}
+
+	adminID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	response, err := h.userService.Impersonate(c.Request.Context(), adminID, targetID, c.GetString("session_id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *AdminHandler) SuspendUser(c *gin.Context) {
+	var req dtos.SuspendUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperr.Validation(err))
+		return
+	}
+
+	h.changeStatus(c, &dtos.ChangeStatusRequest{
+		Status: models.StatusSuspended,
+		Reason: req.Reason,
+		Until:  req.Until,
+	})
+}
+
+func (h *AdminHandler) DisableUser(c *gin.Context) {
+	var req dtos.DisableUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperr.Validation(err))
+		return
+	}
+
+	h.changeStatus(c, &dtos.ChangeStatusRequest{
+		Status: models.StatusDisabled,
+		Reason: req.Reason,
+	})
+}
+
+func (h *AdminHandler) ReactivateUser(c *gin.Context) {
+	h.changeStatus(c, &dtos.ChangeStatusRequest{
+		Status: models.StatusActive,
+	})
+}
+
+func (h *AdminHandler) changeStatus(c *gin.Context, req *dtos.ChangeStatusRequest) {
+	targetID, ok := pathUUID(c, "id")
+	if !ok {
+		return
+	}
+
+	adminID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	user, err := h.userService.ChangeStatus(c.Request.Context(), adminID, targetID, req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dtos.UserTransformer(dtos.SafeUser(user)))
+}
+
+func (h *AdminHandler) RestoreUser(c *gin.Context) {
+	targetID, ok := pathUUID(c, "id")
+	if !ok {
+		return
+	}
+
+	adminID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	user, err := h.userService.RestoreUser(c.Request.Context(), adminID, targetID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dtos.UserTransformer(dtos.SafeUser(user)))
+}
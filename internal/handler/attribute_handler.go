@@ -0,0 +1,111 @@
+package handler
+
+import (
+	"net/http"
+	"user-management/internal/apperr"
+	"user-management/internal/dtos"
+	"user-management/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AttributeHandler struct {
+	attributeService service.AttributeService
+}
+
+func NewAttributeHandler(attributeService service.AttributeService) *AttributeHandler {
+	return &AttributeHandler{
+		attributeService: attributeService,
+	}
+}
+
+func (h *AttributeHandler) ListDefinitions(c *gin.Context) {
+	definitions, err := h.attributeService.ListDefinitions(c.Request.Context())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dtos.AttributeDefinitionListResponse{Definitions: definitions})
+}
+
+func (h *AttributeHandler) PutDefinition(c *gin.Context) {
+	actorID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var req dtos.AttributeDefinitionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperr.Validation(err))
+		return
+	}
+
+	definition, err := h.attributeService.PutDefinition(c.Request.Context(), actorID, c.Param("key"), &req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, definition)
+}
+
+func (h *AttributeHandler) DeleteDefinition(c *gin.Context) {
+	actorID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.attributeService.DeleteDefinition(c.Request.Context(), actorID, c.Param("key")); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *AttributeHandler) GetAttributes(c *gin.Context) {
+	targetID, ok := pathUUID(c, "id")
+	if !ok {
+		return
+	}
+
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	attributes, err := h.attributeService.GetAttributes(c.Request.Context(), userID, targetID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dtos.AttributesResponse{Attributes: attributes})
+}
+
+func (h *AttributeHandler) UpdateAttributes(c *gin.Context) {
+	targetID, ok := pathUUID(c, "id")
+	if !ok {
+		return
+	}
+
+	var req dtos.UpdateAttributesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperr.Validation(err))
+		return
+	}
+
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	attributes, err := h.attributeService.UpdateAttributes(c.Request.Context(), userID, targetID, req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dtos.AttributesResponse{Attributes: attributes})
+}
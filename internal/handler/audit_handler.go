@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+	"user-management/internal/apperr"
+	"user-management/internal/dtos"
+	"user-management/internal/repository"
+	"user-management/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type AuditHandler struct {
+	auditService service.AuditService
+}
+
+func NewAuditHandler(auditService service.AuditService) *AuditHandler {
+	return &AuditHandler{
+		auditService: auditService,
+	}
+}
+
+// List returns audit events newest first. The cursor is the ID of the last event of the previous page.
+func (h *AuditHandler) List(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	var cursor int64
+	if raw := c.Query("cursor"); raw != "" {
+		cursor, err = strconv.ParseInt(raw, 10, 64)
+		if err != nil || cursor < 1 {
+			c.Error(apperr.ErrInvalidCursor)
+			return
+		}
+	}
+
+	filter := repository.AuditFilter{
+		Action:    c.Query("action"),
+		Outcome:   c.Query("outcome"),
+		IP:        c.Query("ip"),
+		RequestID: c.Query("request_id"),
+	}
+
+	ids := map[string]*uuid.UUID{"actor_id": &filter.ActorID, "target_id": &filter.TargetID}
+	for name, target := range ids {
+		if raw := c.Query(name); raw != "" {
+			if *target, err = uuid.Parse(raw); err != nil {
+				c.Error(apperr.ErrValidation.WithMessage("Invalid " + name + " format"))
+				return
+			}
+		}
+	}
+
+	times := map[string]*time.Time{"from": &filter.From, "to": &filter.To}
+	for name, target := range times {
+		if raw := c.Query(name); raw != "" {
+			if *target, err = time.Parse(time.RFC3339, raw); err != nil {
+				c.Error(apperr.ErrValidation.WithMessage("Invalid " + name + " time, expected RFC 3339"))
+				return
+			}
+		}
+	}
+
+	events, err := h.auditService.List(c.Request.Context(), filter, cursor, limit)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	var nextCursor string
+	if len(events) == limit {
+		nextCursor = strconv.FormatInt(events[len(events)-1].ID, 10)
+	}
+
+	c.JSON(http.StatusOK, dtos.AuditLogResponse{
+		Events: events,
+		Pagination: dtos.Pagination{
+			Limit:      limit,
+			NextCursor: nextCursor,
+		},
+	})
+}
+
+// Verify checks the hash chain of the whole log
+func (h *AuditHandler) Verify(c *gin.Context) {
+	result, err := h.auditService.Verify(c.Request.Context())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
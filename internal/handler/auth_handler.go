@@ -1,8 +1,10 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
 	"strings"
+	"user-management/internal/apperr"
 	"user-management/internal/dtos"
 	"user-management/internal/service"
 	"user-management/internal/utils"
@@ -26,11 +28,7 @@ func (h *AuthHandler) SignIn(c *gin.Context) {
 	var req dtos.SignInRequest
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, dtos.ErrorResponse{
-			Error:   utils.ErrCodeValidationError,
-			Message: "Invalid request payload",
-			Details: err.Error(),
-		})
+		c.Error(apperr.Validation(err))
 		return
 	}
 
@@ -38,19 +36,18 @@ func (h *AuthHandler) SignIn(c *gin.Context) {
 	req.Email = strings.TrimSpace(req.Email)
 	req.Email = strings.ToLower(req.Email)
 
-	response, err := h.userService.SignIn(c.Request.Context(), req.Email, req.Password)
-	if err != nil {
-		status := http.StatusUnauthorized
-		message := "Authentication failed"
-
-		if strings.Contains(err.Error(), "disabled") {
-			message = "Account is disabled"
-		}
+	identifier := req.Email
+	switch {
+	case identifier != "":
+	case req.Username != "":
+		identifier = strings.TrimSpace(req.Username)
+	default:
+		identifier = req.PhoneNumber
+	}
 
-		c.JSON(status, dtos.ErrorResponse{
-			Error:   utils.ErrCodeAuthFailed,
-			Message: message,
-		})
+	response, err := h.userService.SignIn(c.Request.Context(), identifier, req.Password)
+	if err != nil {
+		c.Error(err)
 		return
 	}
 
@@ -61,11 +58,7 @@ func (h *AuthHandler) Signup(c *gin.Context) {
 	var req dtos.SignUpRequest
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, dtos.ErrorResponse{
-			Error:   utils.ErrCodeValidationError,
-			Message: "Invalid request payload",
-			Details: err.Error(),
-		})
+		c.Error(apperr.Validation(err))
 		return
 	}
 
@@ -73,17 +66,11 @@ func (h *AuthHandler) Signup(c *gin.Context) {
 	req.Email = strings.TrimSpace(req.Email)
 	req.Email = strings.ToLower(req.Email)
 
+	req.Username = strings.TrimSpace(req.Username)
+
 	_, err := h.userService.CreateUser(c.Request.Context(), &req)
 	if err != nil {
-		status := http.StatusInternalServerError
-		if strings.Contains(err.Error(), "already exists") {
-			status = http.StatusBadRequest
-		}
-
-		c.JSON(status, dtos.ErrorResponse{
-			Error:   utils.ErrCodeSignupFailed,
-			Message: err.Error(),
-		})
+		c.Error(err)
 		return
 	}
 
@@ -91,3 +78,55 @@ func (h *AuthHandler) Signup(c *gin.Context) {
 		Message: "User created successfully",
 	})
 }
+
+// UsernameAvailability reports whether a username can be used for sign-up or a rename
+func (h *AuthHandler) UsernameAvailability(c *gin.Context) {
+	username := strings.TrimSpace(c.Query("username"))
+	if username == "" {
+		c.Error(apperr.ErrValidation.WithMessage("username query parameter is required"))
+		return
+	}
+
+	resp := dtos.UsernameAvailabilityResponse{Username: username, Available: true}
+
+	err := h.userService.CheckUsername(c.Request.Context(), username)
+	switch {
+	case err == nil:
+	case errors.Is(err, service.ErrInvalidUsername):
+		resp.Available, resp.Reason = false, "invalid"
+	case errors.Is(err, service.ErrUsernameReserved):
+		resp.Available, resp.Reason = false, "reserved"
+	case errors.Is(err, service.ErrUsernameTaken):
+		resp.Available, resp.Reason = false, "taken"
+	default:
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// RestoreAccount undoes a self-service deletion during the grace period
+func (h *AuthHandler) RestoreAccount(c *gin.Context) {
+	var req dtos.RestoreAccountRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperr.Validation(err))
+		return
+	}
+
+	// Trim email
+	req.Email = strings.TrimSpace(req.Email)
+	req.Email = strings.ToLower(req.Email)
+
+	user, err := h.userService.RestoreAccount(c.Request.Context(), req.Email, req.Password)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dtos.SuccessResponse{
+		Message: "Account restored successfully",
+		Data:    dtos.UserTransformer(dtos.SafeUser(user)),
+	})
+}
@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"user-management/internal/apperr"
+	"user-management/internal/dtos"
+	"user-management/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// multipartOverhead leaves room for boundaries and part headers around the file
+const multipartOverhead = 64 << 10
+
+type AvatarHandler struct {
+	avatarService service.AvatarService
+	maxBytes      int64
+}
+
+func NewAvatarHandler(avatarService service.AvatarService, maxBytes int64) *AvatarHandler {
+	return &AvatarHandler{
+		avatarService: avatarService,
+		maxBytes:      maxBytes,
+	}
+}
+
+// Upload accepts a multipart form with the image in the "avatar" field
+func (h *AvatarHandler) Upload(c *gin.Context) {
+	targetID, ok := pathUUID(c, "id")
+	if !ok {
+		return
+	}
+
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.maxBytes+multipartOverhead)
+
+	header, err := c.FormFile("avatar")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.Error(service.ErrAvatarTooLarge)
+			return
+		}
+
+		c.Error(apperr.ErrValidation.WithDetails("multipart field \"avatar\" is required"))
+		return
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	defer file.Close()
+
+	user, err := h.avatarService.Upload(c.Request.Context(), userID, targetID, file)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dtos.UserTransformer(dtos.SafeUser(user)))
+}
+
+func (h *AvatarHandler) Remove(c *gin.Context) {
+	targetID, ok := pathUUID(c, "id")
+	if !ok {
+		return
+	}
+
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	user, err := h.avatarService.Remove(c.Request.Context(), userID, targetID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dtos.UserTransformer(dtos.SafeUser(user)))
+}
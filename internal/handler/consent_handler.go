@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"net/http"
+	"user-management/internal/apperr"
+	"user-management/internal/dtos"
+	"user-management/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ConsentHandler struct {
+	consentService service.ConsentService
+}
+
+func NewConsentHandler(consentService service.ConsentService) *ConsentHandler {
+	return &ConsentHandler{
+		consentService: consentService,
+	}
+}
+
+// List returns the user's current consent decisions
+func (h *ConsentHandler) List(c *gin.Context) {
+	targetID, ok := pathUUID(c, "id")
+	if !ok {
+		return
+	}
+
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	consents, err := h.consentService.List(c.Request.Context(), userID, targetID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dtos.ConsentListResponse{
+		Consents: dtos.ConsentsTransformer(consents),
+	})
+}
+
+// Record gives or withdraws consent for a purpose
+func (h *ConsentHandler) Record(c *gin.Context) {
+	targetID, ok := pathUUID(c, "id")
+	if !ok {
+		return
+	}
+
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var req dtos.RecordConsentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperr.Validation(err))
+		return
+	}
+
+	consent, err := h.consentService.Record(c.Request.Context(), userID, targetID, &req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, dtos.ConsentTransformer(consent))
+}
@@ -0,0 +1,35 @@
+package handler
+
+import (
+	"user-management/internal/apperr"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// currentUserID returns the authenticated user's ID, recording a 401 error when it is missing
+func currentUserID(c *gin.Context) (uuid.UUID, bool) {
+	userIDStr, exists := c.Get("user_id")
+	if !exists {
+		c.Error(apperr.ErrUnauthorized.WithMessage("User not authenticated"))
+		return uuid.Nil, false
+	}
+
+	userID, err := uuid.Parse(userIDStr.(string))
+	if err != nil {
+		c.Error(apperr.ErrUnauthorized.WithMessage("Invalid session user ID"))
+		return uuid.Nil, false
+	}
+
+	return userID, true
+}
+
+// pathUUID parses a UUID path parameter, recording a 400 error when it is malformed
+func pathUUID(c *gin.Context, name string) (uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Param(name))
+	if err != nil {
+		c.Error(apperr.ErrInvalidID.WithMessage("Invalid " + name + " format"))
+		return uuid.Nil, false
+	}
+	return id, true
+}
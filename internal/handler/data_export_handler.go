@@ -0,0 +1,82 @@
+package handler
+
+import (
+	"net/http"
+	"user-management/internal/dtos"
+	"user-management/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type DataExportHandler struct {
+	exportService service.DataExportService
+}
+
+func NewDataExportHandler(exportService service.DataExportService) *DataExportHandler {
+	return &DataExportHandler{
+		exportService: exportService,
+	}
+}
+
+func (h *DataExportHandler) RequestExport(c *gin.Context) {
+	targetID, ok := pathUUID(c, "id")
+	if !ok {
+		return
+	}
+
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	export, err := h.exportService.RequestExport(c.Request.Context(), userID, targetID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, dtos.DataExportTransformer(export, h.exportService.DownloadURL(export)))
+}
+
+func (h *DataExportHandler) GetExport(c *gin.Context) {
+	targetID, ok := pathUUID(c, "id")
+	if !ok {
+		return
+	}
+
+	exportID, ok := pathUUID(c, "export_id")
+	if !ok {
+		return
+	}
+
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	export, err := h.exportService.GetExport(c.Request.Context(), userID, targetID, exportID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dtos.DataExportTransformer(export, h.exportService.DownloadURL(export)))
+}
+
+// Download serves the archive behind a signed, expiring link
+func (h *DataExportHandler) Download(c *gin.Context) {
+	exportID, ok := pathUUID(c, "id")
+	if !ok {
+		return
+	}
+
+	archive, err := h.exportService.Download(c.Request.Context(), exportID, c.Query("expires"), c.Query("signature"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Header("Content-Disposition", `attachment; filename="export-`+exportID.String()+`.json"`)
+	c.Header("Cache-Control", "no-store")
+	c.Data(http.StatusOK, "application/json", archive)
+}
@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"net/http"
+	"user-management/internal/apperr"
+	"user-management/internal/dtos"
+	"user-management/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type EmailChangeHandler struct {
+	emailChangeService service.EmailChangeService
+}
+
+func NewEmailChangeHandler(emailChangeService service.EmailChangeService) *EmailChangeHandler {
+	return &EmailChangeHandler{
+		emailChangeService: emailChangeService,
+	}
+}
+
+// Confirm applies a pending email change using the token sent to the new address
+func (h *EmailChangeHandler) Confirm(c *gin.Context) {
+	var req dtos.EmailChangeTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperr.Validation(err))
+		return
+	}
+
+	user, err := h.emailChangeService.Confirm(c.Request.Context(), req.Token)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dtos.SuccessResponse{
+		Message: "Email address changed",
+		Data:    dtos.UserTransformer(dtos.SafeUser(user)),
+	})
+}
+
+// Cancel withdraws or reverts an email change using the token sent to the old address
+func (h *EmailChangeHandler) Cancel(c *gin.Context) {
+	var req dtos.EmailChangeTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperr.Validation(err))
+		return
+	}
+
+	if err := h.emailChangeService.Cancel(c.Request.Context(), req.Token); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dtos.SuccessResponse{
+		Message: "Email change cancelled",
+	})
+}
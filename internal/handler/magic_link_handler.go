@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+	"time"
+	"user-management/internal/apperr"
+	"user-management/internal/dtos"
+	"user-management/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	magicLinkCookie     = "magic_link_nonce"
+	magicLinkCookiePath = "/api/v1/auth/magic-link"
+)
+
+type MagicLinkHandler struct {
+	magicLinkService service.MagicLinkService
+	cookieTTL        time.Duration
+	secureCookies    bool
+}
+
+func NewMagicLinkHandler(magicLinkService service.MagicLinkService, cookieTTL time.Duration, secureCookies bool) *MagicLinkHandler {
+	return &MagicLinkHandler{
+		magicLinkService: magicLinkService,
+		cookieTTL:        cookieTTL,
+		secureCookies:    secureCookies,
+	}
+}
+
+func (h *MagicLinkHandler) Request(c *gin.Context) {
+	var req dtos.MagicLinkRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperr.Validation(err))
+		return
+	}
+
+	// Trim email
+	req.Email = strings.TrimSpace(req.Email)
+	req.Email = strings.ToLower(req.Email)
+
+	browserNonce, err := h.magicLinkService.Request(c.Request.Context(), req.Email)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	// Bind the link to this browser so a forwarded or intercepted email cannot be used elsewhere
+	if browserNonce != "" {
+		c.SetSameSite(http.SameSiteLaxMode)
+		c.SetCookie(magicLinkCookie, browserNonce, int(h.cookieTTL.Seconds()), magicLinkCookiePath, "", h.secureCookies, true)
+	}
+
+	// Same response whether or not the account exists
+	c.JSON(http.StatusAccepted, dtos.SuccessResponse{
+		Message: "If the account exists, a sign-in link has been sent",
+	})
+}
+
+func (h *MagicLinkHandler) Verify(c *gin.Context) {
+	var req dtos.MagicLinkVerifyRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperr.Validation(err))
+		return
+	}
+
+	browserNonce, _ := c.Cookie(magicLinkCookie)
+
+	response, err := h.magicLinkService.Verify(c.Request.Context(), req.Token, browserNonce)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.SetCookie(magicLinkCookie, "", -1, magicLinkCookiePath, "", h.secureCookies, true)
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,69 @@
+package handler
+
+import (
+	"net/http"
+	"user-management/internal/apperr"
+	"user-management/internal/dtos"
+	"user-management/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PhoneHandler struct {
+	phoneService service.PhoneVerificationService
+}
+
+func NewPhoneHandler(phoneService service.PhoneVerificationService) *PhoneHandler {
+	return &PhoneHandler{
+		phoneService: phoneService,
+	}
+}
+
+// SendCode texts a verification code to the phone number on the account
+func (h *PhoneHandler) SendCode(c *gin.Context) {
+	targetID, ok := pathUUID(c, "id")
+	if !ok {
+		return
+	}
+
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.phoneService.SendCode(c.Request.Context(), userID, targetID); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, dtos.SuccessResponse{
+		Message: "Verification code sent",
+	})
+}
+
+// Verify marks the phone number as verified when the code matches
+func (h *PhoneHandler) Verify(c *gin.Context) {
+	targetID, ok := pathUUID(c, "id")
+	if !ok {
+		return
+	}
+
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var req dtos.VerifyPhoneRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperr.Validation(err))
+		return
+	}
+
+	user, err := h.phoneService.Verify(c.Request.Context(), userID, targetID, req.Code)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dtos.UserTransformer(dtos.SafeUser(user)))
+}
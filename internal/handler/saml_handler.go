@@ -0,0 +1,80 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+	"user-management/internal/apperr"
+	"user-management/internal/auth"
+	"user-management/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SAMLHandler struct {
+	userService  service.UserService
+	samlProvider *auth.SAMLProvider
+}
+
+func NewSAMLHandler(userService service.UserService, samlProvider *auth.SAMLProvider) *SAMLHandler {
+	return &SAMLHandler{
+		userService:  userService,
+		samlProvider: samlProvider,
+	}
+}
+
+// Metadata serves the service provider metadata for an organization's IdP
+func (h *SAMLHandler) Metadata(c *gin.Context) {
+	metadata, err := h.samlProvider.Metadata(c.Param("org"))
+	if err != nil {
+		c.Error(auth.ErrSAMLConnectionNotFound)
+		return
+	}
+
+	c.Data(http.StatusOK, "application/samlmetadata+xml", metadata)
+}
+
+// Discover redirects to the organization login owning the email domain
+func (h *SAMLHandler) Discover(c *gin.Context) {
+	email := strings.ToLower(strings.TrimSpace(c.Query("email")))
+	org, err := h.samlProvider.OrganizationForEmail(email)
+	if err != nil {
+		c.Error(auth.ErrSAMLConnectionNotFound)
+		return
+	}
+
+	h.redirectToIDP(c, org)
+}
+
+// Login starts SP-initiated SSO with an AuthnRequest over the HTTP-Redirect binding.
+// Pass force_authn=true to make the IdP re-authenticate the user.
+func (h *SAMLHandler) Login(c *gin.Context) {
+	h.redirectToIDP(c, c.Param("org"))
+}
+
+// ACS consumes the IdP response and exchanges the assertion for our own tokens
+func (h *SAMLHandler) ACS(c *gin.Context) {
+	identity, err := h.samlProvider.ParseResponse(c.Request.Context(), c.Param("org"), c.Request)
+	if err != nil {
+		c.Error(apperr.Or(err, auth.ErrSAMLAuthFailed))
+		return
+	}
+
+	response, err := h.userService.SignInWithIdentity(c.Request.Context(), identity)
+	if err != nil {
+		c.Error(apperr.Or(err, service.ErrInvalidCredentials))
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *SAMLHandler) redirectToIDP(c *gin.Context, org string) {
+	forceAuthn := c.Query("force_authn") == "true"
+	redirectURL, err := h.samlProvider.LoginURL(c.Request.Context(), org, forceAuthn)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Redirect(http.StatusFound, redirectURL)
+}
@@ -3,21 +3,36 @@ package handler
 import (
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
+	"user-management/internal/apperr"
 	"user-management/internal/dtos"
+	"user-management/internal/middleware"
+	"user-management/internal/models"
+	"user-management/internal/repository"
 	"user-management/internal/service"
-	"user-management/internal/utils"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
 
 type UserHandler struct {
-	userService service.UserService
+	userService      service.UserService
+	attributeService service.AttributeService
+	stepUpMaxAge     time.Duration
+	stepUpMFAMaxAge  time.Duration
 }
 
-func NewUserHandler(userService service.UserService) *UserHandler {
+func NewUserHandler(
+	userService service.UserService,
+	attributeService service.AttributeService,
+	stepUpMaxAge, stepUpMFAMaxAge time.Duration,
+) *UserHandler {
 	return &UserHandler{
-		userService: userService,
+		userService:      userService,
+		attributeService: attributeService,
+		stepUpMaxAge:     stepUpMaxAge,
+		stepUpMFAMaxAge:  stepUpMFAMaxAge,
 	}
 }
 
@@ -25,119 +40,61 @@ func (h *UserHandler) GetUser(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := uuid.Parse(idStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, dtos.ErrorResponse{
-			Error:   utils.ErrCodeInvalidID,
-			Message: "Invalid user ID format",
-		})
+		c.Error(apperr.ErrInvalidID.WithMessage("Invalid user ID format"))
 		return
 	}
 
-	// Get current user ID from context
-	userIDStr, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, dtos.ErrorResponse{
-			Error:   utils.ErrCodeUnauthorized,
-			Message: "User not authenticated",
-		})
+	userID, ok := currentUserID(c)
+	if !ok {
 		return
 	}
-	userID, err := uuid.Parse(userIDStr.(string))
+
+	user, err := h.userService.GetUser(c.Request.Context(), userID, id)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, dtos.ErrorResponse{
-			Error:   utils.ErrCodeUnauthorized,
-			Message: "Invalid session user ID",
-		})
+		c.Error(err)
 		return
 	}
 
-	user, err := h.userService.GetUser(c.Request.Context(), userID, id)
+	users := []models.User{dtos.SafeUser(user)}
+	attributes, err := h.attributeService.Readable(c.Request.Context(), userID, users)
 	if err != nil {
-		switch err.Error() {
-		case "unauthorized":
-			c.JSON(http.StatusForbidden, dtos.ErrorResponse{
-				Error:   utils.ErrCodeForbidden,
-				Message: "You don't have permission to view this user",
-			})
-		case "user not found":
-			c.JSON(http.StatusNotFound, dtos.ErrorResponse{
-				Error:   utils.ErrCodeNotFound,
-				Message: "User not found",
-			})
-		default:
-			c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{
-				Error:   utils.ErrCodeInternalServerError,
-				Message: "Failed to get user",
-			})
-		}
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, dtos.UserTransformer(dtos.SafeUser(user)))
+	resp := dtos.UserTransformer(users[0])
+	resp.Attributes = attributes[0]
+	c.JSON(http.StatusOK, resp)
 }
 
 func (h *UserHandler) UpdateUser(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := uuid.Parse(idStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, dtos.ErrorResponse{
-			Error:   utils.ErrCodeInvalidID,
-			Message: "Invalid user ID format",
-		})
+		c.Error(apperr.ErrInvalidID.WithMessage("Invalid user ID format"))
 		return
 	}
 
 	var req dtos.UpdateUserRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, dtos.ErrorResponse{
-			Error:   utils.ErrCodeValidationError,
-			Message: "Invalid request payload",
-			Details: err.Error(),
-		})
+		c.Error(apperr.Validation(err))
 		return
 	}
 
-	// Get current user ID from context
-	userIDStr, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, dtos.ErrorResponse{
-			Error:   utils.ErrCodeUnauthorized,
-			Message: "User not authenticated",
-		})
+	// Changing credentials requires a recent sign-in
+	if (req.Email != "" || req.Password != "") && !middleware.RecentlyAuthenticated(c, h.stepUpMaxAge, h.stepUpMFAMaxAge) {
+		middleware.AbortWithStepUpChallenge(c, h.stepUpMaxAge, h.stepUpMFAMaxAge)
 		return
 	}
-	userID, err := uuid.Parse(userIDStr.(string))
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{
-			Error:   utils.ErrCodeInternalServerError,
-			Message: "Invalid session",
-		})
+
+	userID, ok := currentUserID(c)
+	if !ok {
 		return
 	}
 
 	user, err := h.userService.UpdateUser(c.Request.Context(), userID, id, &req)
 	if err != nil {
-		switch err.Error() {
-		case "unauthorized":
-			c.JSON(http.StatusForbidden, dtos.ErrorResponse{
-				Error:   utils.ErrCodeForbidden,
-				Message: err.Error(),
-			})
-		case "user not found":
-			c.JSON(http.StatusNotFound, dtos.ErrorResponse{
-				Error:   utils.ErrCodeNotFound,
-				Message: "User not found",
-			})
-		case "email already in use":
-			c.JSON(http.StatusConflict, dtos.ErrorResponse{
-				Error:   utils.ErrCodeConflict,
-				Message: "Email already in use",
-			})
-		default:
-			c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{
-				Error:   utils.ErrCodeInternalServerError,
-				Message: "Failed to update user",
-			})
-		}
+		c.Error(err)
 		return
 	}
 
@@ -159,20 +116,40 @@ func (h *UserHandler) ListUsers(c *gin.Context) {
 	if lastIDStr != "" {
 		lastID, err = uuid.Parse(lastIDStr)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, dtos.ErrorResponse{
-				Error:   utils.ErrCodeInvalidCursor,
-				Message: "Invalid last_id format",
-			})
+			c.Error(apperr.ErrInvalidCursor.WithMessage("Invalid last_id format"))
 			return
 		}
 	}
 
-	users, err := h.userService.ListUsers(c.Request.Context(), lastID, searchEmail, limit)
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	// Custom attribute filters are passed as attr.<key>=<value>
+	rawFilter := make(map[string]string)
+	for key, values := range c.Request.URL.Query() {
+		if name, found := strings.CutPrefix(key, "attr."); found && len(values) > 0 {
+			rawFilter[name] = values[0]
+		}
+	}
+
+	attributeFilter, err := h.attributeService.ParseFilter(c.Request.Context(), userID, rawFilter)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	filter := repository.UserFilter{Email: searchEmail, Attributes: attributeFilter}
+	users, err := h.userService.ListUsers(c.Request.Context(), lastID, filter, limit)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{
-			Error:   utils.ErrCodeInternalServerError,
-			Message: "Failed to list users",
-		})
+		c.Error(err)
+		return
+	}
+
+	attributes, err := h.attributeService.Readable(c.Request.Context(), userID, users)
+	if err != nil {
+		c.Error(err)
 		return
 	}
 
@@ -181,11 +158,36 @@ func (h *UserHandler) ListUsers(c *gin.Context) {
 		nextCursor = users[len(users)-1].ID.String()
 	}
 
+	items := dtos.UsersTransformer(users)
+	for i := range items {
+		items[i].Attributes = attributes[i]
+	}
+
 	c.JSON(http.StatusOK, dtos.UserListResponse{
-		Users: dtos.UsersTransformer(users),
+		Users: items,
 		Pagination: dtos.Pagination{
 			Limit:      limit,
 			NextCursor: nextCursor,
 		},
 	})
 }
+
+func (h *UserHandler) DeleteUser(c *gin.Context) {
+	targetID, ok := pathUUID(c, "id")
+	if !ok {
+		return
+	}
+
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	response, err := h.userService.DeleteUser(c.Request.Context(), userID, targetID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
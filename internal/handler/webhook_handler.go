@@ -0,0 +1,214 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"user-management/internal/apperr"
+	"user-management/internal/dtos"
+	"user-management/internal/models"
+	"user-management/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type WebhookHandler struct {
+	webhookService service.WebhookService
+}
+
+func NewWebhookHandler(webhookService service.WebhookService) *WebhookHandler {
+	return &WebhookHandler{
+		webhookService: webhookService,
+	}
+}
+
+func (h *WebhookHandler) ListSubscriptions(c *gin.Context) {
+	subscriptions, err := h.webhookService.ListSubscriptions(c.Request.Context())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dtos.WebhookSubscriptionListResponse{
+		Subscriptions: dtos.WebhookSubscriptionsTransformer(subscriptions),
+	})
+}
+
+func (h *WebhookHandler) CreateSubscription(c *gin.Context) {
+	actorID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var req dtos.WebhookSubscriptionRequest
+	if !bindWebhookRequest(c, &req) {
+		return
+	}
+
+	subscription, err := h.webhookService.CreateSubscription(c.Request.Context(), actorID, &req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, dtos.WebhookSubscriptionTransformer(subscription, subscription.Secret))
+}
+
+func (h *WebhookHandler) GetSubscription(c *gin.Context) {
+	id, ok := pathUUID(c, "id")
+	if !ok {
+		return
+	}
+
+	subscription, err := h.webhookService.GetSubscription(c.Request.Context(), id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dtos.WebhookSubscriptionTransformer(subscription, ""))
+}
+
+func (h *WebhookHandler) UpdateSubscription(c *gin.Context) {
+	id, ok := pathUUID(c, "id")
+	if !ok {
+		return
+	}
+
+	actorID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var req dtos.WebhookSubscriptionRequest
+	if !bindWebhookRequest(c, &req) {
+		return
+	}
+
+	subscription, err := h.webhookService.UpdateSubscription(c.Request.Context(), actorID, id, &req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dtos.WebhookSubscriptionTransformer(subscription, ""))
+}
+
+func (h *WebhookHandler) DeleteSubscription(c *gin.Context) {
+	id, ok := pathUUID(c, "id")
+	if !ok {
+		return
+	}
+
+	actorID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.webhookService.DeleteSubscription(c.Request.Context(), actorID, id); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *WebhookHandler) RotateSecret(c *gin.Context) {
+	id, ok := pathUUID(c, "id")
+	if !ok {
+		return
+	}
+
+	actorID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	subscription, err := h.webhookService.RotateSecret(c.Request.Context(), actorID, id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dtos.WebhookSubscriptionTransformer(subscription, subscription.Secret))
+}
+
+func (h *WebhookHandler) ListDeliveries(c *gin.Context) {
+	id, ok := pathUUID(c, "id")
+	if !ok {
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	status := c.Query("status")
+	switch status {
+	case "", models.DeliveryStatusPending, models.DeliveryStatusDelivered, models.DeliveryStatusDead:
+	default:
+		c.Error(apperr.ErrValidation.WithMessage("Invalid status, expected pending, delivered or dead"))
+		return
+	}
+
+	var cursor uuid.UUID
+	if raw := c.Query("cursor"); raw != "" {
+		if cursor, err = uuid.Parse(raw); err != nil {
+			c.Error(apperr.ErrInvalidCursor)
+			return
+		}
+	}
+
+	deliveries, err := h.webhookService.ListDeliveries(c.Request.Context(), id, status, cursor, limit)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	var nextCursor string
+	if len(deliveries) == limit {
+		nextCursor = deliveries[len(deliveries)-1].ID.String()
+	}
+
+	c.JSON(http.StatusOK, dtos.WebhookDeliveryListResponse{
+		Deliveries: deliveries,
+		Pagination: dtos.Pagination{
+			Limit:      limit,
+			NextCursor: nextCursor,
+		},
+	})
+}
+
+func (h *WebhookHandler) Replay(c *gin.Context) {
+	id, ok := pathUUID(c, "id")
+	if !ok {
+		return
+	}
+
+	actorID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var req dtos.WebhookReplayRequest
+	if !bindWebhookRequest(c, &req) {
+		return
+	}
+
+	queued, err := h.webhookService.Replay(c.Request.Context(), actorID, id, &req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, dtos.WebhookReplayResponse{Queued: queued})
+}
+
+func bindWebhookRequest(c *gin.Context, req interface{}) bool {
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.Error(apperr.Validation(err))
+		return false
+	}
+	return true
+}
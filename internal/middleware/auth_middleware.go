@@ -1,50 +1,80 @@
 package middleware
 
 import (
-	"net/http"
+	"context"
 	"strings"
+	"user-management/internal/apperr"
+	"user-management/internal/reqctx"
 	"user-management/internal/utils"
+	"user-management/pkg/logger"
 
 	"github.com/gin-gonic/gin"
 )
 
-func Auth(jwtManager *utils.JWTManager) gin.HandlerFunc {
+// TokenValidator performs additional checks on a token whose signature is valid,
+// such as session revocation or account status
+type TokenValidator interface {
+	ValidateToken(ctx context.Context, claims *utils.Claims) error
+}
+
+func Auth(jwtManager *utils.JWTManager, validators ...TokenValidator) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"error":   "unauthorized",
-				"message": "Authorization header is required",
-			})
-			c.Abort()
+			abortWithError(c, apperr.ErrUnauthorized.WithMessage("Authorization header is required"))
 			return
 		}
 
 		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
 		if tokenString == authHeader {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"error":   "unauthorized",
-				"message": "Bearer token is required",
-			})
-			c.Abort()
+			abortWithError(c, apperr.ErrUnauthorized.WithMessage("Bearer token is required"))
 			return
 		}
 
 		claims, err := jwtManager.Validate(tokenString)
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"error":   "unauthorized",
-				"message": "Invalid or expired token",
-			})
-			c.Abort()
+			abortWithError(c, apperr.ErrUnauthorized.WithMessage("Invalid or expired token").Wrap(err))
 			return
 		}
 
+		for _, validator := range validators {
+			if err := validator.ValidateToken(c.Request.Context(), claims); err != nil {
+				abortWithError(c, tokenRejected(c, claims, err))
+				return
+			}
+		}
+
 		// Set user context
 		c.Set("user_id", claims.UserID)
 		c.Set("email", claims.Email)
+		c.Set("role", claims.Role)
+		c.Set("session_id", claims.SessionID)
+		c.Set("claims", claims)
 		c.Set("token", tokenString)
 
+		if claims.Impersonated() {
+			c.Set("impersonator_id", claims.Actor.Subject)
+			c.Request = c.Request.WithContext(reqctx.WithImpersonator(c.Request.Context(), claims.Actor.Subject))
+		}
+
+		// Log lines written further down the chain identify the user
+		ctx := c.Request.Context()
+		userLog := logger.FromContext(ctx).With().Str("user_id", claims.UserID).Logger()
+		c.Request = c.Request.WithContext(userLog.WithContext(ctx))
+
 		c.Next()
 	}
 }
+
+// tokenRejected turns a validator failure into a plain 401, so a deleted account or revoked session looks
+// the same as any other invalid token and clients refresh as usual. The cause is only logged.
+func tokenRejected(c *gin.Context, claims *utils.Claims, err error) error {
+	ctx := c.Request.Context()
+	logger.FromContext(ctx).Info().
+		Err(err).
+		Str("user_id", claims.UserID).
+		Str("session_id", claims.SessionID).
+		Msg("Token rejected")
+
+	return apperr.ErrUnauthorized.WithMessage("Token is no longer valid").Wrap(err)
+}
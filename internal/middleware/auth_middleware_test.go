@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"user-management/internal/apperr"
+	"user-management/internal/config"
+	"user-management/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type rejectingValidator struct {
+	err error
+}
+
+func (v rejectingValidator) ValidateToken(context.Context, *utils.Claims) error {
+	return v.err
+}
+
+func TestAuthMapsValidatorFailuresToUnauthorized(t *testing.T) {
+	jwtManager := utils.NewJWTManager(&config.JWTConfig{Secret: "test-secret", AccessExpiration: time.Minute, Issuer: "test"})
+	token, err := jwtManager.GenerateAccessToken(utils.TokenSubject{UserID: "user-1", SessionID: "session-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{"deleted user", apperr.ErrNotFound.WithMessage("User not found")},
+		{"revoked session", apperr.ErrNotFound.WithMessage("Session not found")},
+		{"suspended account", apperr.ErrForbidden.WithMessage("Account suspended")},
+		{"lookup failure", errors.New("connection refused")},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			router := gin.New()
+			router.Use(Errors(false))
+			router.GET("/", Auth(jwtManager, rejectingValidator{err: tc.err}), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+			request := httptest.NewRequest(http.MethodGet, "/", nil)
+			request.Header.Set("Authorization", "Bearer "+token)
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request)
+
+			if recorder.Code != http.StatusUnauthorized {
+				t.Fatalf("status = %d, want 401; body = %s", recorder.Code, recorder.Body.String())
+			}
+		})
+	}
+}
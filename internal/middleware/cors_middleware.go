@@ -13,7 +13,7 @@ func CORS(cfg config.CORSConfig) gin.HandlerFunc {
 		AllowOrigins:     cfg.AllowOrigins,
 		AllowMethods:     cfg.AllowMethods,
 		AllowHeaders:     cfg.AllowHeaders,
-		ExposeHeaders:    []string{"Content-Length", "X-Total-Count"},
+		ExposeHeaders:    []string{"Content-Length", "X-Total-Count", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "RateLimit-Policy", "Retry-After", "X-Request-ID"},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	})
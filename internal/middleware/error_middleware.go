@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+	"user-management/internal/apperr"
+	"user-management/internal/dtos"
+	"user-management/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+const problemContentType = "application/problem+json"
+
+// Errors renders the last error attached with c.Error once the handler chain returns, unless a response
+// was already written. Errors that are not an *apperr.Error become a 500 whose cause is only logged.
+// Must run after Logging and Metrics so they record the rendered status.
+func Errors(problemDetails bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+
+		err := c.Errors.Last().Err
+		if appErr := apperr.From(err); appErr.Status >= http.StatusInternalServerError {
+			ctx := c.Request.Context()
+			logger.FromContext(ctx).Error().
+				Ctx(ctx).
+				Err(err).
+				Str("code", appErr.Code).
+				Msg("Request failed")
+		}
+
+		renderError(c, err, problemDetails)
+	}
+}
+
+// renderError writes err as an ErrorResponse, or as RFC 7807 problem details when configured or
+// requested through the Accept header
+func renderError(c *gin.Context, err error, problemDetails bool) {
+	appErr := apperr.From(err)
+	requestID := c.GetString("request_id")
+	value, _ := c.Get(stepUpChallengeKey)
+	challenge, _ := value.(*dtos.StepUpRequirement)
+
+	if problemDetails || strings.Contains(c.GetHeader("Accept"), problemContentType) {
+		c.Header("Content-Type", problemContentType)
+		c.AbortWithStatusJSON(appErr.Status, dtos.ProblemDetails{
+			Type:      "about:blank",
+			Title:     http.StatusText(appErr.Status),
+			Status:    appErr.Status,
+			Detail:    appErr.Message,
+			Instance:  c.Request.URL.Path,
+			Code:      appErr.Code,
+			Details:   appErr.Details,
+			Challenge: challenge,
+			RequestID: requestID,
+		})
+		return
+	}
+
+	c.AbortWithStatusJSON(appErr.Status, dtos.ErrorResponse{
+		Error:     appErr.Code,
+		Message:   appErr.Message,
+		Details:   appErr.Details,
+		Challenge: challenge,
+		RequestID: requestID,
+	})
+}
+
+// abortWithError records err for the Errors middleware and stops the chain
+func abortWithError(c *gin.Context, err error) {
+	c.Error(err)
+	c.Abort()
+}
@@ -7,18 +7,35 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+// Logging stores a request-scoped logger in the request context and writes one line per request.
+// Must run after RequestID.
 func Logging(log *logger.Logger) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		start := time.Now()
 		path := c.Request.URL.Path
 		query := c.Request.URL.RawQuery
 
+		requestLog := log.With().
+			Str("request_id", c.GetString("request_id")).
+			Str("route", c.FullPath()).
+			Logger()
+		c.Request = c.Request.WithContext(requestLog.WithContext(c.Request.Context()))
+
 		c.Next()
 
 		end := time.Now()
 		latency := end.Sub(start)
 
-		log.Info().
+		// The logger may have gained the user ID during the request
+		ctx := c.Request.Context()
+		event := logger.FromContext(ctx).Info().Ctx(ctx)
+
+		// Requests made while impersonating carry both identities
+		if impersonatorID := c.GetString("impersonator_id"); impersonatorID != "" {
+			event = event.Str("impersonator_id", impersonatorID).Bool("impersonated", true)
+		}
+
+		event.
 			Str("method", c.Request.Method).
 			Str("path", path).
 			Str("query", query).
@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"strconv"
+	"time"
+	"user-management/internal/apperr"
+	"user-management/pkg/metrics"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Metrics records request counts and latencies by route template, so path parameters don't multiply the series
+func Metrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		method := c.Request.Method
+
+		metrics.HTTPRequests.WithLabelValues(method, route, strconv.Itoa(c.Writer.Status())).Inc()
+		metrics.HTTPRequestDuration.WithLabelValues(method, route).Observe(time.Since(start).Seconds())
+	}
+}
+
+// MetricsAuth protects the metrics endpoint with a static bearer token
+func MetricsAuth(token string) gin.HandlerFunc {
+	expected := []byte("Bearer " + token)
+
+	return func(c *gin.Context) {
+		if subtle.ConstantTimeCompare([]byte(c.GetHeader("Authorization")), expected) != 1 {
+			abortWithError(c, apperr.ErrUnauthorized.WithMessage("A valid metrics token is required"))
+			return
+		}
+		c.Next()
+	}
+}
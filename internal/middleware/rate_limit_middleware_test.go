@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// rateLimitKeyFor runs rateLimitKey on a request from remoteAddr behind a router trusting the given proxies
+func rateLimitKeyFor(t *testing.T, trustedProxies []string, keyBy, remoteAddr string, headers map[string]string, set map[string]string) string {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	if err := router.SetTrustedProxies(trustedProxies); err != nil {
+		t.Fatal(err)
+	}
+
+	var key string
+	router.GET("/", func(c *gin.Context) {
+		for name, value := range set {
+			c.Set(name, value)
+		}
+		key = rateLimitKey(c, keyBy)
+	})
+
+	request := httptest.NewRequest(http.MethodGet, "/", nil)
+	request.RemoteAddr = remoteAddr
+	for name, value := range headers {
+		request.Header.Set(name, value)
+	}
+	router.ServeHTTP(httptest.NewRecorder(), request)
+	return key
+}
+
+func TestRateLimitKey(t *testing.T) {
+	spoofed := map[string]string{"X-Forwarded-For": "198.51.100.1", "X-Real-IP": "198.51.100.2"}
+
+	tests := []struct {
+		name           string
+		trustedProxies []string
+		keyBy          string
+		remoteAddr     string
+		headers        map[string]string
+		set            map[string]string
+		want           string
+	}{
+		{"forwarding headers ignored by default", nil, "ip", "203.0.113.7:1234", spoofed, nil, "ip:203.0.113.7"},
+		{"forwarding headers ignored from untrusted peer", []string{"10.0.0.0/8"}, "ip", "203.0.113.7:1234", spoofed, nil, "ip:203.0.113.7"},
+		{"forwarding headers believed from trusted proxy", []string{"10.0.0.0/8"}, "ip", "10.1.2.3:1234",
+			map[string]string{"X-Forwarded-For": "203.0.113.7"}, nil, "ip:203.0.113.7"},
+		{"authenticated user", nil, "user", "203.0.113.7:1234", nil, map[string]string{"user_id": "user-1"}, "user:user-1"},
+		{"anonymous user falls back to IP", nil, "user", "203.0.113.7:1234", nil, nil, "ip:203.0.113.7"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := rateLimitKeyFor(t, tc.trustedProxies, tc.keyBy, tc.remoteAddr, tc.headers, tc.set); got != tc.want {
+				t.Fatalf("key = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
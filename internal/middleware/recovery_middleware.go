@@ -1,30 +1,27 @@
 package middleware
 
 import (
-	"net/http"
+	"user-management/internal/apperr"
 	"user-management/pkg/logger"
 
 	"github.com/gin-gonic/gin"
 )
 
-func Recovery(log *logger.Logger) gin.HandlerFunc {
+// Recovery turns panics into a 500 response. Must run after RequestID.
+func Recovery(problemDetails bool) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		defer func() {
 			if err := recover(); err != nil {
 				// Log the panic
-				log.Error().
+				logger.FromContext(c.Request.Context()).Error().
+					Ctx(c.Request.Context()).
 					Str("path", c.Request.URL.Path).
 					Str("method", c.Request.Method).
 					Interface("error", err).
 					Msg("Recovered from panic")
 
 				// Return error response
-				c.JSON(http.StatusInternalServerError, gin.H{
-					"error":   "internal_server_error",
-					"message": "An unexpected error occurred",
-				})
-
-				c.Abort()
+				renderError(c, apperr.ErrInternal, problemDetails)
 			}
 		}()
 
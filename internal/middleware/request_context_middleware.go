@@ -0,0 +1,21 @@
+package middleware
+
+import (
+	"user-management/internal/reqctx"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestContext stores client metadata in the request context for the service layer. Must run after RequestID.
+func RequestContext() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := reqctx.WithClientInfo(c.Request.Context(), reqctx.ClientInfo{
+			IP:        c.ClientIP(),
+			UserAgent: c.Request.UserAgent(),
+			RequestID: c.GetString("request_id"),
+		})
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+	}
+}
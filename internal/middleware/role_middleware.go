@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"user-management/internal/apperr"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireRole allows the request only when the token carries one of the roles. Must run after Auth,
+// whose validators reject tokens whose role no longer matches the account.
+// Impersonation tokens are always rejected so support staff cannot escalate through a user's identity.
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role := c.GetString("role")
+		if c.GetString("impersonator_id") != "" {
+			role = ""
+		}
+
+		for _, allowed := range roles {
+			if role == allowed {
+				c.Next()
+				return
+			}
+		}
+
+		abortWithError(c, apperr.ErrForbidden.WithMessage("Insufficient permissions"))
+	}
+}
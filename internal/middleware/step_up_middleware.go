@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+	"user-management/internal/apperr"
+	"user-management/internal/dtos"
+	"user-management/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// stepUpChallengeKey holds the challenge that the error renderer adds to a STEP_UP_REQUIRED response
+const stepUpChallengeKey = "step_up_challenge"
+
+var ErrStepUpRequired = apperr.New(http.StatusUnauthorized, utils.ErrCodeStepUpRequired, "Please sign in again to continue")
+
+// StepUpEndpoint sets where clients re-authenticate when a step-up challenge is issued
+func StepUpEndpoint(endpoint string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set("step_up_endpoint", endpoint)
+		c.Next()
+	}
+}
+
+// StepUp requires recent authentication for every request on the route. Must run after Auth.
+func StepUp(maxAge, mfaMaxAge time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !RecentlyAuthenticated(c, maxAge, mfaMaxAge) {
+			AbortWithStepUpChallenge(c, maxAge, mfaMaxAge)
+			return
+		}
+		c.Next()
+	}
+}
+
+// RecentlyAuthenticated reports whether the token's auth_time is within maxAge, or within the longer
+// mfaMaxAge when the sign-in used MFA. A token never qualifies for its whole lifetime just because MFA
+// was used once. Impersonation tokens never qualify.
+func RecentlyAuthenticated(c *gin.Context, maxAge, mfaMaxAge time.Duration) bool {
+	value, exists := c.Get("claims")
+	if !exists {
+		return false
+	}
+
+	claims, ok := value.(*utils.Claims)
+	if !ok || claims.Impersonated() {
+		return false
+	}
+
+	if claims.HasAMR(utils.AMRMFA) && claims.AuthenticatedWithin(mfaMaxAge) {
+		return true
+	}
+	return claims.AuthenticatedWithin(maxAge)
+}
+
+// AbortWithStepUpChallenge responds with an RFC 9470 challenge describing how to re-authenticate
+func AbortWithStepUpChallenge(c *gin.Context, maxAge, mfaMaxAge time.Duration) {
+	maxAgeSeconds := int(maxAge.Seconds())
+
+	c.Header("WWW-Authenticate", fmt.Sprintf(
+		`Bearer error="insufficient_user_authentication", error_description="%s", max_age="%d"`,
+		"A more recent authentication is required", maxAgeSeconds,
+	))
+
+	c.Set(stepUpChallengeKey, &dtos.StepUpRequirement{
+		Reason:    "insufficient_user_authentication",
+		MaxAge:    maxAgeSeconds,
+		MFAMaxAge: int(mfaMaxAge.Seconds()),
+		AMR:       []string{utils.AMRPassword, utils.AMRMFA},
+		Endpoint:  c.GetString("step_up_endpoint"),
+	})
+	abortWithError(c, ErrStepUpRequired)
+}
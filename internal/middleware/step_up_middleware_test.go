@@ -0,0 +1,101 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"user-management/internal/dtos"
+	"user-management/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func stepUpRouter(claims *utils.Claims) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Errors(false))
+	group := router.Group("/api/v1")
+	auth := group.Group("/auth")
+	group.Use(
+		func(c *gin.Context) { c.Set("claims", claims) },
+		StepUpEndpoint(auth.BasePath()+"/signin"),
+	)
+	group.DELETE("/users/me", StepUp(5*time.Minute, time.Hour), func(c *gin.Context) { c.Status(http.StatusNoContent) })
+	return router
+}
+
+func authenticatedAt(at time.Time, amr ...string) *utils.Claims {
+	return &utils.Claims{UserID: "user-1", AuthTime: jwt.NewNumericDate(at), AMR: amr}
+}
+
+func TestStepUp(t *testing.T) {
+	tests := []struct {
+		name   string
+		claims *utils.Claims
+		want   int
+	}{
+		{"recent sign-in", authenticatedAt(time.Now().Add(-time.Minute), utils.AMRPassword), http.StatusNoContent},
+		{"stale sign-in", authenticatedAt(time.Now().Add(-time.Hour), utils.AMRPassword), http.StatusUnauthorized},
+		{"MFA sign-in within the MFA window", authenticatedAt(time.Now().Add(-30*time.Minute), utils.AMRPassword, utils.AMRMFA), http.StatusNoContent},
+		{"stale MFA sign-in", authenticatedAt(time.Now().Add(-2*time.Hour), utils.AMRPassword, utils.AMRMFA), http.StatusUnauthorized},
+		{"password sign-in within the MFA window", authenticatedAt(time.Now().Add(-30*time.Minute), utils.AMRPassword), http.StatusUnauthorized},
+		{"missing auth_time", &utils.Claims{UserID: "user-1", AMR: []string{utils.AMRMFA}}, http.StatusUnauthorized},
+		{"impersonation", &utils.Claims{
+			UserID:   "user-1",
+			AuthTime: jwt.NewNumericDate(time.Now()),
+			Actor:    &utils.ActorClaim{Subject: "admin-1"},
+		}, http.StatusUnauthorized},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			stepUpRouter(tc.claims).ServeHTTP(recorder, httptest.NewRequest(http.MethodDelete, "/api/v1/users/me", nil))
+			if recorder.Code != tc.want {
+				t.Fatalf("status = %d, want %d", recorder.Code, tc.want)
+			}
+		})
+	}
+}
+
+func TestStepUpChallenge(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	router := stepUpRouter(authenticatedAt(time.Now().Add(-time.Hour), utils.AMRPassword))
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodDelete, "/api/v1/users/me", nil))
+
+	if got := recorder.Header().Get("WWW-Authenticate"); got == "" {
+		t.Error("missing WWW-Authenticate header")
+	}
+
+	var body dtos.ErrorResponse
+	if err := json.Unmarshal(recorder.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Error != utils.ErrCodeStepUpRequired || body.Challenge == nil {
+		t.Fatalf("body = %s", recorder.Body.String())
+	}
+	if body.Challenge.MaxAge != 300 || body.Challenge.MFAMaxAge != 3600 {
+		t.Errorf("max_age, mfa_max_age = %d, %d, want 300, 3600", body.Challenge.MaxAge, body.Challenge.MFAMaxAge)
+	}
+	if body.Challenge.Endpoint != "/api/v1/auth/signin" {
+		t.Errorf("endpoint = %q, want the sign-in route of the auth group", body.Challenge.Endpoint)
+	}
+}
+
+func TestStepUpChallengeAsProblemDetails(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodDelete, "/api/v1/users/me", nil)
+	request.Header.Set("Accept", problemContentType)
+	stepUpRouter(authenticatedAt(time.Now().Add(-time.Hour), utils.AMRPassword)).ServeHTTP(recorder, request)
+
+	var body dtos.ProblemDetails
+	if err := json.Unmarshal(recorder.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if recorder.Header().Get("Content-Type") != problemContentType || body.Status != http.StatusUnauthorized ||
+		body.Code != utils.ErrCodeStepUpRequired || body.Challenge == nil {
+		t.Fatalf("response = %s %s", recorder.Header().Get("Content-Type"), recorder.Body.String())
+	}
+}
@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN IF NOT EXISTS role TEXT NOT NULL DEFAULT 'user';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS auth_provider TEXT NOT NULL DEFAULT 'local';
+
+-- +goose Down
+ALTER TABLE users DROP COLUMN IF EXISTS auth_provider;
+ALTER TABLE users DROP COLUMN IF EXISTS role;
@@ -0,0 +1,21 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN IF NOT EXISTS attributes JSONB NOT NULL DEFAULT '{}';
+
+CREATE INDEX IF NOT EXISTS idx_users_attributes ON users USING GIN (attributes jsonb_path_ops);
+
+CREATE TABLE IF NOT EXISTS attribute_definitions (
+    key VARCHAR(64) PRIMARY KEY,
+    description VARCHAR(255) NOT NULL DEFAULT '',
+    schema JSONB NOT NULL,
+    read_permission VARCHAR(20) NOT NULL DEFAULT 'self'
+        CHECK (read_permission IN ('public', 'self', 'admin')),
+    write_permission VARCHAR(20) NOT NULL DEFAULT 'admin'
+        CHECK (write_permission IN ('self', 'admin')),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS attribute_definitions;
+DROP INDEX IF EXISTS idx_users_attributes;
+ALTER TABLE users DROP COLUMN IF EXISTS attributes;
@@ -0,0 +1,10 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN IF NOT EXISTS phone_verified_at TIMESTAMP WITH TIME ZONE;
+
+-- A verified number signs in to exactly one account
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_verified_phone_number
+    ON users(phone_number) WHERE phone_verified_at IS NOT NULL;
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_users_verified_phone_number;
+ALTER TABLE users DROP COLUMN IF EXISTS phone_verified_at;
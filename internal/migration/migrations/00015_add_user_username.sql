@@ -0,0 +1,10 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN IF NOT EXISTS username VARCHAR(32);
+
+-- Usernames are unique regardless of case; the index also serves sign-in lookups
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_username_lower
+    ON users(LOWER(username)) WHERE username IS NOT NULL;
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_users_username_lower;
+ALTER TABLE users DROP COLUMN IF EXISTS username;
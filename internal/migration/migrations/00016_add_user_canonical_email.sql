@@ -0,0 +1,24 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN IF NOT EXISTS canonical_email VARCHAR(255);
+
+-- Provisional value only: the provider rules (EMAIL_CANONICAL_RULES) live in configuration, so existing
+-- accounts are normalized by `go run scripts/migrate.go -command canonical-emails` after the deploy
+UPDATE users SET canonical_email = LOWER(email) WHERE canonical_email IS NULL;
+ALTER TABLE users ALTER COLUMN canonical_email SET NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_users_canonical_email_lookup ON users(canonical_email);
+
+-- users_email_key stays until the backfill has checked for collisions; the backfill then creates the
+-- unique idx_users_canonical_email and drops it
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_users_canonical_email;
+DROP INDEX IF EXISTS idx_users_canonical_email_lookup;
+-- +goose StatementBegin
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'users_email_key') THEN
+        ALTER TABLE users ADD CONSTRAINT users_email_key UNIQUE (email);
+    END IF;
+END $$;
+-- +goose StatementEnd
+ALTER TABLE users DROP COLUMN IF EXISTS canonical_email;
@@ -0,0 +1,9 @@
+-- +goose Up
+-- Tracks publication to the message broker, independently of webhook dispatch
+ALTER TABLE outbox_events ADD COLUMN IF NOT EXISTS published_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS idx_outbox_events_unpublished ON outbox_events(id) WHERE published_at IS NULL;
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_outbox_events_unpublished;
+ALTER TABLE outbox_events DROP COLUMN IF EXISTS published_at;
@@ -0,0 +1,11 @@
+-- +goose Up
+-- Lets the worker reclaim exports left in processing by an instance that crashed
+ALTER TABLE data_exports ADD COLUMN IF NOT EXISTS claimed_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE data_exports ADD COLUMN IF NOT EXISTS attempts INTEGER NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS idx_data_exports_processing ON data_exports(claimed_at) WHERE status = 'processing';
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_data_exports_processing;
+ALTER TABLE data_exports DROP COLUMN IF EXISTS attempts;
+ALTER TABLE data_exports DROP COLUMN IF EXISTS claimed_at;
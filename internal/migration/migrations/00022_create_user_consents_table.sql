@@ -0,0 +1,15 @@
+-- +goose Up
+-- Every consent given or withdrawn, so the state at any point in time can be shown
+CREATE TABLE IF NOT EXISTS user_consents (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    purpose VARCHAR(64) NOT NULL,
+    version VARCHAR(32) NOT NULL,
+    granted BOOLEAN NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_consents_user_id ON user_consents(user_id, purpose, created_at);
+
+-- +goose Down
+DROP TABLE IF EXISTS user_consents;
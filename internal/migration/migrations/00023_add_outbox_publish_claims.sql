@@ -0,0 +1,6 @@
+-- +goose Up
+-- The publishing instance claims a batch for a limited time and publishes it outside any transaction
+ALTER TABLE outbox_events ADD COLUMN IF NOT EXISTS publish_claimed_until TIMESTAMP WITH TIME ZONE;
+
+-- +goose Down
+ALTER TABLE outbox_events DROP COLUMN IF EXISTS publish_claimed_until;
@@ -0,0 +1,34 @@
+-- +goose Up
+-- Claims on an address are matched on its canonical form, like the users table, so two spellings of one
+-- mailbox cannot be claimed at the same time. Existing rows get a provisional lowercase value; they are
+-- short-lived and settle once open claims expire.
+ALTER TABLE email_changes ADD COLUMN IF NOT EXISTS canonical_new_email VARCHAR(255);
+ALTER TABLE email_changes ADD COLUMN IF NOT EXISTS canonical_old_email VARCHAR(255);
+UPDATE email_changes SET canonical_new_email = LOWER(new_email) WHERE canonical_new_email IS NULL;
+UPDATE email_changes SET canonical_old_email = LOWER(old_email) WHERE canonical_old_email IS NULL;
+ALTER TABLE email_changes ALTER COLUMN canonical_new_email SET NOT NULL;
+ALTER TABLE email_changes ALTER COLUMN canonical_old_email SET NOT NULL;
+
+-- Keep the oldest open claim when lowercasing makes two of them collide
+UPDATE email_changes SET cancelled_at = CURRENT_TIMESTAMP
+WHERE confirmed_at IS NULL AND cancelled_at IS NULL AND id IN (
+    SELECT id FROM (
+        SELECT id, ROW_NUMBER() OVER (PARTITION BY canonical_new_email ORDER BY created_at, id) AS position
+        FROM email_changes
+        WHERE confirmed_at IS NULL AND cancelled_at IS NULL
+    ) ranked
+    WHERE position > 1
+);
+
+DROP INDEX IF EXISTS idx_email_changes_pending_new_email;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_email_changes_pending_canonical_new_email
+    ON email_changes(canonical_new_email) WHERE confirmed_at IS NULL AND cancelled_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_email_changes_canonical_old_email ON email_changes(canonical_old_email);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_email_changes_canonical_old_email;
+DROP INDEX IF EXISTS idx_email_changes_pending_canonical_new_email;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_email_changes_pending_new_email
+    ON email_changes(new_email) WHERE confirmed_at IS NULL AND cancelled_at IS NULL;
+ALTER TABLE email_changes DROP COLUMN IF EXISTS canonical_old_email;
+ALTER TABLE email_changes DROP COLUMN IF EXISTS canonical_new_email;
@@ -0,0 +1,50 @@
+package models
+
+import "time"
+
+// Who may read a custom attribute
+const (
+	AttributeReadPublic = "public" // any authenticated user
+	AttributeReadSelf   = "self"   // the user and admins
+	AttributeReadAdmin  = "admin"  // admins only
+)
+
+// Who may write a custom attribute
+const (
+	AttributeWriteSelf  = "self"  // the user and admins
+	AttributeWriteAdmin = "admin" // admins only
+)
+
+// AttributeDefinition registers a custom user attribute and the JSON Schema its values must match
+type AttributeDefinition struct {
+	Key             string    `json:"key" gorm:"primaryKey;size:64"`
+	Description     string    `json:"description" gorm:"size:255;not null;default:''"`
+	Schema          JSONMap   `json:"schema" gorm:"type:jsonb;not null"`
+	ReadPermission  string    `json:"read_permission" gorm:"size:20;not null;default:self"`
+	WritePermission string    `json:"write_permission" gorm:"size:20;not null;default:admin"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// TableName specifies the table name for GORM
+func (AttributeDefinition) TableName() string {
+	return "attribute_definitions"
+}
+
+// CanRead reports whether a viewer may read the attribute
+func (d *AttributeDefinition) CanRead(isSelf, isAdmin bool) bool {
+	switch {
+	case isAdmin:
+		return true
+	case d.ReadPermission == AttributeReadPublic:
+		return true
+	case d.ReadPermission == AttributeReadSelf:
+		return isSelf
+	}
+	return false
+}
+
+// CanWrite reports whether an actor may change the attribute
+func (d *AttributeDefinition) CanWrite(isSelf, isAdmin bool) bool {
+	return isAdmin || (d.WritePermission == AttributeWriteSelf && isSelf)
+}
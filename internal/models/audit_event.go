@@ -0,0 +1,102 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Audit actions
+const (
+	AuditSignIn                     = "auth.signin"
+	AuditSignUp                     = "user.signup"
+	AuditUserUpdated                = "user.updated"
+	AuditPasswordChanged            = "user.password_changed"
+	AuditEmailChangeRequested       = "user.email_change_requested"
+	AuditConsentChanged             = "user.consent_changed"
+	AuditUserDeleted                = "user.deleted"
+	AuditUserRestored               = "user.restored"
+	AuditSessionRevoked             = "session.revoked"
+	AuditSessionsRevokedAll         = "session.revoked_all"
+	AuditImpersonation              = "admin.impersonation_started"
+	AuditStatusChanged              = "admin.status_changed"
+	AuditAdminRestoredUser          = "admin.user_restored"
+	AuditAttributeDefinitionSaved   = "admin.attribute_definition_saved"
+	AuditAttributeDefinitionDeleted = "admin.attribute_definition_deleted"
+	AuditAttributesUpdated          = "admin.attributes_updated"
+	AuditWebhookCreated             = "admin.webhook_created"
+	AuditWebhookUpdated             = "admin.webhook_updated"
+	AuditWebhookDeleted             = "admin.webhook_deleted"
+	AuditWebhookSecretRotated       = "admin.webhook_secret_rotated"
+	AuditWebhookReplayed            = "admin.webhook_replayed"
+)
+
+// Audit outcomes
+const (
+	AuditSuccess = "success"
+	AuditFailure = "failure"
+)
+
+// AuditEvent is an append-only record of a security-relevant action
+type AuditEvent struct {
+	ID             int64      `json:"id" gorm:"primaryKey"`
+	Action         string     `json:"action" gorm:"size:64;not null"`
+	Outcome        string     `json:"outcome" gorm:"size:16;not null"`
+	ActorID        *uuid.UUID `json:"actor_id" gorm:"type:uuid"`
+	ImpersonatorID *uuid.UUID `json:"impersonator_id,omitempty" gorm:"type:uuid"`
+	TargetID       *uuid.UUID `json:"target_id" gorm:"type:uuid"`
+	IP             string     `json:"ip" gorm:"size:45;not null;default:''"`
+	UserAgent      string     `json:"user_agent" gorm:"size:512;not null;default:''"`
+	RequestID      string     `json:"request_id" gorm:"size:128;not null;default:''"`
+	Details        JSONMap    `json:"details" gorm:"type:jsonb;not null;default:'{}'"`
+	PrevHash       string     `json:"prev_hash,omitempty" gorm:"size:64;not null;default:''"`
+	Hash           string     `json:"hash,omitempty" gorm:"size:64;not null;default:''"`
+	CreatedAt      time.Time  `json:"created_at" gorm:"not null"`
+}
+
+// TableName specifies the table name for GORM
+func (AuditEvent) TableName() string {
+	return "audit_events"
+}
+
+// ComputeHash returns the SHA-256 chain hash of the event, linking it to the previous entry.
+// It covers every field except the database ID and the hash itself.
+func (e *AuditEvent) ComputeHash(prevHash string) string {
+	details := e.Details
+	if details == nil {
+		// Stored as {} and read back as an empty map
+		details = JSONMap{}
+	}
+
+	payload, _ := json.Marshal(struct {
+		PrevHash       string     `json:"prev_hash"`
+		Action         string     `json:"action"`
+		Outcome        string     `json:"outcome"`
+		ActorID        *uuid.UUID `json:"actor_id"`
+		ImpersonatorID *uuid.UUID `json:"impersonator_id"`
+		TargetID       *uuid.UUID `json:"target_id"`
+		IP             string     `json:"ip"`
+		UserAgent      string     `json:"user_agent"`
+		RequestID      string     `json:"request_id"`
+		Details        JSONMap    `json:"details"`
+		CreatedAt      string     `json:"created_at"`
+	}{
+		PrevHash:       prevHash,
+		Action:         e.Action,
+		Outcome:        e.Outcome,
+		ActorID:        e.ActorID,
+		ImpersonatorID: e.ImpersonatorID,
+		TargetID:       e.TargetID,
+		IP:             e.IP,
+		UserAgent:      e.UserAgent,
+		RequestID:      e.RequestID,
+		Details:        details,
+		CreatedAt:      e.CreatedAt.UTC().Format(time.RFC3339Nano),
+	})
+
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Consent is one decision by a user to give or withdraw consent for a purpose. Rows are never updated,
+// the latest decision per purpose is the current state.
+type Consent struct {
+	ID        uuid.UUID `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	UserID    uuid.UUID `json:"user_id" gorm:"type:uuid;not null"`
+	Purpose   string    `json:"purpose" gorm:"size:64;not null"`
+	Version   string    `json:"version" gorm:"size:32;not null"`
+	Granted   bool      `json:"granted" gorm:"not null"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName specifies the table name for GORM
+func (Consent) TableName() string {
+	return "user_consents"
+}
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	ExportStatusPending    = "pending"
+	ExportStatusProcessing = "processing"
+	ExportStatusCompleted  = "completed"
+	ExportStatusFailed     = "failed"
+	ExportStatusExpired    = "expired"
+)
+
+// DataExport is an asynchronously generated archive of everything stored about a user
+type DataExport struct {
+	ID          uuid.UUID  `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	UserID      uuid.UUID  `json:"user_id" gorm:"type:uuid;not null;index"`
+	RequestedBy uuid.UUID  `json:"requested_by" gorm:"type:uuid;not null"`
+	Status      string     `json:"status" gorm:"size:20;not null;default:pending"`
+	Error       *string    `json:"error,omitempty"`
+	Archive     []byte     `json:"-"`
+	ClaimedAt   *time.Time `json:"-"`
+	Attempts    int        `json:"-" gorm:"not null;default:0"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+}
+
+// TableName specifies the table name for GORM
+func (DataExport) TableName() string {
+	return "data_exports"
+}
+
+// Downloadable reports whether the archive can still be downloaded
+func (e *DataExport) Downloadable(now time.Time) bool {
+	return e.Status == ExportStatusCompleted && e.ExpiresAt != nil && now.Before(*e.ExpiresAt)
+}
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// EmailChange is a requested change of a user's email. It takes effect once confirmed from the
+// new address, and can be cancelled, or reverted after confirmation, from the old address.
+type EmailChange struct {
+	ID       uuid.UUID `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	UserID   uuid.UUID `json:"user_id" gorm:"type:uuid;not null;index"`
+	OldEmail string    `json:"old_email" gorm:"size:255;not null"`
+	NewEmail string    `json:"new_email" gorm:"size:255;not null"`
+	// CanonicalOldEmail and CanonicalNewEmail are the canonical forms the claims are matched on
+	CanonicalOldEmail string     `json:"-" gorm:"size:255;not null"`
+	CanonicalNewEmail string     `json:"-" gorm:"size:255;not null"`
+	ConfirmTokenHash  string     `json:"-" gorm:"size:64;uniqueIndex;not null"`
+	CancelTokenHash   string     `json:"-" gorm:"size:64;uniqueIndex;not null"`
+	ExpiresAt         time.Time  `json:"expires_at" gorm:"not null"`
+	RevertUntil       time.Time  `json:"revert_until" gorm:"not null"`
+	ConfirmedAt       *time.Time `json:"confirmed_at"`
+	CancelledAt       *time.Time `json:"cancelled_at"`
+	CreatedAt         time.Time  `json:"created_at"`
+}
+
+// TableName specifies the table name for GORM
+func (EmailChange) TableName() string {
+	return "email_changes"
+}
+
+// Pending reports whether the change still awaits confirmation
+func (c *EmailChange) Pending(now time.Time) bool {
+	return c.ConfirmedAt == nil && c.CancelledAt == nil && now.Before(c.ExpiresAt)
+}
+
+// Revertible reports whether a confirmed change can still be undone from the old address
+func (c *EmailChange) Revertible(now time.Time) bool {
+	return c.ConfirmedAt != nil && c.CancelledAt == nil && now.Before(c.RevertUntil)
+}
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MagicLinkToken is a single-use sign-in link. Only the SHA-256 of the token is stored.
+type MagicLinkToken struct {
+	ID          uuid.UUID  `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	UserID      uuid.UUID  `json:"user_id" gorm:"type:uuid;not null;index"`
+	TokenHash   string     `json:"-" gorm:"size:64;uniqueIndex;not null"`
+	BrowserHash *string    `json:"-" gorm:"size:64"`
+	ExpiresAt   time.Time  `json:"expires_at" gorm:"not null"`
+	UsedAt      *time.Time `json:"used_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// TableName specifies the table name for GORM
+func (MagicLinkToken) TableName() string {
+	return "magic_link_tokens"
+}
@@ -0,0 +1,88 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// User lifecycle event types
+const (
+	EventUserCreated  = "user.created"
+	EventUserUpdated  = "user.updated"
+	EventUserDeleted  = "user.deleted"
+	EventUserRestored = "user.restored"
+	// EventUserPurged tells subscribers to erase their copies; earlier events about the user are reduced to its ID
+	EventUserPurged = "user.purged"
+)
+
+// EventTypes lists every event type that can be subscribed to
+var EventTypes = []string{EventUserCreated, EventUserUpdated, EventUserDeleted, EventUserRestored, EventUserPurged}
+
+// OutboxEvent is a domain event recorded in the same transaction as the change it describes
+type OutboxEvent struct {
+	ID           int64      `json:"-" gorm:"primaryKey"`
+	EventID      uuid.UUID  `json:"id" gorm:"type:uuid;not null;default:gen_random_uuid()"`
+	Type         string     `json:"type" gorm:"size:64;not null"`
+	AggregateID  uuid.UUID  `json:"aggregate_id" gorm:"type:uuid;not null"`
+	Payload      JSONMap    `json:"data" gorm:"type:jsonb;not null;default:'{}'"`
+	CreatedAt    time.Time  `json:"created_at"`
+	DispatchedAt *time.Time `json:"-"`
+	PublishedAt  *time.Time `json:"-"`
+	// PublishClaimedUntil is set while an instance publishes the event
+	PublishClaimedUntil *time.Time `json:"-"`
+}
+
+// TableName specifies the table name for GORM
+func (OutboxEvent) TableName() string {
+	return "outbox_events"
+}
+
+// UserEventData is the user as published to subscribers. It leaves out the role, status reason, phone number
+// and every custom attribute that is not public.
+type UserEventData struct {
+	ID            uuid.UUID              `json:"id"`
+	Email         string                 `json:"email"`
+	Username      string                 `json:"username,omitempty"`
+	AuthProvider  string                 `json:"auth_provider"`
+	Status        string                 `json:"status"`
+	DisplayName   string                 `json:"display_name"`
+	GivenName     string                 `json:"given_name"`
+	FamilyName    string                 `json:"family_name"`
+	AvatarURL     string                 `json:"avatar_url"`
+	Locale        string                 `json:"locale"`
+	Timezone      string                 `json:"timezone"`
+	PhoneVerified bool                   `json:"phone_verified"`
+	Attributes    map[string]interface{} `json:"attributes"`
+	CreatedAt     time.Time              `json:"created_at"`
+	UpdatedAt     time.Time              `json:"updated_at"`
+}
+
+// NewUserEventData builds the event data of a user; definitions decide which attributes are public
+func NewUserEventData(user *User, definitions []AttributeDefinition) UserEventData {
+	data := UserEventData{
+		ID:            user.ID,
+		Email:         user.Email,
+		AuthProvider:  user.AuthProvider,
+		Status:        user.EffectiveStatus(time.Now()),
+		DisplayName:   user.DisplayName,
+		GivenName:     user.GivenName,
+		FamilyName:    user.FamilyName,
+		AvatarURL:     user.AvatarURL,
+		Locale:        user.Locale,
+		Timezone:      user.Timezone,
+		PhoneVerified: user.PhoneVerifiedAt != nil,
+		Attributes:    make(map[string]interface{}),
+		CreatedAt:     user.CreatedAt,
+		UpdatedAt:     user.UpdatedAt,
+	}
+	if user.Username != nil {
+		data.Username = *user.Username
+	}
+	for i := range definitions {
+		if value, ok := user.Attributes[definitions[i].Key]; ok && definitions[i].CanRead(false, false) {
+			data.Attributes[definitions[i].Key] = value
+		}
+	}
+	return data
+}
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestNewUserEventDataLeavesOutPrivateFields(t *testing.T) {
+	reason := "fraud review"
+	user := &User{
+		ID:           uuid.New(),
+		Email:        "ada@example.com",
+		Role:         RoleAdmin,
+		Status:       StatusActive,
+		StatusReason: &reason,
+		PhoneNumber:  "+15550100",
+		Attributes:   JSONMap{"team": "core", "employee_id": "E42", "cost_center": "CC7"},
+	}
+	definitions := []AttributeDefinition{
+		{Key: "team", ReadPermission: AttributeReadPublic},
+		{Key: "employee_id", ReadPermission: AttributeReadSelf},
+		{Key: "cost_center", ReadPermission: AttributeReadAdmin},
+	}
+
+	data, err := json.Marshal(NewUserEventData(user, definitions))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var payload map[string]interface{}
+	if err := json.Unmarshal(data, &payload); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, field := range []string{"role", "status_reason", "phone_number", "avatar_key", "password"} {
+		if _, ok := payload[field]; ok {
+			t.Errorf("payload has %q", field)
+		}
+	}
+	attributes := payload["attributes"].(map[string]interface{})
+	if len(attributes) != 1 || attributes["team"] != "core" {
+		t.Errorf("attributes = %v, want only the public one", attributes)
+	}
+	if payload["email"] != "ada@example.com" || payload["status"] != StatusActive {
+		t.Errorf("payload = %v", payload)
+	}
+}
@@ -7,16 +7,67 @@ import (
 	"gorm.io/gorm"
 )
 
+// Roles a user can hold
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+// Authentication providers a user can be managed by
+const (
+	AuthProviderLocal = "local"
+	AuthProviderLDAP  = "ldap"
+	AuthProviderSAML  = "saml"
+)
+
+// UnusablePassword is stored for users whose credentials live in an external
+// directory. It is never a valid bcrypt hash, so local sign-in always fails.
+const UnusablePassword = "!"
+
 type User struct {
-	ID        uuid.UUID      `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
-	Email     string         `json:"email" gorm:"size:255;uniqueIndex;not null"`
-	Password  string         `json:"-" gorm:"size:255;not null"`
-	CreatedAt time.Time      `json:"created_at"`
-	UpdatedAt time.Time      `json:"updated_at"`
-	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+	ID              uuid.UUID      `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	Email           string         `json:"email" gorm:"size:255;index;not null"`
+	CanonicalEmail  string         `json:"-" gorm:"size:255;uniqueIndex;not null"`
+	Username        *string        `json:"username" gorm:"size:32"`
+	Password        string         `json:"-" gorm:"size:255;not null"`
+	Role            string         `json:"role" gorm:"size:50;not null;default:user"`
+	AuthProvider    string         `json:"auth_provider" gorm:"size:50;not null;default:local"`
+	Status          string         `json:"status" gorm:"size:20;not null;default:active;index"`
+	StatusReason    *string        `json:"status_reason"`
+	SuspendedUntil  *time.Time     `json:"suspended_until"`
+	StatusChangedAt *time.Time     `json:"status_changed_at"`
+	DisplayName     string         `json:"display_name" gorm:"size:100;not null;default:''"`
+	GivenName       string         `json:"given_name" gorm:"size:100;not null;default:''"`
+	FamilyName      string         `json:"family_name" gorm:"size:100;not null;default:''"`
+	AvatarURL       string         `json:"avatar_url" gorm:"size:2048;not null;default:''"`
+	AvatarKey       string         `json:"-" gorm:"size:255;not null;default:''"`
+	AvatarVariants  JSONMap        `json:"avatar_variants" gorm:"type:jsonb;not null;default:'{}'"`
+	Locale          string         `json:"locale" gorm:"size:35;not null;default:''"`
+	Timezone        string         `json:"timezone" gorm:"size:64;not null;default:''"`
+	PhoneNumber     string         `json:"phone_number" gorm:"size:16;not null;default:''"`
+	PhoneVerifiedAt *time.Time     `json:"phone_verified_at"`
+	Attributes      JSONMap        `json:"attributes" gorm:"type:jsonb;not null;default:'{}'"`
+	CreatedAt       time.Time      `json:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at"`
+	DeletedAt       gorm.DeletedAt `json:"-" gorm:"index"`
+	PurgedAt        *time.Time     `json:"-"`
+	// PendingEmail is the unconfirmed new address, filled in by the service layer
+	PendingEmail string `json:"-" gorm:"-"`
 }
 
 // TableName specifies the table name for GORM
 func (User) TableName() string {
 	return "users"
 }
+
+// RoleRank orders roles by privilege so the strongest of several mapped roles wins
+func RoleRank(role string) int {
+	switch role {
+	case RoleAdmin:
+		return 2
+	case RoleUser:
+		return 1
+	default:
+		return 0
+	}
+}
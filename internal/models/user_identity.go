@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UserIdentity links a local user to their account at an external authentication backend
+type UserIdentity struct {
+	ID         uuid.UUID `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	UserID     uuid.UUID `json:"user_id" gorm:"type:uuid;not null;index"`
+	Provider   string    `json:"provider" gorm:"size:50;not null"`
+	Issuer     string    `json:"issuer" gorm:"size:512;not null;default:''"`
+	Subject    string    `json:"subject" gorm:"size:512;not null"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at" gorm:"not null"`
+}
+
+// TableName specifies the table name for GORM
+func (UserIdentity) TableName() string {
+	return "user_identities"
+}
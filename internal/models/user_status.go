@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+// Account statuses
+const (
+	StatusPending   = "pending"
+	StatusActive    = "active"
+	StatusSuspended = "suspended"
+	StatusDisabled  = "disabled"
+)
+
+// statusTransitions lists the statuses each status may move to
+var statusTransitions = map[string][]string{
+	StatusPending:   {StatusActive, StatusDisabled},
+	StatusActive:    {StatusSuspended, StatusDisabled},
+	StatusSuspended: {StatusActive, StatusSuspended, StatusDisabled},
+	StatusDisabled:  {StatusActive},
+}
+
+// CanTransition reports whether an account may move from one status to another
+func CanTransition(from, to string) bool {
+	for _, allowed := range statusTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// EffectiveStatus returns the status at the given time. A suspension with an end date lapses on its own.
+func (u *User) EffectiveStatus(now time.Time) string {
+	if u.Status == "" {
+		return StatusActive
+	}
+	if u.Status == StatusSuspended && u.SuspendedUntil != nil && now.After(*u.SuspendedUntil) {
+		return StatusActive
+	}
+	return u.Status
+}
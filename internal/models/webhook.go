@@ -0,0 +1,60 @@
+package models
+
+import (
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	DeliveryStatusPending   = "pending"
+	DeliveryStatusDelivered = "delivered"
+	DeliveryStatusDead      = "dead"
+)
+
+// WebhookSubscription is an endpoint that receives user lifecycle events
+type WebhookSubscription struct {
+	ID  uuid.UUID `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	URL string    `json:"url" gorm:"size:2048;not null"`
+	// Secret keys the HMAC signature of every delivery; it is only shown when created or rotated
+	Secret string `json:"-" gorm:"size:128;not null"`
+	// EventTypes limits the subscription to these events; empty means all of them
+	EventTypes  StringList `json:"event_types" gorm:"type:jsonb;not null;default:'[]'"`
+	Description string     `json:"description" gorm:"size:255;not null;default:''"`
+	Active      bool       `json:"active" gorm:"not null;default:true"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// TableName specifies the table name for GORM
+func (WebhookSubscription) TableName() string {
+	return "webhook_subscriptions"
+}
+
+// Subscribes reports whether events of the type are delivered to the subscription
+func (s *WebhookSubscription) Subscribes(eventType string) bool {
+	return s.Active && (len(s.EventTypes) == 0 || slices.Contains(s.EventTypes, eventType))
+}
+
+// WebhookDelivery tracks the delivery of one event to one subscription
+type WebhookDelivery struct {
+	ID             uuid.UUID            `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	SubscriptionID uuid.UUID            `json:"subscription_id" gorm:"type:uuid;not null"`
+	EventID        int64                `json:"-" gorm:"not null"`
+	Status         string               `json:"status" gorm:"size:16;not null;default:pending"`
+	Attempts       int                  `json:"attempts" gorm:"not null;default:0"`
+	NextAttemptAt  time.Time            `json:"next_attempt_at"`
+	LastStatusCode *int                 `json:"last_status_code"`
+	LastError      *string              `json:"last_error"`
+	DeliveredAt    *time.Time           `json:"delivered_at"`
+	CreatedAt      time.Time            `json:"created_at"`
+	UpdatedAt      time.Time            `json:"updated_at"`
+	Subscription   *WebhookSubscription `json:"-" gorm:"foreignKey:SubscriptionID"`
+	Event          *OutboxEvent         `json:"event,omitempty" gorm:"foreignKey:EventID"`
+}
+
+// TableName specifies the table name for GORM
+func (WebhookDelivery) TableName() string {
+	return "webhook_deliveries"
+}
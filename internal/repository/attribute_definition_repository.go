@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"user-management/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type AttributeDefinitionRepository interface {
+	List(ctx context.Context) ([]models.AttributeDefinition, error)
+	FindByKey(ctx context.Context, key string) (*models.AttributeDefinition, error)
+	Upsert(ctx context.Context, definition *models.AttributeDefinition) error
+	Delete(ctx context.Context, key string) (bool, error)
+}
+
+type attributeDefinitionRepository struct {
+	db *gorm.DB
+}
+
+func NewAttributeDefinitionRepository(db *gorm.DB) AttributeDefinitionRepository {
+	return &attributeDefinitionRepository{db: db}
+}
+
+func (r *attributeDefinitionRepository) List(ctx context.Context) ([]models.AttributeDefinition, error) {
+	var definitions []models.AttributeDefinition
+	err := r.db.WithContext(ctx).Order("key").Find(&definitions).Error
+	return definitions, err
+}
+
+func (r *attributeDefinitionRepository) FindByKey(ctx context.Context, key string) (*models.AttributeDefinition, error) {
+	var definition models.AttributeDefinition
+	err := r.db.WithContext(ctx).First(&definition, "key = ?", key).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &definition, err
+}
+
+// Upsert creates the definition or replaces the existing one with the same key
+func (r *attributeDefinitionRepository) Upsert(ctx context.Context, definition *models.AttributeDefinition) error {
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "key"}},
+		DoUpdates: clause.AssignmentColumns([]string{"description", "schema", "read_permission", "write_permission", "updated_at"}),
+	}).Create(definition).Error
+}
+
+// Delete removes the definition together with every stored value of the attribute
+func (r *attributeDefinitionRepository) Delete(ctx context.Context, key string) (bool, error) {
+	var deleted bool
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Delete(&models.AttributeDefinition{}, "key = ?", key)
+		if result.Error != nil {
+			return result.Error
+		}
+		deleted = result.RowsAffected == 1
+
+		return tx.Unscoped().Model(&models.User{}).
+			Where("jsonb_exists(attributes, ?)", key).
+			Update("attributes", gorm.Expr("attributes - ?::text", key)).Error
+	})
+	return deleted, err
+}
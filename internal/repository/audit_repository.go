@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"context"
+	"time"
+	"user-management/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// auditChainLock is the advisory lock key that serializes chained appends
+const auditChainLock = 0x61756469
+
+type AuditRepository interface {
+	// Append stores the event. With chain set, it is linked to the latest entry through its hash.
+	Append(ctx context.Context, event *models.AuditEvent, chain bool) error
+	// List returns events newest first, starting below beforeID when it is set
+	List(ctx context.Context, filter AuditFilter, beforeID int64, limit int) ([]models.AuditEvent, error)
+	// ListAfter returns events oldest first, starting above afterID
+	ListAfter(ctx context.Context, afterID int64, limit int) ([]models.AuditEvent, error)
+	// ListByUser returns every event the user performed or was the target of
+	ListByUser(ctx context.Context, userID uuid.UUID) ([]models.AuditEvent, error)
+}
+
+// AuditFilter narrows List results. Zero values match everything.
+type AuditFilter struct {
+	ActorID   uuid.UUID
+	TargetID  uuid.UUID
+	Action    string
+	Outcome   string
+	IP        string
+	RequestID string
+	From      time.Time
+	To        time.Time
+}
+
+type auditRepository struct {
+	db *gorm.DB
+}
+
+func NewAuditRepository(db *gorm.DB) AuditRepository {
+	return &auditRepository{db: db}
+}
+
+func (r *auditRepository) Append(ctx context.Context, event *models.AuditEvent, chain bool) error {
+	if !chain {
+		return r.db.WithContext(ctx).Create(event).Error
+	}
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Readers of the latest hash must take turns, or two entries would link to the same predecessor
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", auditChainLock).Error; err != nil {
+			return err
+		}
+
+		var prevHash string
+		err := tx.Model(&models.AuditEvent{}).
+			Select("hash").
+			Order("id DESC").
+			Limit(1).
+			Scan(&prevHash).Error
+		if err != nil {
+			return err
+		}
+
+		event.PrevHash = prevHash
+		event.Hash = event.ComputeHash(prevHash)
+		return tx.Create(event).Error
+	})
+}
+
+func (r *auditRepository) List(ctx context.Context, filter AuditFilter, beforeID int64, limit int) ([]models.AuditEvent, error) {
+	query := r.db.WithContext(ctx).Model(&models.AuditEvent{})
+
+	if filter.ActorID != uuid.Nil {
+		query = query.Where("actor_id = ?", filter.ActorID)
+	}
+	if filter.TargetID != uuid.Nil {
+		query = query.Where("target_id = ?", filter.TargetID)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.Outcome != "" {
+		query = query.Where("outcome = ?", filter.Outcome)
+	}
+	if filter.IP != "" {
+		query = query.Where("ip = ?", filter.IP)
+	}
+	if filter.RequestID != "" {
+		query = query.Where("request_id = ?", filter.RequestID)
+	}
+	if !filter.From.IsZero() {
+		query = query.Where("created_at >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		query = query.Where("created_at < ?", filter.To)
+	}
+	if beforeID > 0 {
+		query = query.Where("id < ?", beforeID)
+	}
+
+	var events []models.AuditEvent
+	err := query.Order("id DESC").Limit(limit).Find(&events).Error
+	return events, err
+}
+
+func (r *auditRepository) ListAfter(ctx context.Context, afterID int64, limit int) ([]models.AuditEvent, error) {
+	var events []models.AuditEvent
+	err := r.db.WithContext(ctx).
+		Where("id > ?", afterID).
+		Order("id ASC").
+		Limit(limit).
+		Find(&events).Error
+	return events, err
+}
+
+func (r *auditRepository) ListByUser(ctx context.Context, userID uuid.UUID) ([]models.AuditEvent, error) {
+	var events []models.AuditEvent
+	err := r.db.WithContext(ctx).
+		Where("actor_id = ? OR target_id = ?", userID, userID).
+		Order("id ASC").
+		Find(&events).Error
+	return events, err
+}
@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"context"
+	"user-management/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type ConsentRepository interface {
+	Create(ctx context.Context, consent *models.Consent) error
+	// ListCurrent returns the latest decision for each purpose
+	ListCurrent(ctx context.Context, userID uuid.UUID) ([]models.Consent, error)
+	// ListHistory returns every decision, oldest first
+	ListHistory(ctx context.Context, userID uuid.UUID) ([]models.Consent, error)
+}
+
+type consentRepository struct {
+	db *gorm.DB
+}
+
+func NewConsentRepository(db *gorm.DB) ConsentRepository {
+	return &consentRepository{db: db}
+}
+
+func (r *consentRepository) Create(ctx context.Context, consent *models.Consent) error {
+	return r.db.WithContext(ctx).Create(consent).Error
+}
+
+func (r *consentRepository) ListCurrent(ctx context.Context, userID uuid.UUID) ([]models.Consent, error) {
+	var consents []models.Consent
+	err := r.db.WithContext(ctx).
+		Raw(`SELECT DISTINCT ON (purpose) * FROM user_consents
+			WHERE user_id = ? ORDER BY purpose, created_at DESC`, userID).
+		Scan(&consents).Error
+	return consents, err
+}
+
+func (r *consentRepository) ListHistory(ctx context.Context, userID uuid.UUID) ([]models.Consent, error) {
+	var consents []models.Consent
+	err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("created_at").
+		Find(&consents).Error
+	return consents, err
+}
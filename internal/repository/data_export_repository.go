@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+	"user-management/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type DataExportRepository interface {
+	Create(ctx context.Context, export *models.DataExport) error
+	FindByID(ctx context.Context, id uuid.UUID) (*models.DataExport, error)
+	FindArchive(ctx context.Context, id uuid.UUID) ([]byte, error)
+	FindInProgress(ctx context.Context, userID uuid.UUID) (*models.DataExport, error)
+	ClaimPending(ctx context.Context, staleBefore time.Time) (*models.DataExport, error)
+	Complete(ctx context.Context, id uuid.UUID, archive []byte, expiresAt time.Time) error
+	Fail(ctx context.Context, id uuid.UUID, reason string) error
+	ExpireArchives(ctx context.Context, now time.Time) (int64, error)
+}
+
+type dataExportRepository struct {
+	db *gorm.DB
+}
+
+func NewDataExportRepository(db *gorm.DB) DataExportRepository {
+	return &dataExportRepository{db: db}
+}
+
+func (r *dataExportRepository) Create(ctx context.Context, export *models.DataExport) error {
+	return r.db.WithContext(ctx).Create(export).Error
+}
+
+// FindByID returns the export without its archive
+func (r *dataExportRepository) FindByID(ctx context.Context, id uuid.UUID) (*models.DataExport, error) {
+	var export models.DataExport
+	err := r.db.WithContext(ctx).Omit("archive").First(&export, "id = ?", id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &export, err
+}
+
+func (r *dataExportRepository) FindArchive(ctx context.Context, id uuid.UUID) ([]byte, error) {
+	var archive []byte
+	err := r.db.WithContext(ctx).Model(&models.DataExport{}).
+		Where("id = ? AND status = ?", id, models.ExportStatusCompleted).
+		Pluck("archive", &archive).Error
+	return archive, err
+}
+
+// FindInProgress returns the user's pending or processing export, if any
+func (r *dataExportRepository) FindInProgress(ctx context.Context, userID uuid.UUID) (*models.DataExport, error) {
+	var export models.DataExport
+	err := r.db.WithContext(ctx).Omit("archive").
+		Where("user_id = ? AND status IN ?", userID, []string{models.ExportStatusPending, models.ExportStatusProcessing}).
+		Order("created_at").
+		First(&export).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &export, err
+}
+
+// ClaimPending marks the oldest pending export as processing and returns it. Exports whose claim is older
+// than staleBefore were abandoned by a crashed worker and are claimed again.
+// SKIP LOCKED lets several instances work through the queue concurrently.
+func (r *dataExportRepository) ClaimPending(ctx context.Context, staleBefore time.Time) (*models.DataExport, error) {
+	var export models.DataExport
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Omit("archive").
+			Where("status = ? OR (status = ? AND claimed_at < ?)",
+				models.ExportStatusPending, models.ExportStatusProcessing, staleBefore).
+			Order("created_at").
+			First(&export).Error
+		if err != nil {
+			return err
+		}
+
+		now := time.Now().UTC()
+		export.Status = models.ExportStatusProcessing
+		export.ClaimedAt = &now
+		export.Attempts++
+		return tx.Model(&models.DataExport{}).
+			Where("id = ?", export.ID).
+			Updates(map[string]interface{}{
+				"status":     models.ExportStatusProcessing,
+				"claimed_at": now,
+				"attempts":   export.Attempts,
+			}).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &export, err
+}
+
+func (r *dataExportRepository) Complete(ctx context.Context, id uuid.UUID, archive []byte, expiresAt time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.DataExport{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"status":       models.ExportStatusCompleted,
+			"archive":      archive,
+			"completed_at": time.Now().UTC(),
+			"expires_at":   expiresAt,
+		}).Error
+}
+
+func (r *dataExportRepository) Fail(ctx context.Context, id uuid.UUID, reason string) error {
+	return r.db.WithContext(ctx).Model(&models.DataExport{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"status": models.ExportStatusFailed,
+			"error":  reason,
+		}).Error
+}
+
+// ExpireArchives drops the archives of completed exports whose download link has expired
+func (r *dataExportRepository) ExpireArchives(ctx context.Context, now time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Model(&models.DataExport{}).
+		Where("status = ? AND expires_at <= ?", models.ExportStatusCompleted, now).
+		Updates(map[string]interface{}{
+			"status":  models.ExportStatusExpired,
+			"archive": nil,
+		})
+	return result.RowsAffected, result.Error
+}
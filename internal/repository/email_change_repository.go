@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+	"user-management/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type EmailChangeRepository interface {
+	Create(ctx context.Context, change *models.EmailChange) error
+	FindByConfirmTokenHash(ctx context.Context, tokenHash string) (*models.EmailChange, error)
+	FindByCancelTokenHash(ctx context.Context, tokenHash string) (*models.EmailChange, error)
+	FindPendingByUser(ctx context.Context, userID uuid.UUID) (*models.EmailChange, error)
+	Confirm(ctx context.Context, change *models.EmailChange, canonical string) (bool, error)
+	Cancel(ctx context.Context, id uuid.UUID) (bool, error)
+	Revert(ctx context.Context, change *models.EmailChange, canonical string) (bool, error)
+}
+
+type emailChangeRepository struct {
+	db *gorm.DB
+}
+
+func NewEmailChangeRepository(db *gorm.DB) EmailChangeRepository {
+	return &emailChangeRepository{db: db}
+}
+
+// Create supersedes the user's open change and expired claims on the same canonical address, then
+// inserts the new change. A concurrent claim on the address fails with gorm.ErrDuplicatedKey.
+func (r *emailChangeRepository) Create(ctx context.Context, change *models.EmailChange) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now().UTC()
+		err := tx.Model(&models.EmailChange{}).
+			Where("confirmed_at IS NULL AND cancelled_at IS NULL").
+			Where(tx.Where("user_id = ?", change.UserID).
+				Or("canonical_new_email = ? AND expires_at <= ?", change.CanonicalNewEmail, now)).
+			Update("cancelled_at", now).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Create(change).Error
+	})
+}
+
+func (r *emailChangeRepository) FindByConfirmTokenHash(ctx context.Context, tokenHash string) (*models.EmailChange, error) {
+	return r.findOne(ctx, "confirm_token_hash = ?", tokenHash)
+}
+
+func (r *emailChangeRepository) FindByCancelTokenHash(ctx context.Context, tokenHash string) (*models.EmailChange, error) {
+	return r.findOne(ctx, "cancel_token_hash = ?", tokenHash)
+}
+
+func (r *emailChangeRepository) FindPendingByUser(ctx context.Context, userID uuid.UUID) (*models.EmailChange, error) {
+	var change models.EmailChange
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND confirmed_at IS NULL AND cancelled_at IS NULL AND expires_at > ?", userID, time.Now().UTC()).
+		Order("created_at DESC").
+		First(&change).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &change, err
+}
+
+// Confirm applies the change and reports whether it was still pending. canonical is the canonical form of the new email.
+func (r *emailChangeRepository) Confirm(ctx context.Context, change *models.EmailChange, canonical string) (bool, error) {
+	var confirmed bool
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now().UTC()
+		result := tx.Model(&models.EmailChange{}).
+			Where("id = ? AND confirmed_at IS NULL AND cancelled_at IS NULL AND expires_at > ?", change.ID, now).
+			Update("confirmed_at", now)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+
+		result = tx.Model(&models.User{}).
+			Where("id = ? AND email = ?", change.UserID, change.OldEmail).
+			Updates(map[string]interface{}{"email": change.NewEmail, "canonical_email": canonical})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			// The email changed some other way in the meantime
+			return tx.Model(&models.EmailChange{}).Where("id = ?", change.ID).Update("cancelled_at", now).Error
+		}
+
+		confirmed = true
+		return appendUserEvent(tx, models.EventUserUpdated, change.UserID)
+	})
+	return confirmed, err
+}
+
+// Cancel withdraws a pending change and reports whether it was pending
+func (r *emailChangeRepository) Cancel(ctx context.Context, id uuid.UUID) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&models.EmailChange{}).
+		Where("id = ? AND confirmed_at IS NULL AND cancelled_at IS NULL", id).
+		Update("cancelled_at", time.Now().UTC())
+	return result.RowsAffected == 1, result.Error
+}
+
+// Revert restores the old email of a confirmed change and reports whether it was restored.
+// canonical is the canonical form of the old email.
+func (r *emailChangeRepository) Revert(ctx context.Context, change *models.EmailChange, canonical string) (bool, error) {
+	var reverted bool
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now().UTC()
+		result := tx.Model(&models.EmailChange{}).
+			Where("id = ? AND confirmed_at IS NOT NULL AND cancelled_at IS NULL AND revert_until > ?", change.ID, now).
+			Update("cancelled_at", now)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+
+		result = tx.Model(&models.User{}).
+			Where("id = ? AND email = ?", change.UserID, change.NewEmail).
+			Updates(map[string]interface{}{"email": change.OldEmail, "canonical_email": canonical})
+		if result.Error != nil {
+			return result.Error
+		}
+
+		reverted = result.RowsAffected == 1
+		if !reverted {
+			return nil
+		}
+		return appendUserEvent(tx, models.EventUserUpdated, change.UserID)
+	})
+	return reverted, err
+}
+
+func (r *emailChangeRepository) findOne(ctx context.Context, query string, args ...interface{}) (*models.EmailChange, error) {
+	var change models.EmailChange
+	err := r.db.WithContext(ctx).Where(query, args...).First(&change).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &change, err
+}
@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+	"user-management/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type MagicLinkRepository interface {
+	Create(ctx context.Context, token *models.MagicLinkToken) error
+	FindByTokenHash(ctx context.Context, tokenHash string) (*models.MagicLinkToken, error)
+	MarkUsed(ctx context.Context, id uuid.UUID) (bool, error)
+}
+
+type magicLinkRepository struct {
+	db *gorm.DB
+}
+
+func NewMagicLinkRepository(db *gorm.DB) MagicLinkRepository {
+	return &magicLinkRepository{db: db}
+}
+
+func (r *magicLinkRepository) Create(ctx context.Context, token *models.MagicLinkToken) error {
+	return r.db.WithContext(ctx).Create(token).Error
+}
+
+func (r *magicLinkRepository) FindByTokenHash(ctx context.Context, tokenHash string) (*models.MagicLinkToken, error) {
+	var token models.MagicLinkToken
+	err := r.db.WithContext(ctx).Where("token_hash = ?", tokenHash).First(&token).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &token, err
+}
+
+// MarkUsed consumes the token and reports whether this call was the one that consumed it
+func (r *magicLinkRepository) MarkUsed(ctx context.Context, id uuid.UUID) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&models.MagicLinkToken{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", time.Now().UTC())
+
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected == 1, nil
+}
@@ -0,0 +1,156 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+	"user-management/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// outboxPublishLock is the advisory lock key taken while claiming events to publish
+const outboxPublishLock = 0x6f757462
+
+type OutboxRepository interface {
+	// ClaimPending claims up to limit unpublished events, oldest first, for the lease duration. Events are
+	// published in order by one instance at a time, so nothing is claimed while another claim is live.
+	ClaimPending(ctx context.Context, limit int, lease time.Duration) ([]models.OutboxEvent, error)
+	// MarkPublished records the events as published and ends their claim
+	MarkPublished(ctx context.Context, ids []int64) error
+	// ReleaseClaims ends the claim of events that were not published, so they are retried on the next claim
+	ReleaseClaims(ctx context.Context, ids []int64) error
+	// Prune deletes up to limit events created before the cutoff that have been dispatched to webhooks, and
+	// published too when requirePublished is set, and have no pending deliveries. Their deliveries go with them.
+	Prune(ctx context.Context, createdBefore time.Time, requirePublished bool, limit int) (int64, error)
+}
+
+type outboxRepository struct {
+	db *gorm.DB
+}
+
+func NewOutboxRepository(db *gorm.DB) OutboxRepository {
+	return &outboxRepository{db: db}
+}
+
+func (r *outboxRepository) ClaimPending(ctx context.Context, limit int, lease time.Duration) ([]models.OutboxEvent, error) {
+	var events []models.OutboxEvent
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// The lock only covers the claim; it is released on commit, before anything is published
+		var locked bool
+		if err := tx.Raw("SELECT pg_try_advisory_xact_lock(?)", outboxPublishLock).Scan(&locked).Error; err != nil || !locked {
+			return err
+		}
+
+		now := time.Now().UTC()
+		var claimed int64
+		err := tx.Model(&models.OutboxEvent{}).
+			Where("published_at IS NULL AND publish_claimed_until > ?", now).
+			Count(&claimed).Error
+		if err != nil || claimed > 0 {
+			return err
+		}
+
+		err = tx.Where("published_at IS NULL").
+			Order("id").
+			Limit(limit).
+			Find(&events).Error
+		if err != nil || len(events) == 0 {
+			return err
+		}
+
+		ids := make([]int64, len(events))
+		for i := range events {
+			ids[i] = events[i].ID
+		}
+		return tx.Model(&models.OutboxEvent{}).
+			Where("id IN ?", ids).
+			Update("publish_claimed_until", now.Add(lease)).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+func (r *outboxRepository) MarkPublished(ctx context.Context, ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Model(&models.OutboxEvent{}).
+		Where("id IN ?", ids).
+		Updates(map[string]interface{}{"published_at": time.Now().UTC(), "publish_claimed_until": nil}).Error
+}
+
+func (r *outboxRepository) ReleaseClaims(ctx context.Context, ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Model(&models.OutboxEvent{}).
+		Where("id IN ?", ids).
+		Update("publish_claimed_until", nil).Error
+}
+
+func (r *outboxRepository) Prune(ctx context.Context, createdBefore time.Time, requirePublished bool, limit int) (int64, error) {
+	expired := r.db.Model(&models.OutboxEvent{}).
+		Select("id").
+		Where("created_at < ? AND dispatched_at IS NOT NULL", createdBefore).
+		Where("NOT EXISTS (SELECT 1 FROM webhook_deliveries d WHERE d.event_id = outbox_events.id AND d.status = ?)",
+			models.DeliveryStatusPending).
+		Order("id").
+		Limit(limit)
+	if requirePublished {
+		expired = expired.Where("published_at IS NOT NULL")
+	}
+
+	result := r.db.WithContext(ctx).Where("id IN (?)", expired).Delete(&models.OutboxEvent{})
+	return result.RowsAffected, result.Error
+}
+
+// appendUserEvent records a user lifecycle event in tx, so the event exists if and only if the change commits.
+// Created, updated and restored events carry the user as stored after the change, as models.UserEventData.
+func appendUserEvent(tx *gorm.DB, eventType string, userID uuid.UUID) error {
+	var payload models.JSONMap
+	switch eventType {
+	case models.EventUserDeleted:
+		payload = models.JSONMap{"id": userID, "deleted_at": time.Now().UTC()}
+	case models.EventUserPurged:
+		payload = models.JSONMap{"id": userID, "purged_at": time.Now().UTC()}
+	default:
+		var user models.User
+		if err := tx.Unscoped().First(&user, "id = ?", userID).Error; err != nil {
+			return err
+		}
+		var definitions []models.AttributeDefinition
+		if err := tx.Where("read_permission = ?", models.AttributeReadPublic).Find(&definitions).Error; err != nil {
+			return err
+		}
+
+		data, err := json.Marshal(models.NewUserEventData(&user, definitions))
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(data, &payload); err != nil {
+			return err
+		}
+	}
+
+	return tx.Create(&models.OutboxEvent{
+		Type:        eventType,
+		AggregateID: userID,
+		Payload:     payload,
+	}).Error
+}
+
+// purgeUserEvents reduces the earlier events about a user to its ID, including ones not yet delivered,
+// and records a user.purged event after them
+func purgeUserEvents(tx *gorm.DB, userID uuid.UUID) error {
+	err := tx.Model(&models.OutboxEvent{}).
+		Where("aggregate_id = ? AND type <> ?", userID, models.EventUserDeleted).
+		Update("payload", models.JSONMap{"id": userID}).Error
+	if err != nil {
+		return err
+	}
+	return appendUserEvent(tx, models.EventUserPurged, userID)
+}
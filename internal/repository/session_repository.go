@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+	"user-management/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type SessionRepository interface {
+	Create(ctx context.Context, session *models.Session) error
+	FindByID(ctx context.Context, id uuid.UUID) (*models.Session, error)
+	ListActiveByUser(ctx context.Context, userID uuid.UUID) ([]models.Session, error)
+	ListByUser(ctx context.Context, userID uuid.UUID) ([]models.Session, error)
+	Revoke(ctx context.Context, userID, id uuid.UUID) (bool, error)
+	RevokeAllByUser(ctx context.Context, userID uuid.UUID) ([]uuid.UUID, error)
+	Touch(ctx context.Context, id uuid.UUID, lastSeenAt time.Time) error
+}
+
+type sessionRepository struct {
+	db *gorm.DB
+}
+
+func NewSessionRepository(db *gorm.DB) SessionRepository {
+	return &sessionRepository{db: db}
+}
+
+func (r *sessionRepository) Create(ctx context.Context, session *models.Session) error {
+	return r.db.WithContext(ctx).Create(session).Error
+}
+
+func (r *sessionRepository) FindByID(ctx context.Context, id uuid.UUID) (*models.Session, error) {
+	var session models.Session
+	err := r.db.WithContext(ctx).First(&session, "id = ?", id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &session, err
+}
+
+func (r *sessionRepository) ListActiveByUser(ctx context.Context, userID uuid.UUID) ([]models.Session, error) {
+	var sessions []models.Session
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now().UTC()).
+		Order("last_seen_at DESC").
+		Find(&sessions).Error
+	return sessions, err
+}
+
+// ListByUser returns every session of the user, including revoked and expired ones
+func (r *sessionRepository) ListByUser(ctx context.Context, userID uuid.UUID) ([]models.Session, error) {
+	var sessions []models.Session
+	err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Find(&sessions).Error
+	return sessions, err
+}
+
+// Revoke revokes one of the user's sessions and reports whether it was active
+func (r *sessionRepository) Revoke(ctx context.Context, userID, id uuid.UUID) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&models.Session{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, userID).
+		Update("revoked_at", time.Now().UTC())
+
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected == 1, nil
+}
+
+// RevokeAllByUser revokes every active session of the user and returns their IDs
+func (r *sessionRepository) RevokeAllByUser(ctx context.Context, userID uuid.UUID) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	err := r.db.WithContext(ctx).Model(&models.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Pluck("id", &ids).Error
+	if err != nil || len(ids) == 0 {
+		return ids, err
+	}
+
+	err = r.db.WithContext(ctx).Model(&models.Session{}).
+		Where("id IN ? AND revoked_at IS NULL", ids).
+		Update("revoked_at", time.Now().UTC()).Error
+
+	return ids, err
+}
+
+func (r *sessionRepository) Touch(ctx context.Context, id uuid.UUID, lastSeenAt time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.Session{}).
+		Where("id = ?", id).
+		Update("last_seen_at", lastSeenAt).Error
+}
@@ -3,18 +3,46 @@ package repository
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strings"
+	"time"
 	"user-management/internal/models"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type UserRepository interface {
 	Create(ctx context.Context, user *models.User) error
 	FindByID(ctx context.Context, id uuid.UUID) (*models.User, error)
-	FindByEmail(ctx context.Context, email string) (*models.User, error)
+	FindByIdentifier(ctx context.Context, identifier string) (*models.User, error)
 	Update(ctx context.Context, id uuid.UUID, updates interface{}) error
-	List(ctx context.Context, lastID uuid.UUID, searchEmail string, limit int) ([]models.User, error)
+	List(ctx context.Context, lastID uuid.UUID, filter UserFilter, limit int) ([]models.User, error)
+	UpdateAttributes(ctx context.Context, id uuid.UUID, set map[string]interface{}, remove []string) error
+	MarkPhoneVerified(ctx context.Context, id uuid.UUID, phone string) (bool, error)
+	EmailTaken(ctx context.Context, email, canonical string, excludeID uuid.UUID) (bool, error)
+	UsernameTaken(ctx context.Context, username string, excludeID uuid.UUID) (bool, error)
+	SoftDelete(ctx context.Context, id uuid.UUID) (bool, error)
+	FindDeleted(ctx context.Context, id uuid.UUID, deletedAfter time.Time) (*models.User, error)
+	FindDeletedByCanonicalEmail(ctx context.Context, canonical string, deletedAfter time.Time) (*models.User, error)
+	Restore(ctx context.Context, id uuid.UUID) (bool, error)
+	ListPurgeable(ctx context.Context, deletedBefore time.Time, limit int) ([]models.User, error)
+	Anonymize(ctx context.Context, id uuid.UUID) error
+	HardDelete(ctx context.Context, id uuid.UUID) error
+	LinkIdentity(ctx context.Context, identity *models.UserIdentity) error
+	ListIdentities(ctx context.Context, userID uuid.UUID) ([]models.UserIdentity, error)
+	ListCanonicalEmails(ctx context.Context, lastID uuid.UUID, limit int) ([]models.User, error)
+	UpdateCanonicalEmails(ctx context.Context, canonical map[uuid.UUID]string) error
+	EnforceUniqueCanonicalEmail(ctx context.Context) error
+}
+
+// UserFilter narrows List results
+type UserFilter struct {
+	// Email matches a substring of the email, case-insensitively
+	Email string
+	// Attributes matches users whose custom attributes contain all of these values
+	Attributes map[string]interface{}
 }
 
 type userRepository struct {
@@ -26,7 +54,12 @@ func NewUserRepository(db *gorm.DB) UserRepository {
 }
 
 func (r *userRepository) Create(ctx context.Context, user *models.User) error {
-	return r.db.WithContext(ctx).Create(user).Error
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+		return appendUserEvent(tx, models.EventUserCreated, user.ID)
+	})
 }
 
 func (r *userRepository) FindByID(ctx context.Context, id uuid.UUID) (*models.User, error) {
@@ -38,37 +71,69 @@ func (r *userRepository) FindByID(ctx context.Context, id uuid.UUID) (*models.Us
 	return &user, err
 }
 
-func (r *userRepository) FindByEmail(ctx context.Context, email string) (*models.User, error) {
+// FindByIdentifier looks a user up by email (anything containing "@", matching the display or canonical form), verified phone number
+// (E.164, starting with "+") or, otherwise, username, which is matched case-insensitively
+func (r *userRepository) FindByIdentifier(ctx context.Context, identifier string) (*models.User, error) {
+	query := r.db.WithContext(ctx)
+	switch {
+	case strings.Contains(identifier, "@"):
+		query = query.Where("(email = ? OR canonical_email = ?)", identifier, identifier)
+	case strings.HasPrefix(identifier, "+"):
+		query = query.Where("phone_number = ? AND phone_verified_at IS NOT NULL", identifier)
+	default:
+		query = query.Where("LOWER(username) = LOWER(?)", identifier)
+	}
+
 	var user models.User
-	err := r.db.WithContext(ctx).Where("email = ?", email).First(&user).Error
+	err := query.First(&user).Error
 	if errors.Is(err, gorm.ErrRecordNotFound) {
 		return nil, nil
 	}
 	return &user, err
 }
 
+// UsernameTaken reports whether another account, including one in its deletion grace period, holds the username
+func (r *userRepository) UsernameTaken(ctx context.Context, username string, excludeID uuid.UUID) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Unscoped().Model(&models.User{}).
+		Where("LOWER(username) = LOWER(?) AND id <> ?", username, excludeID).
+		Count(&count).Error
+	return count > 0, err
+}
+
 func (r *userRepository) Update(ctx context.Context, id uuid.UUID, updates interface{}) error {
-	result := r.db.WithContext(ctx).Model(&models.User{}).
-		Where("id = ?", id).
-		Updates(updates)
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.User{}).
+			Where("id = ?", id).
+			Updates(updates)
 
-	if result.Error != nil {
-		return result.Error
-	}
+		if result.Error != nil {
+			return result.Error
+		}
 
-	if result.RowsAffected == 0 {
-		return gorm.ErrRecordNotFound
-	}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
 
-	return nil
+		return appendUserEvent(tx, models.EventUserUpdated, id)
+	})
 }
 
-func (r *userRepository) List(ctx context.Context, lastID uuid.UUID, searchEmail string, limit int) ([]models.User, error) {
+func (r *userRepository) List(ctx context.Context, lastID uuid.UUID, filter UserFilter, limit int) ([]models.User, error) {
 	var users []models.User
 	query := r.db.WithContext(ctx).Model(&models.User{})
 
-	if searchEmail != "" {
-		query = query.Where("email ILIKE ?", "%"+searchEmail+"%")
+	if filter.Email != "" {
+		query = query.Where("email ILIKE ?", "%"+filter.Email+"%")
+	}
+
+	if len(filter.Attributes) > 0 {
+		// Containment is served by the GIN index on attributes
+		contains, err := models.JSONMap(filter.Attributes).Value()
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where("attributes @> ?::jsonb", contains)
 	}
 
 	if lastID != uuid.Nil {
@@ -88,3 +153,257 @@ func (r *userRepository) List(ctx context.Context, lastID uuid.UUID, searchEmail
 
 	return users, err
 }
+
+// UpdateAttributes merges set into the user's attributes and drops the keys in remove, in a single statement
+func (r *userRepository) UpdateAttributes(ctx context.Context, id uuid.UUID, set map[string]interface{}, remove []string) error {
+	merged, err := models.JSONMap(set).Value()
+	if err != nil {
+		return err
+	}
+
+	expr := "attributes || ?::jsonb"
+	args := []interface{}{merged}
+	for _, key := range remove {
+		expr = "(" + expr + ") - ?::text"
+		args = append(args, key)
+	}
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.User{}).
+			Where("id = ?", id).
+			Update("attributes", gorm.Expr(expr, args...))
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		return appendUserEvent(tx, models.EventUserUpdated, id)
+	})
+}
+
+// MarkPhoneVerified marks the number as verified, unless the user changed it in the meantime.
+// Returns gorm.ErrDuplicatedKey when another account has already verified the same number.
+func (r *userRepository) MarkPhoneVerified(ctx context.Context, id uuid.UUID, phone string) (bool, error) {
+	var verified bool
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.User{}).
+			Where("id = ? AND phone_number = ?", id, phone).
+			Update("phone_verified_at", time.Now().UTC())
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+
+		verified = true
+		return appendUserEvent(tx, models.EventUserUpdated, id)
+	})
+	return verified, err
+}
+
+// EmailTaken reports whether another account holds or has claimed the email. That includes soft-deleted
+// accounts still in their grace period, unconfirmed email changes, and old addresses of confirmed changes
+// that can still be reverted. Claims are compared on the canonical form.
+func (r *userRepository) EmailTaken(ctx context.Context, email, canonical string, excludeID uuid.UUID) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Unscoped().Model(&models.User{}).
+		Where("(email = ? OR canonical_email = ?) AND id <> ?", email, canonical, excludeID).
+		Count(&count).Error
+	if err != nil || count > 0 {
+		return count > 0, err
+	}
+
+	now := time.Now().UTC()
+	err = r.db.WithContext(ctx).Model(&models.EmailChange{}).
+		Where("user_id <> ? AND cancelled_at IS NULL", excludeID).
+		Where(
+			r.db.Where("canonical_new_email = ? AND confirmed_at IS NULL AND expires_at > ?", canonical, now).
+				Or("canonical_old_email = ? AND confirmed_at IS NOT NULL AND revert_until > ?", canonical, now),
+		).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// SoftDelete marks the user deleted and reports whether a live user was found
+func (r *userRepository) SoftDelete(ctx context.Context, id uuid.UUID) (bool, error) {
+	var deleted bool
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Delete(&models.User{}, "id = ?", id)
+		if result.Error != nil || result.RowsAffected != 1 {
+			return result.Error
+		}
+
+		deleted = true
+		return appendUserEvent(tx, models.EventUserDeleted, id)
+	})
+	return deleted, err
+}
+
+func (r *userRepository) FindDeleted(ctx context.Context, id uuid.UUID, deletedAfter time.Time) (*models.User, error) {
+	var user models.User
+	err := r.db.WithContext(ctx).Unscoped().
+		Where("id = ? AND deleted_at > ? AND purged_at IS NULL", id, deletedAfter).
+		First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &user, err
+}
+
+// FindDeletedByCanonicalEmail finds an account in its deletion grace period by the canonical form of its email
+func (r *userRepository) FindDeletedByCanonicalEmail(ctx context.Context, canonical string, deletedAfter time.Time) (*models.User, error) {
+	var user models.User
+	err := r.db.WithContext(ctx).Unscoped().
+		Where("canonical_email = ? AND deleted_at > ? AND purged_at IS NULL", canonical, deletedAfter).
+		First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &user, err
+}
+
+// Restore clears the deletion mark and reports whether a restorable user was found
+func (r *userRepository) Restore(ctx context.Context, id uuid.UUID) (bool, error) {
+	var restored bool
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Unscoped().Model(&models.User{}).
+			Where("id = ? AND deleted_at IS NOT NULL AND purged_at IS NULL", id).
+			Update("deleted_at", nil)
+		if result.Error != nil || result.RowsAffected != 1 {
+			return result.Error
+		}
+
+		restored = true
+		return appendUserEvent(tx, models.EventUserRestored, id)
+	})
+	return restored, err
+}
+
+// ListCanonicalEmails pages through every account, deleted and purged ones included, loading only the
+// columns the canonical email backfill needs
+func (r *userRepository) ListCanonicalEmails(ctx context.Context, lastID uuid.UUID, limit int) ([]models.User, error) {
+	var users []models.User
+	err := r.db.WithContext(ctx).Unscoped().
+		Select("id", "email", "canonical_email", "purged_at").
+		Where("id > ?", lastID).
+		Order("id ASC").
+		Limit(limit).
+		Find(&users).Error
+	return users, err
+}
+
+// UpdateCanonicalEmails stores recomputed canonical addresses in one transaction. The rows are not otherwise
+// changed, so updated_at is left alone and no webhook event is recorded.
+func (r *userRepository) UpdateCanonicalEmails(ctx context.Context, canonical map[uuid.UUID]string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for id, value := range canonical {
+			err := tx.Unscoped().Model(&models.User{}).
+				Where("id = ?", id).
+				UpdateColumn("canonical_email", value).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// EnforceUniqueCanonicalEmail moves account uniqueness from the display address to the canonical one
+func (r *userRepository) EnforceUniqueCanonicalEmail(ctx context.Context) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, statement := range []string{
+			"CREATE UNIQUE INDEX IF NOT EXISTS idx_users_canonical_email ON users(canonical_email)",
+			"DROP INDEX IF EXISTS idx_users_canonical_email_lookup",
+			"ALTER TABLE users DROP CONSTRAINT IF EXISTS users_email_key",
+		} {
+			if err := tx.Exec(statement).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ListPurgeable returns soft-deleted users whose grace period has ended and that have not been purged yet
+func (r *userRepository) ListPurgeable(ctx context.Context, deletedBefore time.Time, limit int) ([]models.User, error) {
+	var users []models.User
+	err := r.db.WithContext(ctx).Unscoped().
+		Where("deleted_at IS NOT NULL AND deleted_at < ? AND purged_at IS NULL", deletedBefore).
+		Order("deleted_at ASC").
+		Limit(limit).
+		Find(&users).Error
+	return users, err
+}
+
+// Anonymize strips personal data from a deleted user but keeps the row for referential history
+func (r *userRepository) Anonymize(ctx context.Context, id uuid.UUID) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", id).Delete(&models.Session{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", id).Delete(&models.MagicLinkToken{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", id).Delete(&models.DataExport{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", id).Delete(&models.EmailChange{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", id).Delete(&models.UserIdentity{}).Error; err != nil {
+			return err
+		}
+		// Event payloads hold copies of the profile
+		if err := purgeUserEvents(tx, id); err != nil {
+			return err
+		}
+
+		return tx.Unscoped().Model(&models.User{}).
+			Where("id = ?", id).
+			Updates(map[string]interface{}{
+				"email":             fmt.Sprintf("deleted-%s@invalid", id.String()),
+				"canonical_email":   fmt.Sprintf("deleted-%s@invalid", id.String()),
+				"password":          models.UnusablePassword,
+				"username":          nil,
+				"status_reason":     nil,
+				"display_name":      "",
+				"given_name":        "",
+				"family_name":       "",
+				"avatar_url":        "",
+				"avatar_key":        "",
+				"avatar_variants":   models.JSONMap{},
+				"phone_number":      "",
+				"phone_verified_at": nil,
+				"attributes":        models.JSONMap{},
+				"purged_at":         time.Now().UTC(),
+			}).Error
+	})
+}
+
+// HardDelete permanently removes the user and reduces the events about them to its ID; dependent rows
+// are removed by ON DELETE CASCADE
+func (r *userRepository) HardDelete(ctx context.Context, id uuid.UUID) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := purgeUserEvents(tx, id); err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(&models.User{}, "id = ?", id).Error
+	})
+}
+
+// LinkIdentity records the external account, or refreshes its last use. An external account already linked
+// to another user is left untouched.
+func (r *userRepository) LinkIdentity(ctx context.Context, identity *models.UserIdentity) error {
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "provider"}, {Name: "issuer"}, {Name: "subject"}},
+		DoUpdates: clause.AssignmentColumns([]string{"last_used_at"}),
+		Where: clause.Where{Exprs: []clause.Expression{
+			clause.Expr{SQL: "user_identities.user_id = excluded.user_id"},
+		}},
+	}).Create(identity).Error
+}
+
+func (r *userRepository) ListIdentities(ctx context.Context, userID uuid.UUID) ([]models.UserIdentity, error) {
+	var identities []models.UserIdentity
+	err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("created_at").
+		Find(&identities).Error
+	return identities, err
+}
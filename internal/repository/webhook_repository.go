@@ -0,0 +1,253 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+	"user-management/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type WebhookRepository interface {
+	CreateSubscription(ctx context.Context, subscription *models.WebhookSubscription) error
+	FindSubscription(ctx context.Context, id uuid.UUID) (*models.WebhookSubscription, error)
+	ListSubscriptions(ctx context.Context) ([]models.WebhookSubscription, error)
+	UpdateSubscription(ctx context.Context, id uuid.UUID, updates map[string]interface{}) (bool, error)
+	DeleteSubscription(ctx context.Context, id uuid.UUID) (bool, error)
+	// FanOut turns up to limit undispatched outbox events into deliveries for the active subscriptions
+	// and returns how many events it dispatched
+	FanOut(ctx context.Context, limit int) (int, error)
+	// ClaimDue returns up to limit pending deliveries that are due, with their subscription and event,
+	// and pushes their next attempt back by lease so other instances skip them while they are in flight
+	ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]models.WebhookDelivery, error)
+	MarkDelivered(ctx context.Context, id uuid.UUID, statusCode int) error
+	// MarkFailed records a failed attempt and schedules the next one, or moves the delivery to
+	// the dead-letter state when nextAttemptAt is nil
+	MarkFailed(ctx context.Context, id uuid.UUID, statusCode *int, reason string, nextAttemptAt *time.Time) error
+	FindDelivery(ctx context.Context, id uuid.UUID) (*models.WebhookDelivery, error)
+	// ListDeliveries returns the subscription's deliveries newest first, starting below the cursor delivery
+	ListDeliveries(ctx context.Context, subscriptionID uuid.UUID, status string, cursor uuid.UUID, limit int) ([]models.WebhookDelivery, error)
+	// Replay queues deliveries again with a fresh attempt budget and returns how many were queued
+	Replay(ctx context.Context, subscriptionID uuid.UUID, deliveryIDs []uuid.UUID, status string) (int64, error)
+}
+
+type webhookRepository struct {
+	db *gorm.DB
+}
+
+func NewWebhookRepository(db *gorm.DB) WebhookRepository {
+	return &webhookRepository{db: db}
+}
+
+func (r *webhookRepository) CreateSubscription(ctx context.Context, subscription *models.WebhookSubscription) error {
+	return r.db.WithContext(ctx).Create(subscription).Error
+}
+
+func (r *webhookRepository) FindSubscription(ctx context.Context, id uuid.UUID) (*models.WebhookSubscription, error) {
+	var subscription models.WebhookSubscription
+	err := r.db.WithContext(ctx).First(&subscription, "id = ?", id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &subscription, err
+}
+
+func (r *webhookRepository) ListSubscriptions(ctx context.Context) ([]models.WebhookSubscription, error) {
+	var subscriptions []models.WebhookSubscription
+	err := r.db.WithContext(ctx).Order("created_at").Find(&subscriptions).Error
+	return subscriptions, err
+}
+
+func (r *webhookRepository) UpdateSubscription(ctx context.Context, id uuid.UUID, updates map[string]interface{}) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&models.WebhookSubscription{}).
+		Where("id = ?", id).
+		Updates(updates)
+	return result.RowsAffected == 1, result.Error
+}
+
+func (r *webhookRepository) DeleteSubscription(ctx context.Context, id uuid.UUID) (bool, error) {
+	result := r.db.WithContext(ctx).Delete(&models.WebhookSubscription{}, "id = ?", id)
+	return result.RowsAffected == 1, result.Error
+}
+
+func (r *webhookRepository) FanOut(ctx context.Context, limit int) (int, error) {
+	var dispatched int
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var events []models.OutboxEvent
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("dispatched_at IS NULL").
+			Order("id").
+			Limit(limit).
+			Find(&events).Error
+		if err != nil || len(events) == 0 {
+			return err
+		}
+
+		var subscriptions []models.WebhookSubscription
+		if err := tx.Where("active").Find(&subscriptions).Error; err != nil {
+			return err
+		}
+
+		now := time.Now().UTC()
+		var deliveries []models.WebhookDelivery
+		ids := make([]int64, 0, len(events))
+		for _, event := range events {
+			ids = append(ids, event.ID)
+			for _, subscription := range subscriptions {
+				if subscription.Subscribes(event.Type) {
+					deliveries = append(deliveries, models.WebhookDelivery{
+						SubscriptionID: subscription.ID,
+						EventID:        event.ID,
+						Status:         models.DeliveryStatusPending,
+						NextAttemptAt:  now,
+					})
+				}
+			}
+		}
+
+		if len(deliveries) > 0 {
+			if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&deliveries).Error; err != nil {
+				return err
+			}
+		}
+
+		dispatched = len(events)
+		return tx.Model(&models.OutboxEvent{}).Where("id IN ?", ids).Update("dispatched_at", now).Error
+	})
+	return dispatched, err
+}
+
+func (r *webhookRepository) ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]models.WebhookDelivery, error) {
+	var deliveries []models.WebhookDelivery
+	var ids []uuid.UUID
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now().UTC()
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_at <= ?", models.DeliveryStatusPending, now).
+			Order("next_attempt_at").
+			Limit(limit).
+			Find(&deliveries).Error
+		if err != nil || len(deliveries) == 0 {
+			return err
+		}
+
+		ids = make([]uuid.UUID, 0, len(deliveries))
+		for _, delivery := range deliveries {
+			ids = append(ids, delivery.ID)
+		}
+		return tx.Model(&models.WebhookDelivery{}).
+			Where("id IN ?", ids).
+			Update("next_attempt_at", now.Add(lease)).Error
+	})
+	if err != nil || len(ids) == 0 {
+		return nil, err
+	}
+
+	// Load the subscription and event of each claimed delivery
+	deliveries = nil
+	err = r.db.WithContext(ctx).
+		Preload("Subscription").
+		Preload("Event").
+		Where("id IN ?", ids).
+		Find(&deliveries).Error
+	return deliveries, err
+}
+
+func (r *webhookRepository) MarkDelivered(ctx context.Context, id uuid.UUID, statusCode int) error {
+	now := time.Now().UTC()
+	return r.db.WithContext(ctx).Model(&models.WebhookDelivery{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"status":           models.DeliveryStatusDelivered,
+			"attempts":         gorm.Expr("attempts + 1"),
+			"last_status_code": statusCode,
+			"last_error":       nil,
+			"delivered_at":     now,
+		}).Error
+}
+
+func (r *webhookRepository) MarkFailed(
+	ctx context.Context,
+	id uuid.UUID,
+	statusCode *int,
+	reason string,
+	nextAttemptAt *time.Time,
+) error {
+	updates := map[string]interface{}{
+		"attempts":         gorm.Expr("attempts + 1"),
+		"last_status_code": statusCode,
+		"last_error":       reason,
+	}
+	if nextAttemptAt != nil {
+		updates["next_attempt_at"] = nextAttemptAt.UTC()
+	} else {
+		updates["status"] = models.DeliveryStatusDead
+	}
+
+	return r.db.WithContext(ctx).Model(&models.WebhookDelivery{}).
+		Where("id = ?", id).
+		Updates(updates).Error
+}
+
+func (r *webhookRepository) FindDelivery(ctx context.Context, id uuid.UUID) (*models.WebhookDelivery, error) {
+	var delivery models.WebhookDelivery
+	err := r.db.WithContext(ctx).Preload("Event").First(&delivery, "id = ?", id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &delivery, err
+}
+
+func (r *webhookRepository) ListDeliveries(
+	ctx context.Context,
+	subscriptionID uuid.UUID,
+	status string,
+	cursor uuid.UUID,
+	limit int,
+) ([]models.WebhookDelivery, error) {
+	query := r.db.WithContext(ctx).Preload("Event").Where("subscription_id = ?", subscriptionID)
+
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	if cursor != uuid.Nil {
+		var last models.WebhookDelivery
+		if err := r.db.WithContext(ctx).Select("created_at").First(&last, "id = ?", cursor).Error; err != nil {
+			return nil, err
+		}
+		query = query.Where("(created_at < ? OR (created_at = ? AND id < ?))", last.CreatedAt, last.CreatedAt, cursor)
+	}
+
+	var deliveries []models.WebhookDelivery
+	err := query.Order("created_at DESC, id DESC").Limit(limit).Find(&deliveries).Error
+	return deliveries, err
+}
+
+func (r *webhookRepository) Replay(
+	ctx context.Context,
+	subscriptionID uuid.UUID,
+	deliveryIDs []uuid.UUID,
+	status string,
+) (int64, error) {
+	query := r.db.WithContext(ctx).Model(&models.WebhookDelivery{}).
+		Where("subscription_id = ? AND status <> ?", subscriptionID, models.DeliveryStatusPending)
+
+	if len(deliveryIDs) > 0 {
+		query = query.Where("id IN ?", deliveryIDs)
+	}
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	result := query.Updates(map[string]interface{}{
+		"status":          models.DeliveryStatusPending,
+		"attempts":        0,
+		"next_attempt_at": time.Now().UTC(),
+		"delivered_at":    nil,
+	})
+	return result.RowsAffected, result.Error
+}
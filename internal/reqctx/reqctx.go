@@ -0,0 +1,38 @@
+// Package reqctx carries request-scoped metadata through context.Context so
+// services can use it without depending on the HTTP layer.
+package reqctx
+
+import "context"
+
+type clientInfoKey struct{}
+
+// ClientInfo describes the client that issued the current request
+type ClientInfo struct {
+	IP        string
+	UserAgent string
+	// RequestID correlates the request across logs and audit entries
+	RequestID string
+}
+
+func WithClientInfo(ctx context.Context, info ClientInfo) context.Context {
+	return context.WithValue(ctx, clientInfoKey{}, info)
+}
+
+// ClientInfoFrom returns the client info stored in ctx, or a zero value
+func ClientInfoFrom(ctx context.Context) ClientInfo {
+	info, _ := ctx.Value(clientInfoKey{}).(ClientInfo)
+	return info
+}
+
+type impersonatorKey struct{}
+
+// WithImpersonator marks the request as made by an admin acting on behalf of another user
+func WithImpersonator(ctx context.Context, impersonatorID string) context.Context {
+	return context.WithValue(ctx, impersonatorKey{}, impersonatorID)
+}
+
+// ImpersonatorFrom returns the impersonating admin's ID, or an empty string
+func ImpersonatorFrom(ctx context.Context) string {
+	id, _ := ctx.Value(impersonatorKey{}).(string)
+	return id
+}
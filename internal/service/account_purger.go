@@ -0,0 +1,88 @@
+package service
+
+import (
+	"context"
+	"time"
+	"user-management/internal/config"
+	"user-management/internal/repository"
+	"user-management/pkg/logger"
+)
+
+const purgeBatchSize = 100
+
+// AccountPurger permanently deletes or anonymizes accounts whose deletion grace period has ended
+type AccountPurger struct {
+	repo    repository.UserRepository
+	avatars AvatarService
+	cfg     *config.DeletionConfig
+	logger  *logger.Logger
+}
+
+func NewAccountPurger(
+	repo repository.UserRepository,
+	avatars AvatarService,
+	cfg *config.DeletionConfig,
+	logger *logger.Logger,
+) *AccountPurger {
+	return &AccountPurger{
+		repo:    repo,
+		avatars: avatars,
+		cfg:     cfg,
+		logger:  logger,
+	}
+}
+
+// Run purges on every interval until the context is cancelled
+func (p *AccountPurger) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.cfg.PurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		if purged, err := p.PurgeExpired(ctx); err != nil && ctx.Err() == nil {
+			p.logger.Error().Err(err).Int("purged", purged).Msg("Account purge failed")
+		} else if purged > 0 {
+			p.logger.Info().Int("purged", purged).Str("mode", p.cfg.PurgeMode).Msg("Purged deleted accounts")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// PurgeExpired processes every account past its grace period and returns how many were purged
+func (p *AccountPurger) PurgeExpired(ctx context.Context) (int, error) {
+	purged := 0
+	cutoff := time.Now().Add(-p.cfg.GracePeriod)
+
+	for {
+		users, err := p.repo.ListPurgeable(ctx, cutoff, purgeBatchSize)
+		if err != nil {
+			return purged, err
+		}
+
+		for _, user := range users {
+			if user.AvatarKey != "" {
+				if err := p.avatars.DeleteObjects(ctx, user.AvatarKey); err != nil {
+					return purged, err
+				}
+			}
+
+			if p.cfg.PurgeMode == "delete" {
+				err = p.repo.HardDelete(ctx, user.ID)
+			} else {
+				err = p.repo.Anonymize(ctx, user.ID)
+			}
+			if err != nil {
+				return purged, err
+			}
+			purged++
+		}
+
+		if len(users) < purgeBatchSize || ctx.Err() != nil {
+			return purged, ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,351 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"user-management/internal/dtos"
+	"user-management/internal/models"
+	"user-management/internal/repository"
+	"user-management/pkg/cache"
+	"user-management/pkg/jsonschema"
+
+	"github.com/google/uuid"
+)
+
+var attributeKeyPattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,63}$`)
+
+type AttributeService interface {
+	ListDefinitions(ctx context.Context) ([]models.AttributeDefinition, error)
+	PutDefinition(ctx context.Context, actorID uuid.UUID, key string, req *dtos.AttributeDefinitionRequest) (*models.AttributeDefinition, error)
+	DeleteDefinition(ctx context.Context, actorID uuid.UUID, key string) error
+	GetAttributes(ctx context.Context, actorID, targetID uuid.UUID) (map[string]interface{}, error)
+	UpdateAttributes(ctx context.Context, actorID, targetID uuid.UUID, req dtos.UpdateAttributesRequest) (map[string]interface{}, error)
+	Readable(ctx context.Context, actorID uuid.UUID, users []models.User) ([]map[string]interface{}, error)
+	ParseFilter(ctx context.Context, actorID uuid.UUID, raw map[string]string) (map[string]interface{}, error)
+}
+
+type attributeService struct {
+	repo     repository.AttributeDefinitionRepository
+	userRepo repository.UserRepository
+	cache    cache.Cache
+	audit    AuditService
+}
+
+func NewAttributeService(
+	repo repository.AttributeDefinitionRepository,
+	userRepo repository.UserRepository,
+	cache cache.Cache,
+	audit AuditService,
+) AttributeService {
+	return &attributeService{
+		repo:     repo,
+		userRepo: userRepo,
+		cache:    cache,
+		audit:    audit,
+	}
+}
+
+func (s *attributeService) ListDefinitions(ctx context.Context) ([]models.AttributeDefinition, error) {
+	return s.repo.List(ctx)
+}
+
+// PutDefinition creates or replaces a definition. Existing values are not revalidated.
+func (s *attributeService) PutDefinition(
+	ctx context.Context,
+	actorID uuid.UUID,
+	key string,
+	req *dtos.AttributeDefinitionRequest,
+) (*models.AttributeDefinition, error) {
+	if !attributeKeyPattern.MatchString(key) {
+		return nil, ErrInvalidAttributeKey
+	}
+
+	if _, err := jsonschema.Compile(req.Schema); err != nil {
+		return nil, ErrInvalidAttributeSchema.WithDetails(err.Error())
+	}
+
+	definition := &models.AttributeDefinition{
+		Key:             key,
+		Description:     req.Description,
+		Schema:          req.Schema,
+		ReadPermission:  req.ReadPermission,
+		WritePermission: req.WritePermission,
+	}
+	if definition.ReadPermission == "" {
+		definition.ReadPermission = models.AttributeReadSelf
+	}
+	if definition.WritePermission == "" {
+		definition.WritePermission = models.AttributeWriteAdmin
+	}
+
+	if err := s.repo.Upsert(ctx, definition); err != nil {
+		return nil, fmt.Errorf("failed to save attribute definition: %w", err)
+	}
+
+	s.audit.Record(ctx, newAuditEvent(models.AuditAttributeDefinitionSaved, models.AuditSuccess, actorID, uuid.Nil, models.JSONMap{
+		"key":              key,
+		"read_permission":  definition.ReadPermission,
+		"write_permission": definition.WritePermission,
+	}))
+
+	return s.repo.FindByKey(ctx, key)
+}
+
+// DeleteDefinition removes the definition and the attribute from every user
+func (s *attributeService) DeleteDefinition(ctx context.Context, actorID uuid.UUID, key string) error {
+	deleted, err := s.repo.Delete(ctx, key)
+	if err != nil {
+		return fmt.Errorf("failed to delete attribute definition: %w", err)
+	}
+	if !deleted {
+		return ErrAttributeDefinitionNotFound
+	}
+
+	s.audit.Record(ctx, newAuditEvent(models.AuditAttributeDefinitionDeleted, models.AuditSuccess, actorID, uuid.Nil, models.JSONMap{
+		"key": key,
+	}))
+
+	return nil
+}
+
+func (s *attributeService) GetAttributes(ctx context.Context, actorID, targetID uuid.UUID) (map[string]interface{}, error) {
+	isAdmin, err := s.authorize(ctx, actorID, targetID)
+	if err != nil {
+		return nil, err
+	}
+
+	target, err := s.userRepo.FindByID(ctx, targetID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+	if target == nil {
+		return nil, ErrUserNotFound
+	}
+
+	definitions, err := s.definitions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return readable(definitions, target, actorID == targetID, isAdmin), nil
+}
+
+// UpdateAttributes validates every value against its definition before writing any of them
+func (s *attributeService) UpdateAttributes(
+	ctx context.Context,
+	actorID, targetID uuid.UUID,
+	req dtos.UpdateAttributesRequest,
+) (map[string]interface{}, error) {
+	isAdmin, err := s.authorize(ctx, actorID, targetID)
+	if err != nil {
+		return nil, err
+	}
+
+	definitions, err := s.definitions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	set := make(map[string]interface{})
+	var remove []string
+
+	for key, raw := range req {
+		definition, ok := definitions[key]
+		if !ok {
+			return nil, ErrUnknownAttribute.WithDetails(key)
+		}
+		if !definition.CanWrite(actorID == targetID, isAdmin) {
+			return nil, ErrAttributeForbidden.WithDetails(key)
+		}
+
+		var value interface{}
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return nil, ErrInvalidAttribute.WithDetails(fmt.Sprintf("%s: %v", key, err))
+		}
+		if value == nil {
+			remove = append(remove, key)
+			continue
+		}
+
+		schema, err := jsonschema.Compile(definition.Schema)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compile schema of %s: %w", key, err)
+		}
+		if err := schema.Validate(value); err != nil {
+			return nil, ErrInvalidAttribute.WithDetails(fmt.Sprintf("%s: %v", key, err))
+		}
+		set[key] = value
+	}
+
+	target, err := s.userRepo.FindByID(ctx, targetID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+	if target == nil {
+		return nil, ErrUserNotFound
+	}
+
+	if len(set) > 0 || len(remove) > 0 {
+		if err := s.userRepo.UpdateAttributes(ctx, targetID, set, remove); err != nil {
+			return nil, fmt.Errorf("failed to update attributes: %w", err)
+		}
+		_ = s.cache.Delete(ctx, userCacheKey(targetID))
+
+		if target, err = s.userRepo.FindByID(ctx, targetID); err != nil {
+			return nil, fmt.Errorf("failed to find user: %w", err)
+		}
+
+		// Values may be personal data, so only the changed keys are recorded
+		if actorID != targetID {
+			s.audit.Record(ctx, newAuditEvent(models.AuditAttributesUpdated, models.AuditSuccess, actorID, targetID, models.JSONMap{
+				"set":     sortedKeys(set),
+				"removed": sortedStrings(remove),
+			}))
+		}
+	}
+
+	return readable(definitions, target, actorID == targetID, isAdmin), nil
+}
+
+// Readable returns, for each user, the attributes the actor is allowed to see
+func (s *attributeService) Readable(ctx context.Context, actorID uuid.UUID, users []models.User) ([]map[string]interface{}, error) {
+	isAdmin, err := s.isAdmin(ctx, actorID)
+	if err != nil {
+		return nil, err
+	}
+
+	definitions, err := s.definitions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]map[string]interface{}, len(users))
+	for i := range users {
+		result[i] = readable(definitions, &users[i], users[i].ID == actorID, isAdmin)
+	}
+	return result, nil
+}
+
+// ParseFilter converts query string values to the type declared by each attribute's schema.
+// Only attributes the actor could read on other users are filterable.
+func (s *attributeService) ParseFilter(ctx context.Context, actorID uuid.UUID, raw map[string]string) (map[string]interface{}, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+
+	isAdmin, err := s.isAdmin(ctx, actorID)
+	if err != nil {
+		return nil, err
+	}
+
+	definitions, err := s.definitions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := make(map[string]interface{}, len(raw))
+	for key, value := range raw {
+		definition, ok := definitions[key]
+		if !ok {
+			return nil, ErrUnknownAttribute.WithDetails(key)
+		}
+		if !definition.CanRead(false, isAdmin) {
+			return nil, ErrAttributeForbidden.WithDetails(key)
+		}
+
+		schema, err := jsonschema.Compile(definition.Schema)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compile schema of %s: %w", key, err)
+		}
+
+		if filter[key], err = parseFilterValue(schema.Types(), value); err != nil {
+			return nil, ErrInvalidAttribute.WithDetails(fmt.Sprintf("%s: %v", key, err))
+		}
+	}
+
+	return filter, nil
+}
+
+func parseFilterValue(types []string, value string) (interface{}, error) {
+	if len(types) == 0 {
+		return value, nil
+	}
+
+	switch types[0] {
+	case "string":
+		return value, nil
+	case "integer", "number":
+		return strconv.ParseFloat(value, 64)
+	case "boolean":
+		return strconv.ParseBool(value)
+	}
+	return nil, fmt.Errorf("%s attributes cannot be filtered", types[0])
+}
+
+// authorize lets users manage their own attributes and admins anyone's, and reports whether the actor is an admin
+func (s *attributeService) authorize(ctx context.Context, actorID, targetID uuid.UUID) (bool, error) {
+	isAdmin, err := s.isAdmin(ctx, actorID)
+	if err != nil {
+		return false, err
+	}
+	if actorID != targetID && !isAdmin {
+		return false, ErrUnauthorized
+	}
+	return isAdmin, nil
+}
+
+func (s *attributeService) isAdmin(ctx context.Context, actorID uuid.UUID) (bool, error) {
+	actor, err := s.userRepo.FindByID(ctx, actorID)
+	if err != nil {
+		return false, fmt.Errorf("failed to find user: %w", err)
+	}
+	return actor != nil && actor.Role == models.RoleAdmin, nil
+}
+
+func (s *attributeService) definitions(ctx context.Context) (map[string]*models.AttributeDefinition, error) {
+	list, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list attribute definitions: %w", err)
+	}
+
+	definitions := make(map[string]*models.AttributeDefinition, len(list))
+	for i := range list {
+		definitions[list[i].Key] = &list[i]
+	}
+	return definitions, nil
+}
+
+func sortedKeys(values map[string]interface{}) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	return sortedStrings(keys)
+}
+
+func sortedStrings(values []string) []string {
+	if values == nil {
+		values = []string{}
+	}
+	sort.Strings(values)
+	return values
+}
+
+// readable drops values without a definition or that the viewer may not read
+func readable(
+	definitions map[string]*models.AttributeDefinition,
+	user *models.User,
+	isSelf, isAdmin bool,
+) map[string]interface{} {
+	result := make(map[string]interface{})
+	for key, value := range user.Attributes {
+		if definition, ok := definitions[key]; ok && definition.CanRead(isSelf, isAdmin) {
+			result[key] = value
+		}
+	}
+	return result
+}
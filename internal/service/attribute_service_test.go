@@ -0,0 +1,90 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"user-management/internal/dtos"
+	"user-management/internal/models"
+	"user-management/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+type fakeDefinitionRepository struct {
+	repository.AttributeDefinitionRepository
+	definitions []models.AttributeDefinition
+}
+
+func (r *fakeDefinitionRepository) List(context.Context) ([]models.AttributeDefinition, error) {
+	return r.definitions, nil
+}
+
+// attributeUserRepository serves users from memory and applies attribute updates to them
+type attributeUserRepository struct {
+	repository.UserRepository
+	users map[uuid.UUID]*models.User
+}
+
+func (r *attributeUserRepository) FindByID(_ context.Context, id uuid.UUID) (*models.User, error) {
+	return r.users[id], nil
+}
+
+func (r *attributeUserRepository) UpdateAttributes(_ context.Context, id uuid.UUID, set map[string]interface{}, remove []string) error {
+	user := r.users[id]
+	for key, value := range set {
+		user.Attributes[key] = value
+	}
+	for _, key := range remove {
+		delete(user.Attributes, key)
+	}
+	return nil
+}
+
+func TestUpdateAttributesAuditsAdminWrites(t *testing.T) {
+	admin := &models.User{ID: uuid.New(), Role: models.RoleAdmin, Attributes: models.JSONMap{}}
+	user := &models.User{ID: uuid.New(), Role: models.RoleUser, Attributes: models.JSONMap{"nickname": "ada"}}
+	audit := &recordingAudit{}
+	svc := NewAttributeService(
+		&fakeDefinitionRepository{definitions: []models.AttributeDefinition{
+			{Key: "department", Schema: models.JSONMap{"type": "string"}, WritePermission: models.AttributeWriteAdmin},
+			{Key: "nickname", Schema: models.JSONMap{"type": "string"}, WritePermission: models.AttributeWriteSelf},
+		}},
+		&attributeUserRepository{users: map[uuid.UUID]*models.User{admin.ID: admin, user.ID: user}},
+		newMemoryCache(),
+		audit,
+	)
+	ctx := context.Background()
+
+	if _, err := svc.UpdateAttributes(ctx, user.ID, user.ID, dtos.UpdateAttributesRequest{
+		"nickname": json.RawMessage(`"ada l."`),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if len(audit.events) != 0 {
+		t.Fatalf("user's own write audited: %+v", audit.events)
+	}
+
+	if _, err := svc.UpdateAttributes(ctx, admin.ID, user.ID, dtos.UpdateAttributesRequest{
+		"department": json.RawMessage(`"Research"`),
+		"nickname":   json.RawMessage(`null`),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if len(audit.events) != 1 {
+		t.Fatalf("recorded %d events, want 1", len(audit.events))
+	}
+
+	event := audit.events[0]
+	if event.Action != models.AuditAttributesUpdated || *event.ActorID != admin.ID || *event.TargetID != user.ID {
+		t.Fatalf("event = %+v", event)
+	}
+	if !reflect.DeepEqual(event.Details["set"], []string{"department"}) ||
+		!reflect.DeepEqual(event.Details["removed"], []string{"nickname"}) {
+		t.Errorf("details = %v", event.Details)
+	}
+	if value, _ := json.Marshal(event.Details); string(value) != `{"removed":["nickname"],"set":["department"]}` {
+		t.Errorf("details hold more than the keys: %s", value)
+	}
+}
@@ -0,0 +1,139 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+	"user-management/internal/config"
+	"user-management/internal/dtos"
+	"user-management/internal/models"
+	"user-management/internal/repository"
+	"user-management/internal/reqctx"
+	"user-management/pkg/logger"
+
+	"github.com/google/uuid"
+)
+
+const auditVerifyBatchSize = 500
+
+type AuditService interface {
+	// Record stores the event along with the client details of ctx. Failures are logged rather
+	// than returned so that auditing never blocks the action being audited.
+	Record(ctx context.Context, event *models.AuditEvent)
+	List(ctx context.Context, filter repository.AuditFilter, beforeID int64, limit int) ([]models.AuditEvent, error)
+	// Verify walks the whole log and checks the hash chain
+	Verify(ctx context.Context) (*dtos.AuditVerificationResponse, error)
+	// Pseudonym stands in for personal data in event details. Entries are never erased, so they hold a
+	// keyed hash that still shows whether two entries mention the same value; empty values stay empty.
+	Pseudonym(value string) string
+}
+
+type auditService struct {
+	repo  repository.AuditRepository
+	chain bool
+	key   []byte
+}
+
+func NewAuditService(repo repository.AuditRepository, cfg *config.AuditConfig) AuditService {
+	return &auditService{
+		repo:  repo,
+		chain: cfg.HashChain,
+		key:   []byte(cfg.PseudonymKey),
+	}
+}
+
+func (s *auditService) Record(ctx context.Context, event *models.AuditEvent) {
+	client := reqctx.ClientInfoFrom(ctx)
+	event.IP = client.IP
+	event.UserAgent = truncate(client.UserAgent, 512)
+	event.RequestID = truncate(client.RequestID, 128)
+	if impersonator, err := uuid.Parse(reqctx.ImpersonatorFrom(ctx)); err == nil {
+		event.ImpersonatorID = &impersonator
+	}
+	if event.Details == nil {
+		event.Details = models.JSONMap{}
+	}
+	// Postgres keeps microseconds, so truncate before hashing
+	event.CreatedAt = time.Now().UTC().Truncate(time.Microsecond)
+
+	// The audited action has already happened, so the entry is written even if the request was cancelled
+	if err := s.repo.Append(context.WithoutCancel(ctx), event, s.chain); err != nil {
+		logger.FromContext(ctx).Error().Ctx(ctx).Err(err).
+			Str("action", event.Action).
+			Str("outcome", event.Outcome).
+			Msg("Failed to write audit event")
+	}
+}
+
+func (s *auditService) List(
+	ctx context.Context,
+	filter repository.AuditFilter,
+	beforeID int64,
+	limit int,
+) ([]models.AuditEvent, error) {
+	return s.repo.List(ctx, filter, beforeID, limit)
+}
+
+// Verify recomputes every hash. Entries written while the chain was disabled have no hash; the
+// entry after them starts a new chain.
+func (s *auditService) Verify(ctx context.Context) (*dtos.AuditVerificationResponse, error) {
+	result := &dtos.AuditVerificationResponse{Valid: true}
+
+	var lastID int64
+	prevHash := ""
+	for {
+		events, err := s.repo.ListAfter(ctx, lastID, auditVerifyBatchSize)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read audit log: %w", err)
+		}
+
+		for i := range events {
+			event := &events[i]
+			result.Checked++
+
+			if event.Hash != "" {
+				if event.PrevHash != prevHash || event.ComputeHash(prevHash) != event.Hash {
+					result.Valid = false
+					result.FirstInvalidID = event.ID
+					return result, nil
+				}
+				result.Chained++
+			}
+
+			prevHash = event.Hash
+			lastID = event.ID
+		}
+
+		if len(events) < auditVerifyBatchSize {
+			return result, nil
+		}
+	}
+}
+
+func (s *auditService) Pseudonym(value string) string {
+	if value == "" {
+		return ""
+	}
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// newAuditEvent builds an event; uuid.Nil leaves the actor or target empty
+func newAuditEvent(action, outcome string, actorID, targetID uuid.UUID, details models.JSONMap) *models.AuditEvent {
+	event := &models.AuditEvent{
+		Action:  action,
+		Outcome: outcome,
+		Details: details,
+	}
+	if actorID != uuid.Nil {
+		event.ActorID = &actorID
+	}
+	if targetID != uuid.Nil {
+		event.TargetID = &targetID
+	}
+	return event
+}
@@ -0,0 +1,206 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"net/http"
+	"user-management/internal/apperr"
+	"user-management/internal/models"
+	"user-management/internal/repository"
+	"user-management/internal/utils"
+	"user-management/pkg/blobstore"
+	"user-management/pkg/cache"
+	"user-management/pkg/imaging"
+
+	"github.com/google/uuid"
+)
+
+// avatarMaxPixels bounds decoding memory independently of the compressed upload size
+const avatarMaxPixels = 25_000_000
+
+var (
+	ErrAvatarTooLarge        = apperr.New(http.StatusRequestEntityTooLarge, utils.ErrCodeValidationError, "Avatar file is too large")
+	ErrAvatarUnsupportedType = apperr.New(http.StatusUnsupportedMediaType, utils.ErrCodeValidationError, "Avatar must be a JPEG, PNG or GIF image")
+	ErrInvalidAvatar         = apperr.ErrValidation.WithMessage("Avatar image is invalid")
+)
+
+// avatarVariants are generated for every upload, largest first
+var avatarVariants = []struct {
+	name string
+	size int
+}{
+	{"lg", 512},
+	{"md", 256},
+	{"sm", 64},
+}
+
+type AvatarService interface {
+	Upload(ctx context.Context, userID, targetID uuid.UUID, file io.Reader) (*models.User, error)
+	Remove(ctx context.Context, userID, targetID uuid.UUID) (*models.User, error)
+	DeleteObjects(ctx context.Context, key string) error
+}
+
+type avatarService struct {
+	repo     repository.UserRepository
+	store    blobstore.BlobStore
+	cache    cache.Cache
+	maxBytes int64
+}
+
+func NewAvatarService(repo repository.UserRepository, store blobstore.BlobStore, cache cache.Cache, maxBytes int64) AvatarService {
+	return &avatarService{
+		repo:     repo,
+		store:    store,
+		cache:    cache,
+		maxBytes: maxBytes,
+	}
+}
+
+// Upload re-encodes the image into square variants, which drops EXIF and any other metadata,
+// points the profile at them and deletes the previous avatar's objects
+func (s *avatarService) Upload(ctx context.Context, userID, targetID uuid.UUID, file io.Reader) (*models.User, error) {
+	if userID != targetID {
+		return nil, ErrUnauthorized
+	}
+
+	user, err := s.repo.FindByID(ctx, targetID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+
+	data, err := io.ReadAll(io.LimitReader(file, s.maxBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read avatar: %w", err)
+	}
+	if int64(len(data)) > s.maxBytes {
+		return nil, ErrAvatarTooLarge
+	}
+
+	// Trust the content, not the client-supplied filename or Content-Type
+	switch http.DetectContentType(data) {
+	case "image/jpeg", "image/png", "image/gif":
+	default:
+		return nil, ErrAvatarUnsupportedType
+	}
+
+	img, format, err := imaging.Decode(data, avatarMaxPixels)
+	if err != nil {
+		return nil, ErrInvalidAvatar.WithDetails(err.Error())
+	}
+
+	// PNG keeps the transparency GIFs and PNGs may have
+	outFormat, contentType := imaging.FormatPNG, "image/png"
+	if format == imaging.FormatJPEG {
+		outFormat, contentType = imaging.FormatJPEG, "image/jpeg"
+	}
+
+	suffix, err := utils.GenerateToken()
+	if err != nil {
+		return nil, err
+	}
+	key := fmt.Sprintf("avatars/%s/%s", targetID, suffix[:16])
+
+	variants, err := s.putVariants(ctx, key, img, outFormat, contentType)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.repo.Update(ctx, targetID, map[string]interface{}{
+		"avatar_url":      variants[avatarVariants[0].name],
+		"avatar_key":      key,
+		"avatar_variants": variants,
+	})
+	if err != nil {
+		_ = s.DeleteObjects(ctx, key)
+		return nil, fmt.Errorf("failed to update user: %w", err)
+	}
+
+	_ = s.cache.Delete(ctx, userCacheKey(targetID))
+
+	if user.AvatarKey != "" {
+		_ = s.DeleteObjects(ctx, user.AvatarKey)
+	}
+
+	return s.repo.FindByID(ctx, targetID)
+}
+
+// Remove clears the avatar and deletes its objects
+func (s *avatarService) Remove(ctx context.Context, userID, targetID uuid.UUID) (*models.User, error) {
+	if userID != targetID {
+		return nil, ErrUnauthorized
+	}
+
+	user, err := s.repo.FindByID(ctx, targetID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+
+	err = s.repo.Update(ctx, targetID, map[string]interface{}{
+		"avatar_url":      "",
+		"avatar_key":      "",
+		"avatar_variants": models.JSONMap{},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to update user: %w", err)
+	}
+
+	_ = s.cache.Delete(ctx, userCacheKey(targetID))
+
+	if user.AvatarKey != "" {
+		_ = s.DeleteObjects(ctx, user.AvatarKey)
+	}
+
+	return s.repo.FindByID(ctx, targetID)
+}
+
+// DeleteObjects removes every variant stored under an avatar key
+func (s *avatarService) DeleteObjects(ctx context.Context, key string) error {
+	var errs []error
+	for _, variant := range avatarVariants {
+		if err := s.store.Delete(ctx, key+"/"+variant.name); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// putVariants stores every variant and returns their URLs; on failure nothing is left behind
+func (s *avatarService) putVariants(
+	ctx context.Context,
+	key string,
+	img image.Image,
+	format, contentType string,
+) (models.JSONMap, error) {
+	variants := models.JSONMap{}
+
+	for _, variant := range avatarVariants {
+		// Each variant is scaled from the previous one, which is much cheaper than from the original
+		img = imaging.Square(img, variant.size)
+
+		var buf bytes.Buffer
+		if err := imaging.Encode(&buf, img, format); err != nil {
+			_ = s.DeleteObjects(ctx, key)
+			return nil, fmt.Errorf("failed to encode avatar: %w", err)
+		}
+
+		objectKey := key + "/" + variant.name
+		if err := s.store.Put(ctx, objectKey, contentType, &buf, int64(buf.Len())); err != nil {
+			_ = s.DeleteObjects(ctx, key)
+			return nil, fmt.Errorf("failed to store avatar: %w", err)
+		}
+
+		variants[variant.name] = s.store.URL(objectKey)
+	}
+
+	return variants, nil
+}
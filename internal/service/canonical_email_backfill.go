@@ -0,0 +1,88 @@
+package service
+
+import (
+	"context"
+	"sort"
+	"user-management/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+const canonicalEmailBatchSize = 500
+
+// CanonicalEmailCollision is a canonical address that more than one account normalizes to
+type CanonicalEmailCollision struct {
+	CanonicalEmail string
+	UserIDs        []uuid.UUID
+}
+
+// CanonicalEmailBackfill reports what BackfillCanonicalEmails found and changed
+type CanonicalEmailBackfill struct {
+	Scanned    int
+	Updated    int
+	Collisions []CanonicalEmailCollision
+	// Enforced is set once canonical_email is the unique key of the users table
+	Enforced bool
+}
+
+// BackfillCanonicalEmails recomputes canonical_email for existing accounts with the configured provider rules,
+// the same ones applied at sign-up. When several accounts share a canonical form nothing is written and the
+// collisions are returned, so they can be merged or renamed first. Otherwise the new values are stored and
+// uniqueness moves from email to canonical_email. Running it again is safe.
+func BackfillCanonicalEmails(ctx context.Context, repo repository.UserRepository, emails EmailPolicy) (*CanonicalEmailBackfill, error) {
+	report := &CanonicalEmailBackfill{}
+	changes := make(map[uuid.UUID]string)
+	owners := make(map[string][]uuid.UUID)
+
+	lastID := uuid.Nil
+	for {
+		users, err := repo.ListCanonicalEmails(ctx, lastID, canonicalEmailBatchSize)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, user := range users {
+			report.Scanned++
+			canonical := user.CanonicalEmail
+			// Purged accounts hold a placeholder address that must not be rewritten
+			if user.PurgedAt == nil {
+				canonical = emails.Canonical(user.Email)
+			}
+			if canonical != user.CanonicalEmail {
+				changes[user.ID] = canonical
+			}
+			owners[canonical] = append(owners[canonical], user.ID)
+		}
+
+		if len(users) < canonicalEmailBatchSize {
+			break
+		}
+		lastID = users[len(users)-1].ID
+	}
+
+	for canonical, ids := range owners {
+		if len(ids) > 1 {
+			report.Collisions = append(report.Collisions, CanonicalEmailCollision{CanonicalEmail: canonical, UserIDs: ids})
+		}
+	}
+	if len(report.Collisions) > 0 {
+		sort.Slice(report.Collisions, func(i, j int) bool {
+			return report.Collisions[i].CanonicalEmail < report.Collisions[j].CanonicalEmail
+		})
+		return report, nil
+	}
+
+	if len(changes) > 0 {
+		if err := repo.UpdateCanonicalEmails(ctx, changes); err != nil {
+			return nil, err
+		}
+		report.Updated = len(changes)
+	}
+
+	if err := repo.EnforceUniqueCanonicalEmail(ctx); err != nil {
+		return nil, err
+	}
+	report.Enforced = true
+
+	return report, nil
+}
@@ -0,0 +1,99 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+	"user-management/internal/config"
+	"user-management/internal/models"
+	"user-management/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// backfillRepository serves ListCanonicalEmails from memory and records the writes
+type backfillRepository struct {
+	repository.UserRepository
+	users    []models.User
+	updated  map[uuid.UUID]string
+	enforced bool
+}
+
+func (r *backfillRepository) ListCanonicalEmails(_ context.Context, lastID uuid.UUID, limit int) ([]models.User, error) {
+	var page []models.User
+	for _, user := range r.users {
+		if user.ID.String() > lastID.String() && len(page) < limit {
+			page = append(page, user)
+		}
+	}
+	return page, nil
+}
+
+func (r *backfillRepository) UpdateCanonicalEmails(_ context.Context, canonical map[uuid.UUID]string) error {
+	r.updated = canonical
+	return nil
+}
+
+func (r *backfillRepository) EnforceUniqueCanonicalEmail(context.Context) error {
+	r.enforced = true
+	return nil
+}
+
+func newBackfillRepository(emails ...string) *backfillRepository {
+	repo := &backfillRepository{}
+	for i, email := range emails {
+		id := uuid.MustParse(fmt.Sprintf("00000000-0000-0000-0000-%012d", i+1))
+		repo.users = append(repo.users, models.User{ID: id, Email: email, CanonicalEmail: email})
+	}
+	return repo
+}
+
+func gmailPolicy(t *testing.T) EmailPolicy {
+	t.Helper()
+	emails, err := NewEmailPolicy(&config.EmailConfig{
+		CanonicalRules: map[string]string{"gmail.com": "dots,plus", "googlemail.com": "dots,plus,domain=gmail.com"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return emails
+}
+
+func TestBackfillCanonicalEmailsAppliesProviderRules(t *testing.T) {
+	repo := newBackfillRepository("J.Doe+news@gmail.com", "ada@example.com")
+	purgedAt := time.Now()
+	repo.users = append(repo.users, models.User{
+		ID: uuid.MustParse("00000000-0000-0000-0000-00000000000f"), Email: "deleted-x@invalid",
+		CanonicalEmail: "deleted-x@invalid", PurgedAt: &purgedAt,
+	})
+
+	report, err := BackfillCanonicalEmails(context.Background(), repo, gmailPolicy(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report.Scanned != 3 || report.Updated != 1 || !report.Enforced || !repo.enforced {
+		t.Fatalf("report = %+v, enforced = %v", report, repo.enforced)
+	}
+	if got := repo.updated[repo.users[0].ID]; got != "jdoe@gmail.com" {
+		t.Errorf("canonical = %q, want jdoe@gmail.com", got)
+	}
+}
+
+func TestBackfillCanonicalEmailsReportsCollisions(t *testing.T) {
+	repo := newBackfillRepository("jdoe@gmail.com", "J.Doe+work@googlemail.com", "ada@example.com")
+
+	report, err := BackfillCanonicalEmails(context.Background(), repo, gmailPolicy(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(report.Collisions) != 1 || report.Collisions[0].CanonicalEmail != "jdoe@gmail.com" ||
+		len(report.Collisions[0].UserIDs) != 2 {
+		t.Fatalf("collisions = %+v", report.Collisions)
+	}
+	if report.Enforced || repo.enforced || repo.updated != nil {
+		t.Fatal("backfill wrote changes despite collisions")
+	}
+}
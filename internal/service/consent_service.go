@@ -0,0 +1,92 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+	"user-management/internal/apperr"
+	"user-management/internal/config"
+	"user-management/internal/dtos"
+	"user-management/internal/models"
+	"user-management/internal/repository"
+	"user-management/internal/reqctx"
+
+	"github.com/google/uuid"
+)
+
+var ErrUnknownConsentPurpose = apperr.ErrValidation.WithMessage("Unknown consent purpose")
+
+type ConsentService interface {
+	// List returns the current decision for each purpose the user has decided on
+	List(ctx context.Context, userID, targetID uuid.UUID) ([]models.Consent, error)
+	// Record gives or withdraws consent for a purpose
+	Record(ctx context.Context, userID, targetID uuid.UUID, req *dtos.RecordConsentRequest) (*models.Consent, error)
+}
+
+type consentService struct {
+	repo     repository.ConsentRepository
+	audit    AuditService
+	purposes map[string]bool
+}
+
+func NewConsentService(cfg *config.ConsentConfig, repo repository.ConsentRepository, audit AuditService) ConsentService {
+	purposes := make(map[string]bool, len(cfg.Purposes))
+	for _, purpose := range cfg.Purposes {
+		if purpose = strings.TrimSpace(purpose); purpose != "" {
+			purposes[purpose] = true
+		}
+	}
+
+	return &consentService{
+		repo:     repo,
+		audit:    audit,
+		purposes: purposes,
+	}
+}
+
+func (s *consentService) List(ctx context.Context, userID, targetID uuid.UUID) ([]models.Consent, error) {
+	if userID != targetID {
+		return nil, ErrUnauthorized
+	}
+
+	return s.repo.ListCurrent(ctx, targetID)
+}
+
+func (s *consentService) Record(
+	ctx context.Context,
+	userID, targetID uuid.UUID,
+	req *dtos.RecordConsentRequest,
+) (*models.Consent, error) {
+	if userID != targetID {
+		return nil, ErrUnauthorized
+	}
+
+	// Consent is only valid when given by the user themselves
+	if reqctx.ImpersonatorFrom(ctx) != "" {
+		return nil, ErrImpersonationForbidden
+	}
+
+	if !s.purposes[req.Purpose] {
+		return nil, ErrUnknownConsentPurpose
+	}
+
+	consent := &models.Consent{
+		UserID:    targetID,
+		Purpose:   req.Purpose,
+		Version:   req.Version,
+		Granted:   *req.Granted,
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := s.repo.Create(ctx, consent); err != nil {
+		return nil, fmt.Errorf("failed to record consent: %w", err)
+	}
+
+	s.audit.Record(ctx, newAuditEvent(models.AuditConsentChanged, models.AuditSuccess, userID, targetID, models.JSONMap{
+		"purpose": consent.Purpose,
+		"version": consent.Version,
+		"granted": consent.Granted,
+	}))
+
+	return consent, nil
+}
@@ -0,0 +1,128 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"user-management/internal/config"
+	"user-management/internal/dtos"
+	"user-management/internal/models"
+	"user-management/internal/repository"
+	"user-management/internal/reqctx"
+
+	"github.com/google/uuid"
+)
+
+type fakeConsentRepository struct {
+	consents []models.Consent
+}
+
+func (r *fakeConsentRepository) Create(_ context.Context, consent *models.Consent) error {
+	r.consents = append(r.consents, *consent)
+	return nil
+}
+
+func (r *fakeConsentRepository) ListCurrent(_ context.Context, userID uuid.UUID) ([]models.Consent, error) {
+	latest := make(map[string]models.Consent)
+	for _, consent := range r.consents {
+		if consent.UserID == userID {
+			latest[consent.Purpose] = consent
+		}
+	}
+	result := make([]models.Consent, 0, len(latest))
+	for _, consent := range latest {
+		result = append(result, consent)
+	}
+	return result, nil
+}
+
+func (r *fakeConsentRepository) ListHistory(_ context.Context, userID uuid.UUID) ([]models.Consent, error) {
+	var result []models.Consent
+	for _, consent := range r.consents {
+		if consent.UserID == userID {
+			result = append(result, consent)
+		}
+	}
+	return result, nil
+}
+
+// recordingAudit keeps recorded events in memory
+type recordingAudit struct {
+	events []*models.AuditEvent
+}
+
+func (a *recordingAudit) Record(_ context.Context, event *models.AuditEvent) {
+	a.events = append(a.events, event)
+}
+
+func (a *recordingAudit) List(context.Context, repository.AuditFilter, int64, int) ([]models.AuditEvent, error) {
+	return nil, nil
+}
+
+func (a *recordingAudit) Verify(context.Context) (*dtos.AuditVerificationResponse, error) {
+	return nil, nil
+}
+
+func (a *recordingAudit) Pseudonym(value string) string {
+	return "pseudonym(" + value + ")"
+}
+
+func TestConsentService(t *testing.T) {
+	repo := &fakeConsentRepository{}
+	audit := &recordingAudit{}
+	svc := NewConsentService(&config.ConsentConfig{Purposes: []string{"terms", "marketing"}}, repo, audit)
+
+	ctx := context.Background()
+	userID := uuid.New()
+	granted, withdrawn := true, false
+
+	record := func(ctx context.Context, actorID uuid.UUID, purpose string, value *bool) error {
+		_, err := svc.Record(ctx, actorID, userID, &dtos.RecordConsentRequest{Purpose: purpose, Version: "2024-01", Granted: value})
+		return err
+	}
+
+	if err := record(ctx, userID, "marketing", &granted); err != nil {
+		t.Fatalf("grant: %v", err)
+	}
+	if err := record(ctx, userID, "marketing", &withdrawn); err != nil {
+		t.Fatalf("withdraw: %v", err)
+	}
+
+	current, err := svc.List(ctx, userID, userID)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(current) != 1 || current[0].Granted {
+		t.Fatalf("current consents = %+v, want marketing withdrawn", current)
+	}
+	if history, _ := repo.ListHistory(ctx, userID); len(history) != 2 {
+		t.Fatalf("history has %d decisions, want 2", len(history))
+	}
+	if len(audit.events) != 2 || audit.events[1].Action != models.AuditConsentChanged {
+		t.Fatalf("audit events = %d, want 2 consent changes", len(audit.events))
+	}
+
+	rejected := []struct {
+		name string
+		ctx  context.Context
+		err  error
+		run  func(ctx context.Context) error
+	}{
+		{"unknown purpose", ctx, ErrUnknownConsentPurpose, func(ctx context.Context) error {
+			return record(ctx, userID, "profiling", &granted)
+		}},
+		{"another user", ctx, ErrUnauthorized, func(ctx context.Context) error {
+			return record(ctx, uuid.New(), "terms", &granted)
+		}},
+		{"impersonation", reqctx.WithImpersonator(ctx, uuid.NewString()), ErrImpersonationForbidden, func(ctx context.Context) error {
+			return record(ctx, userID, "terms", &granted)
+		}},
+	}
+	for _, tc := range rejected {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := tc.run(tc.ctx); !errors.Is(err, tc.err) {
+				t.Fatalf("err = %v, want %v", err, tc.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,276 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+	"user-management/internal/apperr"
+	"user-management/internal/config"
+	"user-management/internal/models"
+	"user-management/internal/repository"
+	"user-management/internal/reqctx"
+	"user-management/internal/utils"
+	"user-management/pkg/logger"
+
+	"github.com/google/uuid"
+)
+
+const exportFormatVersion = 1
+
+// exportFailedReason is the public reason stored for a failed export; the cause is only logged
+const exportFailedReason = "export failed"
+
+var (
+	ErrExportNotFound    = apperr.ErrNotFound.WithMessage("Export not found")
+	ErrExportLinkInvalid = apperr.New(http.StatusForbidden, utils.ErrCodeInvalidToken, "Invalid download link")
+	ErrExportExpired     = apperr.New(http.StatusGone, utils.ErrCodeInvalidToken, "Download link has expired")
+)
+
+type DataExportService interface {
+	RequestExport(ctx context.Context, actorID, targetID uuid.UUID) (*models.DataExport, error)
+	GetExport(ctx context.Context, actorID, targetID, exportID uuid.UUID) (*models.DataExport, error)
+	DownloadURL(export *models.DataExport) string
+	Download(ctx context.Context, exportID uuid.UUID, expires, signature string) ([]byte, error)
+	Run(ctx context.Context)
+}
+
+// exportArchive is the JSON document handed to the user
+type exportArchive struct {
+	FormatVersion int                    `json:"format_version"`
+	GeneratedAt   time.Time              `json:"generated_at"`
+	UserID        uuid.UUID              `json:"user_id"`
+	Data          map[string]interface{} `json:"data"`
+}
+
+type dataExportService struct {
+	repo     repository.DataExportRepository
+	userRepo repository.UserRepository
+	sources  []ExportSource
+	cfg      *config.ExportConfig
+	logger   *logger.Logger
+	wake     chan struct{}
+}
+
+func NewDataExportService(
+	repo repository.DataExportRepository,
+	userRepo repository.UserRepository,
+	cfg *config.ExportConfig,
+	logger *logger.Logger,
+	sources ...ExportSource,
+) DataExportService {
+	return &dataExportService{
+		repo:     repo,
+		userRepo: userRepo,
+		sources:  sources,
+		cfg:      cfg,
+		logger:   logger,
+		wake:     make(chan struct{}, 1),
+	}
+}
+
+// RequestExport queues an export of the target's data. Users may export themselves, admins anyone.
+func (s *dataExportService) RequestExport(ctx context.Context, actorID, targetID uuid.UUID) (*models.DataExport, error) {
+	if err := s.authorize(ctx, actorID, targetID); err != nil {
+		return nil, err
+	}
+
+	target, err := s.userRepo.FindByID(ctx, targetID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+	if target == nil {
+		return nil, ErrUserNotFound
+	}
+
+	// Only one export runs per user at a time
+	existing, err := s.repo.FindInProgress(ctx, targetID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find export: %w", err)
+	}
+	if existing != nil {
+		return existing, nil
+	}
+
+	export := &models.DataExport{
+		UserID:      targetID,
+		RequestedBy: actorID,
+		Status:      models.ExportStatusPending,
+	}
+	if err := s.repo.Create(ctx, export); err != nil {
+		return nil, fmt.Errorf("failed to create export: %w", err)
+	}
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+
+	return export, nil
+}
+
+func (s *dataExportService) GetExport(ctx context.Context, actorID, targetID, exportID uuid.UUID) (*models.DataExport, error) {
+	if err := s.authorize(ctx, actorID, targetID); err != nil {
+		return nil, err
+	}
+
+	export, err := s.repo.FindByID(ctx, exportID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find export: %w", err)
+	}
+	if export == nil || export.UserID != targetID {
+		return nil, ErrExportNotFound
+	}
+
+	return export, nil
+}
+
+// DownloadURL returns a signed link valid until the export expires, or an empty string
+func (s *dataExportService) DownloadURL(export *models.DataExport) string {
+	if !export.Downloadable(time.Now()) {
+		return ""
+	}
+
+	expires := strconv.FormatInt(export.ExpiresAt.Unix(), 10)
+	query := url.Values{}
+	query.Set("expires", expires)
+	query.Set("signature", utils.Sign(s.cfg.SigningKey, downloadMessage(export.ID, expires)))
+
+	return fmt.Sprintf("%s/%s/download?%s", s.cfg.DownloadURL, export.ID, query.Encode())
+}
+
+// Download checks the link signature and returns the archive
+func (s *dataExportService) Download(ctx context.Context, exportID uuid.UUID, expires, signature string) ([]byte, error) {
+	if !utils.VerifySignature(s.cfg.SigningKey, downloadMessage(exportID, expires), signature) {
+		return nil, ErrExportLinkInvalid
+	}
+
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil {
+		return nil, ErrExportLinkInvalid
+	}
+	if time.Now().Unix() >= expiresAt {
+		return nil, ErrExportExpired
+	}
+
+	archive, err := s.repo.FindArchive(ctx, exportID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load export: %w", err)
+	}
+	if len(archive) == 0 {
+		return nil, ErrExportExpired
+	}
+
+	return archive, nil
+}
+
+// Run processes queued exports and drops expired archives until the context is cancelled
+func (s *dataExportService) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		if expired, err := s.repo.ExpireArchives(ctx, time.Now().UTC()); err != nil && ctx.Err() == nil {
+			s.logger.Error().Err(err).Msg("Failed to expire data exports")
+		} else if expired > 0 {
+			s.logger.Info().Int64("expired", expired).Msg("Expired data exports")
+		}
+
+		s.processPending(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.wake:
+		}
+	}
+}
+
+func (s *dataExportService) processPending(ctx context.Context) {
+	for ctx.Err() == nil {
+		export, err := s.repo.ClaimPending(ctx, time.Now().Add(-s.cfg.ClaimTimeout).UTC())
+		if err != nil {
+			if ctx.Err() == nil {
+				s.logger.Error().Err(err).Msg("Failed to claim data export")
+			}
+			return
+		}
+		if export == nil {
+			return
+		}
+
+		// An export that keeps taking its worker down must not be retried forever
+		if export.Attempts > s.cfg.MaxAttempts {
+			s.logger.Error().Str("export_id", export.ID.String()).Int("attempts", export.Attempts).Msg("Data export abandoned")
+			if err := s.repo.Fail(ctx, export.ID, exportFailedReason); err != nil {
+				s.logger.Error().Err(err).Str("export_id", export.ID.String()).Msg("Failed to mark data export as failed")
+			}
+			continue
+		}
+
+		if err := s.process(ctx, export); err != nil {
+			s.logger.Error().Err(err).Str("export_id", export.ID.String()).Msg("Data export failed")
+			if err := s.repo.Fail(ctx, export.ID, exportFailedReason); err != nil {
+				s.logger.Error().Err(err).Str("export_id", export.ID.String()).Msg("Failed to mark data export as failed")
+			}
+		}
+	}
+}
+
+func (s *dataExportService) process(ctx context.Context, export *models.DataExport) error {
+	archive := exportArchive{
+		FormatVersion: exportFormatVersion,
+		GeneratedAt:   time.Now().UTC(),
+		UserID:        export.UserID,
+		Data:          make(map[string]interface{}, len(s.sources)),
+	}
+
+	for _, source := range s.sources {
+		data, err := source.Collect(ctx, export.UserID)
+		if err != nil {
+			return fmt.Errorf("failed to collect %s: %w", source.Name(), err)
+		}
+		archive.Data[source.Name()] = data
+	}
+
+	body, err := json.MarshalIndent(archive, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode export: %w", err)
+	}
+
+	if err := s.repo.Complete(ctx, export.ID, body, time.Now().Add(s.cfg.LinkTTL).UTC()); err != nil {
+		return fmt.Errorf("failed to store export: %w", err)
+	}
+
+	s.logger.Info().Str("export_id", export.ID.String()).Str("user_id", export.UserID.String()).Msg("Data export completed")
+
+	return nil
+}
+
+// authorize allows users to act on their own data and admins on anyone's, but not while impersonating
+func (s *dataExportService) authorize(ctx context.Context, actorID, targetID uuid.UUID) error {
+	if reqctx.ImpersonatorFrom(ctx) != "" {
+		return ErrImpersonationForbidden
+	}
+	if actorID == targetID {
+		return nil
+	}
+
+	actor, err := s.userRepo.FindByID(ctx, actorID)
+	if err != nil {
+		return fmt.Errorf("failed to find user: %w", err)
+	}
+	if actor == nil || actor.Role != models.RoleAdmin {
+		return ErrUnauthorized
+	}
+
+	return nil
+}
+
+func downloadMessage(exportID uuid.UUID, expires string) string {
+	return "data-export:" + exportID.String() + ":" + expires
+}
@@ -0,0 +1,156 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+	"user-management/internal/config"
+	"user-management/internal/models"
+	"user-management/pkg/logger"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+func nopLogger() *logger.Logger {
+	log := zerolog.Nop()
+	return &logger.Logger{Logger: &log}
+}
+
+// fakeExportRepository hands out queued exports and records how each one ended
+type fakeExportRepository struct {
+	queue       []*models.DataExport
+	staleBefore time.Time
+	completed   map[uuid.UUID][]byte
+	failed      map[uuid.UUID]string
+}
+
+func newFakeExportRepository(queue ...*models.DataExport) *fakeExportRepository {
+	return &fakeExportRepository{
+		queue:     queue,
+		completed: make(map[uuid.UUID][]byte),
+		failed:    make(map[uuid.UUID]string),
+	}
+}
+
+func (r *fakeExportRepository) Create(context.Context, *models.DataExport) error { return nil }
+
+func (r *fakeExportRepository) FindByID(context.Context, uuid.UUID) (*models.DataExport, error) {
+	return nil, nil
+}
+
+func (r *fakeExportRepository) FindArchive(context.Context, uuid.UUID) ([]byte, error) {
+	return nil, nil
+}
+
+func (r *fakeExportRepository) FindInProgress(context.Context, uuid.UUID) (*models.DataExport, error) {
+	return nil, nil
+}
+
+func (r *fakeExportRepository) ClaimPending(_ context.Context, staleBefore time.Time) (*models.DataExport, error) {
+	r.staleBefore = staleBefore
+	if len(r.queue) == 0 {
+		return nil, nil
+	}
+	export := r.queue[0]
+	r.queue = r.queue[1:]
+	export.Attempts++
+	return export, nil
+}
+
+func (r *fakeExportRepository) Complete(_ context.Context, id uuid.UUID, archive []byte, _ time.Time) error {
+	r.completed[id] = archive
+	return nil
+}
+
+func (r *fakeExportRepository) Fail(_ context.Context, id uuid.UUID, reason string) error {
+	r.failed[id] = reason
+	return nil
+}
+
+func (r *fakeExportRepository) ExpireArchives(context.Context, time.Time) (int64, error) {
+	return 0, nil
+}
+
+// failingSource fails like a store that is down
+type failingSource struct{}
+
+func (failingSource) Name() string { return "sessions" }
+
+func (failingSource) Collect(context.Context, uuid.UUID) (interface{}, error) {
+	return nil, errors.New(`pq: relation "user_sessions" does not exist`)
+}
+
+type staticSource struct {
+	name string
+	data interface{}
+}
+
+func (s staticSource) Name() string { return s.name }
+
+func (s staticSource) Collect(context.Context, uuid.UUID) (interface{}, error) { return s.data, nil }
+
+func TestProcessPendingReclaimsAndAbandonsExports(t *testing.T) {
+	fresh := &models.DataExport{ID: uuid.New(), UserID: uuid.New()}
+	// Claimed three times already by workers that never finished
+	crashing := &models.DataExport{ID: uuid.New(), UserID: uuid.New(), Attempts: 3}
+
+	repo := newFakeExportRepository(fresh, crashing)
+	cfg := &config.ExportConfig{LinkTTL: time.Hour, ClaimTimeout: 15 * time.Minute, MaxAttempts: 3}
+	svc := &dataExportService{
+		repo:   repo,
+		cfg:    cfg,
+		logger: nopLogger(),
+		sources: []ExportSource{
+			staticSource{name: "identities", data: []exportedIdentity{{Provider: models.AuthProviderLDAP, Subject: "uid=ada"}}},
+			staticSource{name: "consents", data: []exportedConsent{{Purpose: "marketing", Version: "1", Granted: true}}},
+		},
+	}
+
+	before := time.Now()
+	svc.processPending(context.Background())
+
+	if age := before.Sub(repo.staleBefore); age < cfg.ClaimTimeout-time.Second || age > cfg.ClaimTimeout+time.Second {
+		t.Errorf("claims older than %v reclaimed, want %v", age, cfg.ClaimTimeout)
+	}
+
+	archive, ok := repo.completed[fresh.ID]
+	if !ok {
+		t.Fatalf("fresh export not completed, failed with %q", repo.failed[fresh.ID])
+	}
+	var document exportArchive
+	if err := json.Unmarshal(archive, &document); err != nil {
+		t.Fatal(err)
+	}
+	for _, section := range []string{"identities", "consents"} {
+		if _, ok := document.Data[section]; !ok {
+			t.Errorf("archive has no %s section", section)
+		}
+	}
+
+	if _, ok := repo.completed[crashing.ID]; ok {
+		t.Error("export past its attempts was processed again")
+	}
+	if repo.failed[crashing.ID] == "" {
+		t.Error("export past its attempts was not marked failed")
+	}
+}
+
+func TestProcessPendingStoresOnlyAPublicFailureReason(t *testing.T) {
+	export := &models.DataExport{ID: uuid.New(), UserID: uuid.New()}
+	repo := newFakeExportRepository(export)
+	svc := &dataExportService{
+		repo:    repo,
+		cfg:     &config.ExportConfig{LinkTTL: time.Hour, ClaimTimeout: time.Minute, MaxAttempts: 3},
+		logger:  nopLogger(),
+		sources: []ExportSource{failingSource{}},
+	}
+
+	svc.processPending(context.Background())
+
+	if reason := repo.failed[export.ID]; reason != exportFailedReason {
+		t.Fatalf("stored reason = %q, want %q", reason, exportFailedReason)
+	}
+}
@@ -0,0 +1,225 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+	"user-management/internal/apperr"
+	"user-management/internal/config"
+	"user-management/internal/models"
+	"user-management/internal/repository"
+	"user-management/internal/utils"
+	"user-management/pkg/cache"
+	"user-management/pkg/mailer"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrEmailInUse         = apperr.ErrConflict.WithMessage("Email already in use")
+	ErrEmailChangeInvalid = apperr.New(http.StatusBadRequest, utils.ErrCodeInvalidToken, "Invalid or expired link")
+)
+
+type EmailChangeService interface {
+	// Request records a pending change and emails the confirmation and cancel links
+	Request(ctx context.Context, user *models.User, newEmail string) (*models.EmailChange, error)
+	Pending(ctx context.Context, userID uuid.UUID) (*models.EmailChange, error)
+	Confirm(ctx context.Context, token string) (*models.User, error)
+	// Cancel withdraws a pending change, or reverts a confirmed one and signs the account out everywhere
+	Cancel(ctx context.Context, token string) error
+}
+
+type emailChangeService struct {
+	cfg            *config.EmailChangeConfig
+	repo           repository.EmailChangeRepository
+	userRepo       repository.UserRepository
+	sessionService SessionService
+	sender         mailer.Sender
+	cache          cache.Cache
+	emails         EmailPolicy
+}
+
+func NewEmailChangeService(
+	cfg *config.EmailChangeConfig,
+	repo repository.EmailChangeRepository,
+	userRepo repository.UserRepository,
+	sessionService SessionService,
+	sender mailer.Sender,
+	cache cache.Cache,
+	emails EmailPolicy,
+) EmailChangeService {
+	return &emailChangeService{
+		cfg:            cfg,
+		repo:           repo,
+		userRepo:       userRepo,
+		sessionService: sessionService,
+		sender:         sender,
+		cache:          cache,
+		emails:         emails,
+	}
+}
+
+func (s *emailChangeService) Request(ctx context.Context, user *models.User, newEmail string) (*models.EmailChange, error) {
+	canonical := s.emails.Canonical(newEmail)
+	taken, err := s.userRepo.EmailTaken(ctx, newEmail, canonical, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check email: %w", err)
+	}
+	if taken {
+		return nil, ErrEmailInUse
+	}
+
+	confirmToken, err := utils.GenerateToken()
+	if err != nil {
+		return nil, err
+	}
+	cancelToken, err := utils.GenerateToken()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	change := &models.EmailChange{
+		UserID:            user.ID,
+		OldEmail:          user.Email,
+		NewEmail:          newEmail,
+		CanonicalOldEmail: user.CanonicalEmail,
+		CanonicalNewEmail: canonical,
+		ConfirmTokenHash:  utils.HashToken(confirmToken),
+		CancelTokenHash:   utils.HashToken(cancelToken),
+		ExpiresAt:         now.Add(s.cfg.TTL),
+		RevertUntil:       now.Add(s.cfg.RevertWindow),
+	}
+
+	if err := s.repo.Create(ctx, change); err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, ErrEmailInUse
+		}
+		return nil, fmt.Errorf("failed to store email change: %w", err)
+	}
+
+	confirmLink, err := withToken(s.cfg.ConfirmURL, confirmToken)
+	if err != nil {
+		return nil, err
+	}
+	cancelLink, err := withToken(s.cfg.CancelURL, cancelToken)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.sender.Send(ctx, mailer.Message{
+		To:      newEmail,
+		Subject: "Confirm your new email address",
+		Body: fmt.Sprintf(
+			"Use the link below to confirm %s as the new email address of your account. "+
+				"It expires in %s.\n\n%s\n\nIf you did not request this, you can ignore this email.",
+			newEmail, s.cfg.TTL, confirmLink,
+		),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to send confirmation: %w", err)
+	}
+
+	err = s.sender.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Your email address is being changed",
+		Body: fmt.Sprintf(
+			"Someone asked to change the email address of your account to %s.\n\n"+
+				"If this was not you, use the link below to cancel the change. It also undoes the change "+
+				"if it was already confirmed and signs out every device. It works for %s.\n\n%s",
+			newEmail, s.cfg.RevertWindow, cancelLink,
+		),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to send change notice: %w", err)
+	}
+
+	return change, nil
+}
+
+func (s *emailChangeService) Pending(ctx context.Context, userID uuid.UUID) (*models.EmailChange, error) {
+	return s.repo.FindPendingByUser(ctx, userID)
+}
+
+func (s *emailChangeService) Confirm(ctx context.Context, token string) (*models.User, error) {
+	change, err := s.repo.FindByConfirmTokenHash(ctx, utils.HashToken(token))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find email change: %w", err)
+	}
+	if change == nil || !change.Pending(time.Now()) {
+		return nil, ErrEmailChangeInvalid
+	}
+
+	confirmed, err := s.repo.Confirm(ctx, change, s.emails.Canonical(change.NewEmail))
+	if err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, ErrEmailInUse
+		}
+		return nil, fmt.Errorf("failed to confirm email change: %w", err)
+	}
+	if !confirmed {
+		return nil, ErrEmailChangeInvalid
+	}
+
+	_ = s.cache.Delete(ctx, userCacheKey(change.UserID))
+
+	return s.userRepo.FindByID(ctx, change.UserID)
+}
+
+func (s *emailChangeService) Cancel(ctx context.Context, token string) error {
+	change, err := s.repo.FindByCancelTokenHash(ctx, utils.HashToken(token))
+	if err != nil {
+		return fmt.Errorf("failed to find email change: %w", err)
+	}
+	if change == nil {
+		return ErrEmailChangeInvalid
+	}
+
+	now := time.Now()
+	switch {
+	case change.ConfirmedAt == nil && change.CancelledAt == nil:
+		cancelled, err := s.repo.Cancel(ctx, change.ID)
+		if err != nil {
+			return fmt.Errorf("failed to cancel email change: %w", err)
+		}
+		if !cancelled {
+			return ErrEmailChangeInvalid
+		}
+		return nil
+
+	case change.Revertible(now):
+		reverted, err := s.repo.Revert(ctx, change, s.emails.Canonical(change.OldEmail))
+		if err != nil {
+			return fmt.Errorf("failed to revert email change: %w", err)
+		}
+		if !reverted {
+			return ErrEmailChangeInvalid
+		}
+
+		// Whoever confirmed the change may hold the account, so sign it out everywhere
+		_ = s.cache.Delete(ctx, userCacheKey(change.UserID))
+		if err := s.sessionService.RevokeAll(ctx, change.UserID); err != nil {
+			return fmt.Errorf("failed to revoke sessions: %w", err)
+		}
+		return nil
+	}
+
+	return ErrEmailChangeInvalid
+}
+
+func withToken(base, token string) (string, error) {
+	link, err := url.Parse(base)
+	if err != nil {
+		return "", fmt.Errorf("invalid link URL: %w", err)
+	}
+
+	query := link.Query()
+	query.Set("token", token)
+	link.RawQuery = query.Encode()
+
+	return link.String(), nil
+}
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"user-management/internal/apperr"
+	"user-management/internal/config"
+	"user-management/pkg/emailaddr"
+)
+
+var (
+	ErrInvalidEmail          = apperr.ErrValidation.WithMessage("Invalid email address")
+	ErrEmailDomainNotAllowed = apperr.ErrValidation.WithMessage("Email domain is not allowed")
+	ErrDisposableEmail       = apperr.ErrValidation.WithMessage("Disposable email addresses are not allowed")
+	ErrDirectoryManagedEmail = apperr.ErrValidation.WithMessage("Accounts in this email domain sign in through the company directory")
+)
+
+// EmailPolicy normalizes addresses and decides which domains may be used for local accounts
+type EmailPolicy interface {
+	Normalize(email string) (emailaddr.Address, error)
+	// Canonical returns the canonical form of a stored address, falling back to the lowercased
+	// address for ones Normalize rejects, such as directory addresses without a dot in the domain
+	Canonical(email string) string
+	// CheckDomain applies the allowlist, the denylist and the disposable provider list
+	CheckDomain(addr emailaddr.Address) error
+}
+
+type emailPolicy struct {
+	normalizer *emailaddr.Normalizer
+	allowed    emailaddr.DomainSet
+	denied     emailaddr.DomainSet
+	disposable emailaddr.DomainSet
+}
+
+func NewEmailPolicy(cfg *config.EmailConfig) (EmailPolicy, error) {
+	rules := make(map[string]emailaddr.Rule, len(cfg.CanonicalRules))
+	for domain, spec := range cfg.CanonicalRules {
+		rule, err := emailaddr.ParseRule(spec)
+		if err != nil {
+			return nil, fmt.Errorf("invalid EMAIL_CANONICAL_RULES entry for %s: %w", domain, err)
+		}
+		rules[domain] = rule
+	}
+
+	policy := &emailPolicy{
+		normalizer: emailaddr.NewNormalizer(rules),
+		allowed:    emailaddr.NewDomainSet(cfg.AllowedDomains),
+		denied:     emailaddr.NewDomainSet(cfg.DeniedDomains),
+	}
+
+	if cfg.BlockDisposable {
+		policy.disposable = emailaddr.DisposableDomains()
+		if cfg.DisposableDomainsFile != "" {
+			file, err := os.Open(cfg.DisposableDomainsFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to open disposable domains file: %w", err)
+			}
+			defer file.Close()
+
+			if policy.disposable, err = emailaddr.ReadDomainSet(file); err != nil {
+				return nil, fmt.Errorf("failed to read disposable domains file: %w", err)
+			}
+		}
+	}
+
+	return policy, nil
+}
+
+func (p *emailPolicy) Normalize(email string) (emailaddr.Address, error) {
+	addr, err := p.normalizer.Normalize(email)
+	if err != nil {
+		return emailaddr.Address{}, ErrInvalidEmail
+	}
+	return addr, nil
+}
+
+func (p *emailPolicy) Canonical(email string) string {
+	if addr, err := p.normalizer.Normalize(email); err == nil {
+		return addr.Canonical
+	}
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// CheckDomain lets explicitly allowed domains through even when they are listed as disposable
+func (p *emailPolicy) CheckDomain(addr emailaddr.Address) error {
+	if p.denied.Contains(addr.Domain) {
+		return ErrEmailDomainNotAllowed
+	}
+	if len(p.allowed) > 0 {
+		if !p.allowed.Contains(addr.Domain) {
+			return ErrEmailDomainNotAllowed
+		}
+		return nil
+	}
+	if p.disposable.Contains(addr.Domain) {
+		return ErrDisposableEmail
+	}
+	return nil
+}
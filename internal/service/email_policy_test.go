@@ -0,0 +1,91 @@
+package service
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"user-management/internal/config"
+)
+
+func TestEmailPolicyCheckDomain(t *testing.T) {
+	tests := []struct {
+		name  string
+		cfg   config.EmailConfig
+		email string
+		want  error
+	}{
+		{"no lists", config.EmailConfig{}, "ada@mailinator.com", nil},
+		{"disposable blocked", config.EmailConfig{BlockDisposable: true}, "ada@mailinator.com", ErrDisposableEmail},
+		{"disposable subdomain blocked", config.EmailConfig{BlockDisposable: true}, "ada@eu.mailinator.com", ErrDisposableEmail},
+		{"ordinary domain passes", config.EmailConfig{BlockDisposable: true}, "ada@example.com", nil},
+		{"denied", config.EmailConfig{DeniedDomains: []string{"example.com"}}, "ada@mail.example.com", ErrEmailDomainNotAllowed},
+		{"not on allowlist", config.EmailConfig{AllowedDomains: []string{"corp.example"}}, "ada@example.com", ErrEmailDomainNotAllowed},
+		{"on allowlist", config.EmailConfig{AllowedDomains: []string{"corp.example"}}, "ada@eu.corp.example", nil},
+		{"allowlist overrides disposable", config.EmailConfig{AllowedDomains: []string{"mailinator.com"}, BlockDisposable: true},
+			"ada@mailinator.com", nil},
+		{"denylist overrides allowlist", config.EmailConfig{AllowedDomains: []string{"example.com"}, DeniedDomains: []string{"eu.example.com"}},
+			"ada@eu.example.com", ErrEmailDomainNotAllowed},
+		{"unicode allowlist entry", config.EmailConfig{AllowedDomains: []string{"bücher.example"}}, "ada@xn--bcher-kva.example", nil},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			emails, err := NewEmailPolicy(&tc.cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			addr, err := emails.Normalize(tc.email)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := emails.CheckDomain(addr); !errors.Is(err, tc.want) {
+				t.Fatalf("CheckDomain(%s) = %v, want %v", tc.email, err, tc.want)
+			}
+		})
+	}
+}
+
+func TestEmailPolicyDisposableDomainsFileReplacesBundledList(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "disposable.txt")
+	if err := os.WriteFile(path, []byte("# local list\nthrowaway.example\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	emails, err := NewEmailPolicy(&config.EmailConfig{BlockDisposable: true, DisposableDomainsFile: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for email, want := range map[string]error{
+		"ada@throwaway.example": ErrDisposableEmail,
+		"ada@mailinator.com":    nil,
+	} {
+		addr, _ := emails.Normalize(email)
+		if err := emails.CheckDomain(addr); !errors.Is(err, want) {
+			t.Errorf("CheckDomain(%s) = %v, want %v", email, err, want)
+		}
+	}
+}
+
+func TestEmailPolicyRejectsInvalidRules(t *testing.T) {
+	_, err := NewEmailPolicy(&config.EmailConfig{CanonicalRules: map[string]string{"gmail.com": "dots,hyphens"}})
+	if err == nil {
+		t.Fatal("NewEmailPolicy accepted an unknown rule option")
+	}
+}
+
+func TestEmailPolicyCanonical(t *testing.T) {
+	emails := gmailPolicy(t)
+
+	for email, want := range map[string]string{
+		"J.Doe+news@GoogleMail.com": "jdoe@gmail.com",
+		" Ada@Example.com ":         "ada@example.com",
+		"Ada@CORP":                  "ada@corp",
+	} {
+		if got := emails.Canonical(email); got != want {
+			t.Errorf("Canonical(%q) = %q, want %q", email, got, want)
+		}
+	}
+	if _, err := emails.Normalize("Ada@CORP"); !errors.Is(err, ErrInvalidEmail) {
+		t.Errorf("Normalize accepted a domain without a dot: %v", err)
+	}
+}
@@ -0,0 +1,32 @@
+package service
+
+import (
+	"net/http"
+	"user-management/internal/apperr"
+	"user-management/internal/utils"
+)
+
+var (
+	// ErrUnauthorized means the caller may not act on the target resource
+	ErrUnauthorized       = apperr.ErrForbidden
+	ErrUserNotFound       = apperr.ErrNotFound.WithMessage("User not found")
+	ErrInvalidCredentials = apperr.New(http.StatusUnauthorized, utils.ErrCodeAuthFailed, "Authentication failed")
+
+	ErrImpersonationForbidden = apperr.ErrForbidden.WithMessage("This action is not allowed while impersonating")
+	ErrCannotImpersonate      = apperr.ErrForbidden.WithMessage("This user cannot be impersonated")
+
+	ErrAccountDisabled         = ErrInvalidCredentials.WithMessage("Account is disabled")
+	ErrAccountSuspended        = ErrInvalidCredentials.WithMessage("Account is suspended")
+	ErrAccountPending          = ErrInvalidCredentials.WithMessage("Account is pending activation")
+	ErrProviderMismatch        = ErrInvalidCredentials.WithMessage("Account uses a different sign-in method")
+	ErrRoleChanged             = apperr.ErrUnauthorized.WithMessage("Your role has changed, please sign in again")
+	ErrInvalidStatusTransition = apperr.New(http.StatusConflict, utils.ErrCodeInvalidStatusTransition, "The account cannot move to this status")
+	ErrCannotChangeOwnStatus   = apperr.ErrForbidden.WithMessage("You cannot change the status of your own account")
+
+	ErrAttributeDefinitionNotFound = apperr.ErrNotFound.WithMessage("Attribute definition not found")
+	ErrInvalidAttributeKey         = apperr.ErrValidation.WithMessage("Invalid attribute key")
+	ErrInvalidAttributeSchema      = apperr.ErrValidation.WithMessage("Invalid attribute schema")
+	ErrUnknownAttribute            = apperr.ErrValidation.WithMessage("Unknown attribute")
+	ErrAttributeForbidden          = apperr.ErrForbidden.WithMessage("You don't have permission to access this attribute")
+	ErrInvalidAttribute            = apperr.ErrValidation.WithMessage("Invalid attribute value")
+)
@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+	"user-management/internal/config"
+	"user-management/internal/models"
+	"user-management/internal/repository"
+	"user-management/pkg/events"
+	"user-management/pkg/logger"
+)
+
+// eventSchemaVersion versions the data of user events; it is part of the CloudEvents type,
+// e.g. "user.created.v1", and changes whenever the data changes incompatibly
+const eventSchemaVersion = "v1"
+
+// EventRelay publishes outbox events to the message broker, in order and at least once
+type EventRelay struct {
+	repo      repository.OutboxRepository
+	publisher events.EventPublisher
+	cfg       *config.EventsConfig
+	logger    *logger.Logger
+}
+
+func NewEventRelay(
+	repo repository.OutboxRepository,
+	publisher events.EventPublisher,
+	cfg *config.EventsConfig,
+	logger *logger.Logger,
+) *EventRelay {
+	return &EventRelay{
+		repo:      repo,
+		publisher: publisher,
+		cfg:       cfg,
+		logger:    logger,
+	}
+}
+
+// Run publishes on every interval until the context is cancelled
+func (r *EventRelay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := r.PublishPending(ctx); err != nil && ctx.Err() == nil {
+			r.logger.Error().Err(err).Str("driver", r.cfg.Driver).Msg("Failed to publish events")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// PublishPending publishes every unpublished event. Events are claimed a batch at a time and published
+// outside any transaction; an event that fails is retried on the next run, and so are the ones after it.
+func (r *EventRelay) PublishPending(ctx context.Context) error {
+	for ctx.Err() == nil {
+		claimed, err := r.repo.ClaimPending(ctx, r.cfg.BatchSize, r.cfg.PublishLease)
+		if err != nil || len(claimed) == 0 {
+			return err
+		}
+
+		published, err := r.publish(ctx, claimed)
+		if err != nil || published < r.cfg.BatchSize {
+			return err
+		}
+	}
+	return ctx.Err()
+}
+
+// publish sends claimed events in order until one fails and records the outcome. It gives up when the
+// lease runs out, since another instance may claim the events after that.
+func (r *EventRelay) publish(ctx context.Context, claimed []models.OutboxEvent) (int, error) {
+	leaseCtx, cancel := context.WithTimeout(ctx, r.cfg.PublishLease)
+	defer cancel()
+
+	var published, unpublished []int64
+	var publishErr error
+	for i := range claimed {
+		if publishErr == nil {
+			publishErr = r.publishOne(leaseCtx, &claimed[i])
+		}
+		if publishErr != nil {
+			unpublished = append(unpublished, claimed[i].ID)
+			continue
+		}
+		published = append(published, claimed[i].ID)
+	}
+
+	// The events have been handed to the broker, so their state is recorded even if the run is cancelled
+	recordCtx := context.WithoutCancel(ctx)
+	if err := r.repo.MarkPublished(recordCtx, published); err != nil {
+		return 0, err
+	}
+	if err := r.repo.ReleaseClaims(recordCtx, unpublished); err != nil && publishErr == nil {
+		publishErr = err
+	}
+	return len(published), publishErr
+}
+
+func (r *EventRelay) publishOne(ctx context.Context, event *models.OutboxEvent) error {
+	envelope, err := r.envelope(event)
+	if err != nil {
+		return err
+	}
+	return r.publisher.Publish(ctx, envelope)
+}
+
+func (r *EventRelay) envelope(event *models.OutboxEvent) (events.Event, error) {
+	data, err := json.Marshal(event.Payload)
+	if err != nil {
+		return events.Event{}, err
+	}
+
+	return events.Event{
+		SpecVersion:     events.SpecVersion,
+		ID:              event.EventID.String(),
+		Source:          r.cfg.Source,
+		Type:            event.Type + "." + eventSchemaVersion,
+		Subject:         event.AggregateID.String(),
+		Time:            event.CreatedAt.UTC(),
+		DataContentType: "application/json",
+		Data:            data,
+	}, nil
+}
@@ -0,0 +1,189 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+	"user-management/internal/config"
+	"user-management/internal/models"
+	"user-management/pkg/events"
+
+	"github.com/google/uuid"
+)
+
+// memoryOutbox implements the claim protocol of OutboxRepository in memory
+type memoryOutbox struct {
+	events       []models.OutboxEvent
+	published    map[int64]bool
+	claimedUntil map[int64]time.Time
+	// claiming is set while ClaimPending runs, to check that nothing is published inside the claim
+	claiming bool
+}
+
+func newMemoryOutbox(count int) *memoryOutbox {
+	outbox := &memoryOutbox{published: make(map[int64]bool), claimedUntil: make(map[int64]time.Time)}
+	for i := 1; i <= count; i++ {
+		outbox.events = append(outbox.events, models.OutboxEvent{
+			ID:          int64(i),
+			EventID:     uuid.New(),
+			Type:        models.EventUserCreated,
+			AggregateID: uuid.New(),
+			Payload:     models.JSONMap{"n": i},
+			CreatedAt:   time.Now(),
+		})
+	}
+	return outbox
+}
+
+func (o *memoryOutbox) ClaimPending(_ context.Context, limit int, lease time.Duration) ([]models.OutboxEvent, error) {
+	o.claiming = true
+	defer func() { o.claiming = false }()
+
+	now := time.Now()
+	for id, until := range o.claimedUntil {
+		if !o.published[id] && until.After(now) {
+			return nil, nil
+		}
+	}
+
+	var claimed []models.OutboxEvent
+	for _, event := range o.events {
+		if !o.published[event.ID] && len(claimed) < limit {
+			o.claimedUntil[event.ID] = now.Add(lease)
+			claimed = append(claimed, event)
+		}
+	}
+	return claimed, nil
+}
+
+func (o *memoryOutbox) MarkPublished(_ context.Context, ids []int64) error {
+	for _, id := range ids {
+		o.published[id] = true
+		delete(o.claimedUntil, id)
+	}
+	return nil
+}
+
+func (o *memoryOutbox) ReleaseClaims(_ context.Context, ids []int64) error {
+	for _, id := range ids {
+		delete(o.claimedUntil, id)
+	}
+	return nil
+}
+
+func (o *memoryOutbox) Prune(context.Context, time.Time, bool, int) (int64, error) {
+	return 0, nil
+}
+
+// flakyPublisher fails the event with the given aggregate once and passes everything else on
+type flakyPublisher struct {
+	*events.MemoryPublisher
+	outbox  *memoryOutbox
+	failing string
+}
+
+func (p *flakyPublisher) Publish(ctx context.Context, event events.Event) error {
+	if p.outbox.claiming {
+		return errors.New("published while claiming")
+	}
+	if event.Subject == p.failing {
+		p.failing = ""
+		return errors.New("broker unavailable")
+	}
+	return p.MemoryPublisher.Publish(ctx, event)
+}
+
+func newTestRelay(outbox *memoryOutbox, publisher events.EventPublisher) *EventRelay {
+	return NewEventRelay(outbox, publisher, &config.EventsConfig{
+		Driver:       "memory",
+		Source:       "/test",
+		BatchSize:    2,
+		PublishLease: time.Minute,
+	}, nopLogger())
+}
+
+func subjects(published []events.Event) []string {
+	result := make([]string, len(published))
+	for i, event := range published {
+		result[i] = event.Subject
+	}
+	return result
+}
+
+func TestEventRelayPublishesInOrder(t *testing.T) {
+	outbox := newMemoryOutbox(5)
+	publisher := &flakyPublisher{MemoryPublisher: events.NewMemoryPublisher(), outbox: outbox}
+
+	if err := newTestRelay(outbox, publisher).PublishPending(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	published := publisher.Events()
+	if len(published) != 5 {
+		t.Fatalf("published %d events, want 5", len(published))
+	}
+	for i, event := range published {
+		source := outbox.events[i]
+		if event.ID != source.EventID.String() || event.Subject != source.AggregateID.String() {
+			t.Errorf("event %d = %s, want %s", i, event.ID, source.EventID)
+		}
+		if event.Type != "user.created.v1" || event.Source != "/test" || string(event.Data) != fmt.Sprintf(`{"n":%d}`, i+1) {
+			t.Errorf("event %d = %+v", i, event)
+		}
+	}
+	if len(outbox.published) != 5 || len(outbox.claimedUntil) != 0 {
+		t.Errorf("published = %v, claims = %v", outbox.published, outbox.claimedUntil)
+	}
+}
+
+func TestEventRelayRetriesFromTheFailedEvent(t *testing.T) {
+	outbox := newMemoryOutbox(4)
+	publisher := &flakyPublisher{
+		MemoryPublisher: events.NewMemoryPublisher(),
+		outbox:          outbox,
+		failing:         outbox.events[2].AggregateID.String(),
+	}
+	relay := newTestRelay(outbox, publisher)
+
+	if err := relay.PublishPending(context.Background()); err == nil {
+		t.Fatal("expected the broker error")
+	}
+	if len(outbox.published) != 2 || len(outbox.claimedUntil) != 0 {
+		t.Fatalf("after failure: published = %v, claims = %v", outbox.published, outbox.claimedUntil)
+	}
+
+	if err := relay.PublishPending(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	got := subjects(publisher.Events())
+	for i, event := range outbox.events {
+		if i >= len(got) || got[i] != event.AggregateID.String() {
+			t.Fatalf("published %v, want the events in order", got)
+		}
+	}
+}
+
+func TestEventRelayWaitsForAnotherInstancesClaim(t *testing.T) {
+	outbox := newMemoryOutbox(3)
+	outbox.claimedUntil[1] = time.Now().Add(time.Minute)
+	publisher := &flakyPublisher{MemoryPublisher: events.NewMemoryPublisher(), outbox: outbox}
+	relay := newTestRelay(outbox, publisher)
+
+	if err := relay.PublishPending(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(publisher.Events()) != 0 {
+		t.Fatalf("published %d events during another instance's claim", len(publisher.Events()))
+	}
+
+	// An expired claim no longer blocks publishing
+	outbox.claimedUntil[1] = time.Now().Add(-time.Second)
+	if err := relay.PublishPending(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(publisher.Events()) != 3 {
+		t.Fatalf("published %d events after the claim expired, want 3", len(publisher.Events()))
+	}
+}
@@ -0,0 +1,241 @@
+package service
+
+import (
+	"context"
+	"time"
+	"user-management/internal/models"
+	"user-management/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// ExportSource contributes one section of a user's data export. Stores that hold
+// personal data register a source so the export stays complete as the schema grows.
+type ExportSource interface {
+	Name() string
+	Collect(ctx context.Context, userID uuid.UUID) (interface{}, error)
+}
+
+type profileSource struct {
+	repo repository.UserRepository
+}
+
+func NewProfileExportSource(repo repository.UserRepository) ExportSource {
+	return &profileSource{repo: repo}
+}
+
+func (s *profileSource) Name() string {
+	return "profile"
+}
+
+func (s *profileSource) Collect(ctx context.Context, userID uuid.UUID) (interface{}, error) {
+	user, err := s.repo.FindByID(ctx, userID)
+	if err != nil || user == nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+type exportedIdentity struct {
+	Provider   string    `json:"provider"`
+	Issuer     string    `json:"issuer"`
+	Subject    string    `json:"subject"`
+	LinkedAt   time.Time `json:"linked_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+}
+
+type identitySource struct {
+	repo repository.UserRepository
+}
+
+// NewIdentityExportSource exports the external accounts linked to the user
+func NewIdentityExportSource(repo repository.UserRepository) ExportSource {
+	return &identitySource{repo: repo}
+}
+
+func (s *identitySource) Name() string {
+	return "identities"
+}
+
+func (s *identitySource) Collect(ctx context.Context, userID uuid.UUID) (interface{}, error) {
+	identities, err := s.repo.ListIdentities(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]exportedIdentity, 0, len(identities))
+	for _, identity := range identities {
+		result = append(result, exportedIdentity{
+			Provider:   identity.Provider,
+			Issuer:     identity.Issuer,
+			Subject:    identity.Subject,
+			LinkedAt:   identity.CreatedAt,
+			LastUsedAt: identity.LastUsedAt,
+		})
+	}
+	return result, nil
+}
+
+type exportedConsent struct {
+	Purpose   string    `json:"purpose"`
+	Version   string    `json:"version"`
+	Granted   bool      `json:"granted"`
+	DecidedAt time.Time `json:"decided_at"`
+}
+
+type consentSource struct {
+	repo repository.ConsentRepository
+}
+
+// NewConsentExportSource exports every consent the user gave or withdrew
+func NewConsentExportSource(repo repository.ConsentRepository) ExportSource {
+	return &consentSource{repo: repo}
+}
+
+func (s *consentSource) Name() string {
+	return "consents"
+}
+
+func (s *consentSource) Collect(ctx context.Context, userID uuid.UUID) (interface{}, error) {
+	consents, err := s.repo.ListHistory(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]exportedConsent, 0, len(consents))
+	for _, consent := range consents {
+		result = append(result, exportedConsent{
+			Purpose:   consent.Purpose,
+			Version:   consent.Version,
+			Granted:   consent.Granted,
+			DecidedAt: consent.CreatedAt,
+		})
+	}
+	return result, nil
+}
+
+type exportedSession struct {
+	ID         uuid.UUID  `json:"id"`
+	UserAgent  string     `json:"user_agent"`
+	IP         string     `json:"ip"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastSeenAt time.Time  `json:"last_seen_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+type exportedLogin struct {
+	At        time.Time `json:"at"`
+	IP        string    `json:"ip"`
+	UserAgent string    `json:"user_agent"`
+}
+
+type sessionSource struct {
+	repo repository.SessionRepository
+}
+
+// NewSessionExportSource exports active sessions
+func NewSessionExportSource(repo repository.SessionRepository) ExportSource {
+	return &sessionSource{repo: repo}
+}
+
+func (s *sessionSource) Name() string {
+	return "sessions"
+}
+
+func (s *sessionSource) Collect(ctx context.Context, userID uuid.UUID) (interface{}, error) {
+	sessions, err := s.repo.ListActiveByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]exportedSession, 0, len(sessions))
+	for _, session := range sessions {
+		result = append(result, exportedSession{
+			ID:         session.ID,
+			UserAgent:  session.UserAgent,
+			IP:         session.IP,
+			CreatedAt:  session.CreatedAt,
+			LastSeenAt: session.LastSeenAt,
+			ExpiresAt:  session.ExpiresAt,
+			RevokedAt:  session.RevokedAt,
+		})
+	}
+	return result, nil
+}
+
+type loginHistorySource struct {
+	repo repository.SessionRepository
+}
+
+// NewLoginHistoryExportSource exports every sign-in, each of which started a session
+func NewLoginHistoryExportSource(repo repository.SessionRepository) ExportSource {
+	return &loginHistorySource{repo: repo}
+}
+
+func (s *loginHistorySource) Name() string {
+	return "login_history"
+}
+
+func (s *loginHistorySource) Collect(ctx context.Context, userID uuid.UUID) (interface{}, error) {
+	sessions, err := s.repo.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]exportedLogin, 0, len(sessions))
+	for _, session := range sessions {
+		result = append(result, exportedLogin{
+			At:        session.CreatedAt,
+			IP:        session.IP,
+			UserAgent: session.UserAgent,
+		})
+	}
+	return result, nil
+}
+
+type exportedAuditEvent struct {
+	Action    string         `json:"action"`
+	Outcome   string         `json:"outcome"`
+	IP        string         `json:"ip"`
+	UserAgent string         `json:"user_agent"`
+	Details   models.JSONMap `json:"details"`
+	At        time.Time      `json:"at"`
+}
+
+type auditSource struct {
+	repo repository.AuditRepository
+}
+
+// NewAuditExportSource exports the audit events the user performed or was the target of
+func NewAuditExportSource(repo repository.AuditRepository) ExportSource {
+	return &auditSource{repo: repo}
+}
+
+func (s *auditSource) Name() string {
+	return "audit_log"
+}
+
+func (s *auditSource) Collect(ctx context.Context, userID uuid.UUID) (interface{}, error) {
+	events, err := s.repo.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]exportedAuditEvent, 0, len(events))
+	for _, event := range events {
+		exported := exportedAuditEvent{
+			Action:  event.Action,
+			Outcome: event.Outcome,
+			Details: event.Details,
+			At:      event.CreatedAt,
+		}
+		// Client details of actions taken by someone else, such as an admin, belong to them
+		if event.ActorID != nil && *event.ActorID == userID {
+			exported.IP = event.IP
+			exported.UserAgent = event.UserAgent
+		}
+		result = append(result, exported)
+	}
+	return result, nil
+}
@@ -0,0 +1,172 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+	"user-management/internal/apperr"
+	"user-management/internal/auth"
+	"user-management/internal/config"
+	"user-management/internal/dtos"
+	"user-management/internal/models"
+	"user-management/internal/repository"
+	"user-management/internal/utils"
+	"user-management/pkg/cache"
+	"user-management/pkg/mailer"
+)
+
+var (
+	ErrMagicLinkRateLimited = apperr.ErrRateLimited.WithMessage("Too many sign-in link requests, try again later")
+	ErrMagicLinkInvalid     = apperr.New(http.StatusUnauthorized, utils.ErrCodeInvalidToken, "Invalid or expired sign-in link")
+)
+
+type MagicLinkService interface {
+	// Request emails a sign-in link and returns the browser nonce the link is bound to, if any
+	Request(ctx context.Context, email string) (string, error)
+	Verify(ctx context.Context, token, browserNonce string) (*dtos.SignInResponse, error)
+}
+
+type magicLinkService struct {
+	cfg         *config.MagicLinkConfig
+	repo        repository.MagicLinkRepository
+	userRepo    repository.UserRepository
+	userService UserService
+	sender      mailer.Sender
+	cache       cache.Cache
+	emails      EmailPolicy
+}
+
+func NewMagicLinkService(
+	cfg *config.MagicLinkConfig,
+	repo repository.MagicLinkRepository,
+	userRepo repository.UserRepository,
+	userService UserService,
+	sender mailer.Sender,
+	cache cache.Cache,
+	emails EmailPolicy,
+) MagicLinkService {
+	return &magicLinkService{
+		cfg:         cfg,
+		repo:        repo,
+		userRepo:    userRepo,
+		userService: userService,
+		sender:      sender,
+		cache:       cache,
+		emails:      emails,
+	}
+}
+
+func (s *magicLinkService) Request(ctx context.Context, email string) (string, error) {
+	// Every spelling of a mailbox shares one rate limit bucket and finds the same account
+	canonical := s.emails.Canonical(email)
+
+	// Rate limit per email, counting unknown addresses too so the limit does not reveal accounts
+	count, err := s.cache.Increment(ctx, fmt.Sprintf("magic_link:rate:%s", canonical), s.cfg.RateWindow)
+	if err != nil {
+		return "", fmt.Errorf("failed to check rate limit: %w", err)
+	}
+	if count > int64(s.cfg.RateLimit) {
+		return "", ErrMagicLinkRateLimited
+	}
+
+	var browserNonce string
+	if s.cfg.BindBrowser {
+		browserNonce, err = utils.GenerateToken()
+		if err != nil {
+			return "", err
+		}
+	}
+
+	user, err := s.userRepo.FindByIdentifier(ctx, canonical)
+	if err != nil {
+		return "", fmt.Errorf("failed to find user: %w", err)
+	}
+
+	// Unknown or directory-managed accounts get the same response without an email
+	if user == nil || user.AuthProvider != models.AuthProviderLocal {
+		return browserNonce, nil
+	}
+
+	token, err := utils.GenerateToken()
+	if err != nil {
+		return "", err
+	}
+
+	record := &models.MagicLinkToken{
+		UserID:    user.ID,
+		TokenHash: utils.HashToken(token),
+		ExpiresAt: time.Now().UTC().Add(s.cfg.TTL),
+	}
+	if browserNonce != "" {
+		browserHash := utils.HashToken(browserNonce)
+		record.BrowserHash = &browserHash
+	}
+
+	if err := s.repo.Create(ctx, record); err != nil {
+		return "", fmt.Errorf("failed to store magic link: %w", err)
+	}
+
+	link, err := s.link(token)
+	if err != nil {
+		return "", err
+	}
+
+	err = s.sender.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Your sign-in link",
+		Body: fmt.Sprintf(
+			"Use the link below to sign in. It expires in %s and can only be used once.\n\n%s\n\n"+
+				"If you did not request this, you can ignore this email.",
+			s.cfg.TTL, link,
+		),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to send magic link: %w", err)
+	}
+
+	return browserNonce, nil
+}
+
+func (s *magicLinkService) Verify(ctx context.Context, token, browserNonce string) (*dtos.SignInResponse, error) {
+	record, err := s.repo.FindByTokenHash(ctx, utils.HashToken(token))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find magic link: %w", err)
+	}
+
+	if record == nil || record.UsedAt != nil || time.Now().After(record.ExpiresAt) {
+		return nil, ErrMagicLinkInvalid
+	}
+
+	if record.BrowserHash != nil && utils.HashToken(browserNonce) != *record.BrowserHash {
+		return nil, ErrMagicLinkInvalid
+	}
+
+	// Consume before issuing tokens so concurrent verifications cannot both succeed
+	consumed, err := s.repo.MarkUsed(ctx, record.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to consume magic link: %w", err)
+	}
+	if !consumed {
+		return nil, ErrMagicLinkInvalid
+	}
+
+	user, err := s.userRepo.FindByID(ctx, record.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+	if user == nil {
+		return nil, ErrMagicLinkInvalid
+	}
+
+	return s.userService.SignInWithIdentity(ctx, &auth.Identity{
+		Email:    user.Email,
+		Provider: user.AuthProvider,
+		User:     user,
+		Methods:  []string{utils.AMREmailLink},
+	})
+}
+
+func (s *magicLinkService) link(token string) (string, error) {
+	return withToken(s.cfg.URL, token)
+}
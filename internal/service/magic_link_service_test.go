@@ -0,0 +1,61 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+	"user-management/internal/config"
+	"user-management/internal/models"
+	"user-management/internal/repository"
+)
+
+// countingCache counts Increment calls per key
+type countingCache struct {
+	*memoryCache
+	counts map[string]int64
+}
+
+func (c *countingCache) Increment(_ context.Context, key string, _ time.Duration) (int64, error) {
+	c.counts[key]++
+	return c.counts[key], nil
+}
+
+// lookupRepository records the identifiers users are looked up by
+type lookupRepository struct {
+	repository.UserRepository
+	lookups []string
+}
+
+func (r *lookupRepository) FindByIdentifier(_ context.Context, identifier string) (*models.User, error) {
+	r.lookups = append(r.lookups, identifier)
+	return nil, nil
+}
+
+func TestMagicLinkRequestUsesTheCanonicalAddress(t *testing.T) {
+	cache := &countingCache{memoryCache: newMemoryCache(), counts: make(map[string]int64)}
+	users := &lookupRepository{}
+	svc := NewMagicLinkService(
+		&config.MagicLinkConfig{RateLimit: 2, RateWindow: time.Hour, TTL: time.Minute},
+		nil, users, nil, nil, cache, gmailPolicy(t),
+	)
+	ctx := context.Background()
+
+	for _, email := range []string{"J.Doe@gmail.com", "jdoe+1@gmail.com"} {
+		if _, err := svc.Request(ctx, email); err != nil {
+			t.Fatalf("Request(%s): %v", email, err)
+		}
+	}
+	if _, err := svc.Request(ctx, "j.d.o.e+2@googlemail.com"); !errors.Is(err, ErrMagicLinkRateLimited) {
+		t.Fatalf("third spelling of the mailbox: err = %v, want ErrMagicLinkRateLimited", err)
+	}
+
+	if len(cache.counts) != 1 || cache.counts["magic_link:rate:jdoe@gmail.com"] != 3 {
+		t.Errorf("rate limit buckets = %v", cache.counts)
+	}
+	for _, lookup := range users.lookups {
+		if lookup != "jdoe@gmail.com" {
+			t.Errorf("looked up %q, want the canonical address", lookup)
+		}
+	}
+}
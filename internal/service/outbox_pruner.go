@@ -0,0 +1,68 @@
+package service
+
+import (
+	"context"
+	"time"
+	"user-management/internal/config"
+	"user-management/internal/repository"
+	"user-management/pkg/logger"
+)
+
+const (
+	outboxPruneInterval  = time.Hour
+	outboxPruneBatchSize = 1000
+)
+
+// OutboxPruner deletes outbox events, and their webhook deliveries, once they have been handled and
+// are older than the retention period
+type OutboxPruner struct {
+	repo   repository.OutboxRepository
+	cfg    *config.EventsConfig
+	logger *logger.Logger
+}
+
+func NewOutboxPruner(repo repository.OutboxRepository, cfg *config.EventsConfig, logger *logger.Logger) *OutboxPruner {
+	return &OutboxPruner{
+		repo:   repo,
+		cfg:    cfg,
+		logger: logger,
+	}
+}
+
+// Run prunes every hour until the context is cancelled
+func (p *OutboxPruner) Run(ctx context.Context) {
+	ticker := time.NewTicker(outboxPruneInterval)
+	defer ticker.Stop()
+
+	for {
+		if pruned, err := p.Prune(ctx); err != nil && ctx.Err() == nil {
+			p.logger.Error().Err(err).Int64("pruned", pruned).Msg("Outbox pruning failed")
+		} else if pruned > 0 {
+			p.logger.Info().Int64("pruned", pruned).Msg("Pruned outbox events")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Prune deletes every expired event and returns how many were deleted. Events must also have been
+// published when a broker is configured.
+func (p *OutboxPruner) Prune(ctx context.Context) (int64, error) {
+	var pruned int64
+	cutoff := time.Now().Add(-p.cfg.Retention)
+
+	for {
+		deleted, err := p.repo.Prune(ctx, cutoff, p.cfg.Driver != "none", outboxPruneBatchSize)
+		pruned += deleted
+		if err != nil || deleted < outboxPruneBatchSize || ctx.Err() != nil {
+			if err == nil {
+				err = ctx.Err()
+			}
+			return pruned, err
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+	"user-management/internal/config"
+	"user-management/internal/models"
+)
+
+// pruneRepository deletes from a fixed number of expired events and records the arguments
+type pruneRepository struct {
+	expired          int64
+	cutoff           time.Time
+	requirePublished bool
+	calls            int
+}
+
+func (r *pruneRepository) ClaimPending(context.Context, int, time.Duration) ([]models.OutboxEvent, error) {
+	return nil, nil
+}
+
+func (r *pruneRepository) MarkPublished(context.Context, []int64) error { return nil }
+
+func (r *pruneRepository) ReleaseClaims(context.Context, []int64) error { return nil }
+
+func (r *pruneRepository) Prune(_ context.Context, createdBefore time.Time, requirePublished bool, limit int) (int64, error) {
+	r.calls++
+	r.cutoff, r.requirePublished = createdBefore, requirePublished
+	deleted := min(r.expired, int64(limit))
+	r.expired -= deleted
+	return deleted, nil
+}
+
+func TestOutboxPrunerPrune(t *testing.T) {
+	for driver, requirePublished := range map[string]bool{"none": false, "nats": true} {
+		repo := &pruneRepository{expired: outboxPruneBatchSize + 5}
+		pruner := NewOutboxPruner(repo, &config.EventsConfig{Driver: driver, Retention: 24 * time.Hour}, nopLogger())
+
+		pruned, err := pruner.Prune(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pruned != outboxPruneBatchSize+5 || repo.calls != 2 {
+			t.Errorf("%s: pruned %d in %d calls", driver, pruned, repo.calls)
+		}
+		if repo.requirePublished != requirePublished {
+			t.Errorf("%s: requirePublished = %v", driver, repo.requirePublished)
+		}
+		if age := time.Since(repo.cutoff); age < 24*time.Hour || age > 25*time.Hour {
+			t.Errorf("%s: cutoff is %s ago", driver, age)
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"user-management/internal/apperr"
+	"user-management/internal/config"
+	"user-management/internal/models"
+	"user-management/internal/repository"
+	"user-management/internal/reqctx"
+	"user-management/internal/utils"
+	"user-management/pkg/cache"
+	"user-management/pkg/sms"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrPhoneNumberMissing   = apperr.ErrValidation.WithMessage("Set a phone number before verifying it")
+	ErrPhoneAlreadyVerified = apperr.ErrConflict.WithMessage("Phone number already verified")
+	ErrPhoneInUse           = apperr.ErrConflict.WithMessage("Phone number already verified by another account")
+	ErrPhoneCodeRateLimited = apperr.ErrRateLimited.WithMessage("Too many verification codes requested, please try again later")
+	ErrPhoneCodeInvalid     = apperr.New(http.StatusBadRequest, utils.ErrCodeInvalidToken, "Invalid or expired verification code")
+)
+
+type PhoneVerificationService interface {
+	// SendCode texts a one-time code to the phone number on the account
+	SendCode(ctx context.Context, userID, targetID uuid.UUID) error
+	// Verify checks the code and marks the phone number as verified
+	Verify(ctx context.Context, userID, targetID uuid.UUID, code string) (*models.User, error)
+}
+
+type phoneVerificationService struct {
+	cfg      *config.PhoneConfig
+	userRepo repository.UserRepository
+	sender   sms.SMSSender
+	cache    cache.Cache
+}
+
+func NewPhoneVerificationService(
+	cfg *config.PhoneConfig,
+	userRepo repository.UserRepository,
+	sender sms.SMSSender,
+	cache cache.Cache,
+) PhoneVerificationService {
+	return &phoneVerificationService{
+		cfg:      cfg,
+		userRepo: userRepo,
+		sender:   sender,
+		cache:    cache,
+	}
+}
+
+// pendingPhoneCode is the outstanding code of a user, kept in the cache until it expires
+type pendingPhoneCode struct {
+	Phone    string `json:"phone"`
+	CodeHash string `json:"code_hash"`
+}
+
+func (s *phoneVerificationService) SendCode(ctx context.Context, userID, targetID uuid.UUID) error {
+	user, err := s.authorize(ctx, userID, targetID)
+	if err != nil {
+		return err
+	}
+	if user.PhoneNumber == "" {
+		return ErrPhoneNumberMissing
+	}
+	if user.PhoneVerifiedAt != nil {
+		return ErrPhoneAlreadyVerified
+	}
+
+	// Limit per number so nobody can be flooded with texts, and per IP so one client
+	// cannot cycle through numbers
+	if err := s.checkRate(ctx, "phone_code:rate:number:"+user.PhoneNumber, s.cfg.RateLimit); err != nil {
+		return err
+	}
+	if ip := reqctx.ClientInfoFrom(ctx).IP; ip != "" {
+		if err := s.checkRate(ctx, "phone_code:rate:ip:"+ip, s.cfg.IPRateLimit); err != nil {
+			return err
+		}
+	}
+
+	code, err := generateCode(6)
+	if err != nil {
+		return err
+	}
+
+	pending := pendingPhoneCode{
+		Phone:    user.PhoneNumber,
+		CodeHash: phoneCodeHash(targetID, user.PhoneNumber, code),
+	}
+	if err := s.cache.Set(ctx, phoneCodeKey(targetID), pending, s.cfg.CodeTTL); err != nil {
+		return fmt.Errorf("failed to store verification code: %w", err)
+	}
+	// A new code gets a fresh set of attempts
+	_ = s.cache.Delete(ctx, phoneAttemptsKey(targetID))
+
+	err = s.sender.Send(ctx, sms.Message{
+		To:   user.PhoneNumber,
+		Body: fmt.Sprintf("Your verification code is %s. It expires in %s.", code, s.cfg.CodeTTL),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to send verification code: %w", err)
+	}
+
+	return nil
+}
+
+func (s *phoneVerificationService) Verify(ctx context.Context, userID, targetID uuid.UUID, code string) (*models.User, error) {
+	user, err := s.authorize(ctx, userID, targetID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Count the attempt before comparing so parallel guesses are limited too
+	attempts, err := s.cache.Increment(ctx, phoneAttemptsKey(targetID), s.cfg.CodeTTL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check attempts: %w", err)
+	}
+	if attempts > int64(s.cfg.MaxAttempts) {
+		_ = s.cache.Delete(ctx, phoneCodeKey(targetID))
+		return nil, ErrPhoneCodeInvalid
+	}
+
+	raw, err := s.cache.Get(ctx, phoneCodeKey(targetID))
+	if err != nil {
+		return nil, ErrPhoneCodeInvalid
+	}
+	var pending pendingPhoneCode
+	if err := json.Unmarshal([]byte(raw), &pending); err != nil {
+		return nil, ErrPhoneCodeInvalid
+	}
+
+	// The code only proves ownership of the number it was sent to
+	if pending.Phone != user.PhoneNumber {
+		return nil, ErrPhoneCodeInvalid
+	}
+	expected := phoneCodeHash(targetID, pending.Phone, code)
+	if subtle.ConstantTimeCompare([]byte(expected), []byte(pending.CodeHash)) != 1 {
+		return nil, ErrPhoneCodeInvalid
+	}
+
+	verified, err := s.userRepo.MarkPhoneVerified(ctx, targetID, pending.Phone)
+	if err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, ErrPhoneInUse
+		}
+		return nil, fmt.Errorf("failed to verify phone number: %w", err)
+	}
+	if !verified {
+		return nil, ErrPhoneCodeInvalid
+	}
+
+	_ = s.cache.Delete(ctx, phoneCodeKey(targetID))
+	_ = s.cache.Delete(ctx, phoneAttemptsKey(targetID))
+	_ = s.cache.Delete(ctx, userCacheKey(targetID))
+
+	return s.userRepo.FindByID(ctx, targetID)
+}
+
+// authorize lets users verify only their own number, and never through an impersonated token
+// since a verified number becomes a sign-in identifier
+func (s *phoneVerificationService) authorize(ctx context.Context, userID, targetID uuid.UUID) (*models.User, error) {
+	if userID != targetID {
+		return nil, ErrUnauthorized
+	}
+	if reqctx.ImpersonatorFrom(ctx) != "" {
+		return nil, ErrImpersonationForbidden
+	}
+
+	user, err := s.userRepo.FindByID(ctx, targetID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	return user, nil
+}
+
+func (s *phoneVerificationService) checkRate(ctx context.Context, key string, limit int) error {
+	count, err := s.cache.Increment(ctx, key, s.cfg.RateWindow)
+	if err != nil {
+		return fmt.Errorf("failed to check rate limit: %w", err)
+	}
+	if count > int64(limit) {
+		return ErrPhoneCodeRateLimited
+	}
+	return nil
+}
+
+// generateCode returns a uniformly random numeric code of the given length
+func generateCode(length int) (string, error) {
+	max := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(length)), nil)
+	n, err := rand.Int(rand.Reader, max)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate code: %w", err)
+	}
+	return fmt.Sprintf("%0*d", length, n), nil
+}
+
+func phoneCodeHash(userID uuid.UUID, phone, code string) string {
+	return utils.HashToken(userID.String() + ":" + phone + ":" + code)
+}
+
+func phoneCodeKey(userID uuid.UUID) string {
+	return fmt.Sprintf("phone_code:%s", userID.String())
+}
+
+func phoneAttemptsKey(userID uuid.UUID) string {
+	return fmt.Sprintf("phone_code:attempts:%s", userID.String())
+}
@@ -0,0 +1,199 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+	"user-management/internal/apperr"
+	"user-management/internal/models"
+	"user-management/internal/repository"
+	"user-management/internal/reqctx"
+	"user-management/internal/utils"
+	"user-management/pkg/cache"
+
+	"github.com/google/uuid"
+)
+
+const (
+	sessionCacheTTL      = 5 * time.Minute
+	sessionTouchInterval = time.Minute
+)
+
+var (
+	ErrSessionNotFound = apperr.ErrNotFound.WithMessage("Session not found")
+	ErrSessionRevoked  = apperr.ErrUnauthorized.WithMessage("Session has been revoked")
+)
+
+type SessionService interface {
+	Create(ctx context.Context, userID uuid.UUID) (*models.Session, error)
+	ValidateToken(ctx context.Context, claims *utils.Claims) error
+	ListSessions(ctx context.Context, userID, targetID uuid.UUID) ([]models.Session, error)
+	RevokeSession(ctx context.Context, userID, targetID, sessionID uuid.UUID) error
+	RevokeAll(ctx context.Context, userID uuid.UUID) error
+}
+
+// cachedSession is the minimal state needed to authorize a request without hitting the database
+type cachedSession struct {
+	UserID    uuid.UUID `json:"user_id"`
+	Active    bool      `json:"active"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type sessionService struct {
+	repo     repository.SessionRepository
+	cache    cache.Cache
+	audit    AuditService
+	lifetime time.Duration
+}
+
+func NewSessionService(
+	repo repository.SessionRepository,
+	cache cache.Cache,
+	audit AuditService,
+	lifetime time.Duration,
+) SessionService {
+	return &sessionService{
+		repo:     repo,
+		cache:    cache,
+		audit:    audit,
+		lifetime: lifetime,
+	}
+}
+
+func (s *sessionService) Create(ctx context.Context, userID uuid.UUID) (*models.Session, error) {
+	client := reqctx.ClientInfoFrom(ctx)
+	now := time.Now().UTC()
+
+	session := &models.Session{
+		UserID:     userID,
+		UserAgent:  truncate(client.UserAgent, 512),
+		IP:         client.IP,
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(s.lifetime),
+	}
+
+	if err := s.repo.Create(ctx, session); err != nil {
+		return nil, fmt.Errorf("failed to create session: %w", err)
+	}
+
+	return session, nil
+}
+
+// ValidateToken rejects tokens whose session was revoked, using the cache to avoid a query per request
+func (s *sessionService) ValidateToken(ctx context.Context, claims *utils.Claims) error {
+	sessionID, err := uuid.Parse(claims.SessionID)
+	if err != nil {
+		return ErrSessionNotFound
+	}
+
+	state, err := s.state(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+
+	// Impersonation tokens are bound to the acting admin's session
+	owner := claims.UserID
+	if claims.Impersonated() {
+		owner = claims.Actor.Subject
+	}
+
+	if !state.Active || time.Now().After(state.ExpiresAt) || state.UserID.String() != owner {
+		return ErrSessionRevoked
+	}
+
+	// Record activity at most once per interval
+	seen, err := s.cache.Increment(ctx, fmt.Sprintf("session:seen:%s", sessionID), sessionTouchInterval)
+	if err == nil && seen == 1 {
+		_ = s.repo.Touch(ctx, sessionID, time.Now().UTC())
+	}
+
+	return nil
+}
+
+func (s *sessionService) ListSessions(ctx context.Context, userID, targetID uuid.UUID) ([]models.Session, error) {
+	if userID != targetID {
+		return nil, ErrUnauthorized
+	}
+
+	return s.repo.ListActiveByUser(ctx, targetID)
+}
+
+func (s *sessionService) RevokeSession(ctx context.Context, userID, targetID, sessionID uuid.UUID) error {
+	if userID != targetID {
+		return ErrUnauthorized
+	}
+
+	revoked, err := s.repo.Revoke(ctx, targetID, sessionID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+	if !revoked {
+		return ErrSessionNotFound
+	}
+
+	_ = s.cache.Delete(ctx, sessionCacheKey(sessionID))
+
+	s.audit.Record(ctx, newAuditEvent(models.AuditSessionRevoked, models.AuditSuccess, userID, targetID, models.JSONMap{
+		"session_id": sessionID,
+	}))
+
+	return nil
+}
+
+// RevokeAll signs the user out everywhere, invalidating every token issued to them
+func (s *sessionService) RevokeAll(ctx context.Context, userID uuid.UUID) error {
+	ids, err := s.repo.RevokeAllByUser(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+
+	for _, id := range ids {
+		_ = s.cache.Delete(ctx, sessionCacheKey(id))
+	}
+
+	s.audit.Record(ctx, newAuditEvent(models.AuditSessionsRevokedAll, models.AuditSuccess, userID, userID, models.JSONMap{
+		"count": len(ids),
+	}))
+
+	return nil
+}
+
+func (s *sessionService) state(ctx context.Context, sessionID uuid.UUID) (*cachedSession, error) {
+	cacheKey := sessionCacheKey(sessionID)
+
+	if cached, err := s.cache.Get(ctx, cacheKey); err == nil {
+		var state cachedSession
+		if err := json.Unmarshal([]byte(cached), &state); err == nil {
+			return &state, nil
+		}
+	}
+
+	session, err := s.repo.FindByID(ctx, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find session: %w", err)
+	}
+	if session == nil {
+		return nil, ErrSessionNotFound
+	}
+
+	state := &cachedSession{
+		UserID:    session.UserID,
+		Active:    session.Active(time.Now()),
+		ExpiresAt: session.ExpiresAt,
+	}
+	_ = s.cache.Set(ctx, cacheKey, state, sessionCacheTTL)
+
+	return state, nil
+}
+
+func sessionCacheKey(sessionID uuid.UUID) string {
+	return fmt.Sprintf("session:%s", sessionID.String())
+}
+
+func truncate(value string, limit int) string {
+	if len(value) <= limit {
+		return value
+	}
+	return value[:limit]
+}
@@ -5,67 +5,188 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
+	"user-management/internal/auth"
 	"user-management/internal/dtos"
 	"user-management/internal/models"
 	"user-management/internal/repository"
+	"user-management/internal/reqctx"
 	"user-management/internal/utils"
 	"user-management/pkg/cache"
+	"user-management/pkg/emailaddr"
+	"user-management/pkg/logger"
+	"user-management/pkg/metrics"
 
 	"github.com/google/uuid"
+	"golang.org/x/text/language"
+	"gorm.io/gorm"
 )
 
 type UserService interface {
-	SignIn(ctx context.Context, email, password string) (*dtos.SignInResponse, error)
+	// SignIn accepts the email, username or verified phone number (E.164) of the account
+	SignIn(ctx context.Context, identifier, password string) (*dtos.SignInResponse, error)
+	SignInWithIdentity(ctx context.Context, identity *auth.Identity) (*dtos.SignInResponse, error)
 	GetUser(ctx context.Context, userID, targetID uuid.UUID) (*models.User, error)
 	UpdateUser(ctx context.Context, userID, targetID uuid.UUID, req *dtos.UpdateUserRequest) (*models.User, error)
-	ListUsers(ctx context.Context, lastID uuid.UUID, searchEmail string, limit int) ([]models.User, error)
+	ListUsers(ctx context.Context, lastID uuid.UUID, filter repository.UserFilter, limit int) ([]models.User, error)
 	CreateUser(ctx context.Context, req *dtos.SignUpRequest) (*models.User, error)
+	// CheckUsername returns nil when the username is valid and free
+	CheckUsername(ctx context.Context, username string) error
+	Impersonate(ctx context.Context, adminID, targetID uuid.UUID, adminSessionID string) (*dtos.ImpersonationResponse, error)
+	ChangeStatus(ctx context.Context, actorID, targetID uuid.UUID, req *dtos.ChangeStatusRequest) (*models.User, error)
+	ValidateToken(ctx context.Context, claims *utils.Claims) error
+	DeleteUser(ctx context.Context, userID, targetID uuid.UUID) (*dtos.AccountDeletionResponse, error)
+	RestoreAccount(ctx context.Context, email, password string) (*models.User, error)
+	RestoreUser(ctx context.Context, actorID, targetID uuid.UUID) (*models.User, error)
 }
 
 type userService struct {
 	repo            repository.UserRepository
 	jwtManager      *utils.JWTManager
 	passwordManager *utils.PasswordManager
+	authenticator   auth.Authenticator
+	directories     auth.DomainManager
+	sessionService  SessionService
 	cache           cache.Cache
+	avatars         AvatarService
+	emailChanges    EmailChangeService
+	emails          EmailPolicy
+	audit           AuditService
+	gracePeriod     time.Duration
 }
 
 func NewUserService(
 	repo repository.UserRepository,
 	jwtManager *utils.JWTManager,
 	passwordManager *utils.PasswordManager,
+	authenticator auth.Authenticator,
+	directories auth.DomainManager,
+	sessionService SessionService,
 	cache cache.Cache,
+	avatars AvatarService,
+	emailChanges EmailChangeService,
+	emails EmailPolicy,
+	audit AuditService,
+	gracePeriod time.Duration,
 ) UserService {
 	return &userService{
 		repo:            repo,
 		jwtManager:      jwtManager,
 		passwordManager: passwordManager,
+		authenticator:   authenticator,
+		directories:     directories,
+		sessionService:  sessionService,
 		cache:           cache,
+		avatars:         avatars,
+		emailChanges:    emailChanges,
+		emails:          emails,
+		audit:           audit,
+		gracePeriod:     gracePeriod,
 	}
 }
 
-func (s *userService) SignIn(ctx context.Context, email, password string) (*dtos.SignInResponse, error) {
-	// Find user by email
-	user, err := s.repo.FindByEmail(ctx, email)
+func (s *userService) SignIn(ctx context.Context, identifier, password string) (*dtos.SignInResponse, error) {
+	isEmail := strings.Contains(identifier, "@")
+	lookup := identifier
+	if isEmail {
+		if addr, err := s.emails.Normalize(identifier); err == nil {
+			lookup = addr.Canonical
+		}
+	}
+
+	user, err := s.repo.FindByIdentifier(ctx, lookup)
 	if err != nil {
 		return nil, fmt.Errorf("failed to find user: %w", err)
 	}
 
-	if user == nil {
-		return nil, errors.New("invalid credentials")
+	// Authenticate against the stored email so directory users are routed as usual.
+	// Unknown emails are passed on as typed, since a directory may provision them.
+	email := identifier
+	targetID := uuid.Nil
+	if user != nil {
+		email = user.Email
+		targetID = user.ID
+	} else if !isEmail {
+		s.recordSignInFailure(ctx, identifier, targetID, "invalid_credentials")
+		return nil, ErrInvalidCredentials
+	}
+
+	identity, err := s.authenticator.Authenticate(ctx, email, password)
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidCredentials) {
+			s.recordSignInFailure(ctx, identifier, targetID, "invalid_credentials")
+			return nil, ErrInvalidCredentials
+		}
+		return nil, fmt.Errorf("failed to authenticate: %w", err)
+	}
+
+	return s.SignInWithIdentity(ctx, identity)
+}
+
+// SignInWithIdentity issues tokens for an identity already verified by an external provider
+func (s *userService) SignInWithIdentity(ctx context.Context, identity *auth.Identity) (*dtos.SignInResponse, error) {
+	user, err := s.syncIdentity(ctx, identity)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkStatus(user); err != nil {
+		s.recordSignInFailure(ctx, user.Email, user.ID, user.EffectiveStatus(time.Now()))
+		return nil, err
 	}
 
-	// Verify password
-	if err := s.passwordManager.Compare(user.Password, password); err != nil {
-		return nil, errors.New("invalid credentials")
+	response, err := s.issueTokens(ctx, user, identity)
+	if err != nil {
+		return nil, err
 	}
 
-	accessToken, err := s.jwtManager.GenerateAccessToken(user.ID.String(), user.Email)
+	metrics.AuthSuccesses.WithLabelValues(identity.Provider).Inc()
+	s.audit.Record(ctx, newAuditEvent(models.AuditSignIn, models.AuditSuccess, user.ID, user.ID, models.JSONMap{
+		"provider": identity.Provider,
+		"methods":  identity.Methods,
+	}))
+
+	return response, nil
+}
+
+// recordSignInFailure audits a rejected sign-in; targetID is uuid.Nil when no account matched
+func (s *userService) recordSignInFailure(ctx context.Context, identifier string, targetID uuid.UUID, reason string) {
+	metrics.AuthFailures.WithLabelValues(reason).Inc()
+	s.audit.Record(ctx, newAuditEvent(models.AuditSignIn, models.AuditFailure, uuid.Nil, targetID, models.JSONMap{
+		"identifier": s.audit.Pseudonym(strings.ToLower(strings.TrimSpace(identifier))),
+		"reason":     reason,
+	}))
+}
+
+// issueTokens starts a new session and issues tokens bound to it
+func (s *userService) issueTokens(
+	ctx context.Context,
+	user *models.User,
+	identity *auth.Identity,
+) (*dtos.SignInResponse, error) {
+	session, err := s.sessionService.Create(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	subject := utils.TokenSubject{
+		UserID:    user.ID.String(),
+		Email:     user.Email,
+		Role:      user.Role,
+		SessionID: session.ID.String(),
+		AuthTime:  identity.AuthTime,
+		AMR:       identity.Methods,
+	}
+	if subject.AuthTime.IsZero() {
+		subject.AuthTime = time.Now()
+	}
+
+	accessToken, err := s.jwtManager.GenerateAccessToken(subject)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate token: %w", err)
 	}
 
-	refreshToken, err := s.jwtManager.GenerateRefreshToken(user.ID.String(), user.Email)
+	refreshToken, err := s.jwtManager.GenerateRefreshToken(subject)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate token: %w", err)
 	}
@@ -76,28 +197,125 @@ func (s *userService) SignIn(ctx context.Context, email, password string) (*dtos
 	}, nil
 }
 
+// syncIdentity provisions a local user on first external login and keeps the role in sync with the directory.
+// Existing accounts of another provider are refused.
+func (s *userService) syncIdentity(ctx context.Context, identity *auth.Identity) (*models.User, error) {
+	if identity.User != nil {
+		return identity.User, nil
+	}
+
+	canonical := s.emails.Canonical(identity.Email)
+	user, err := s.repo.FindByIdentifier(ctx, canonical)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+
+	if user == nil {
+		user = &models.User{
+			Email:          identity.Email,
+			CanonicalEmail: canonical,
+			Password:       models.UnusablePassword,
+			Role:           identity.Role,
+			AuthProvider:   identity.Provider,
+			Status:         models.StatusActive,
+		}
+		if err := s.repo.Create(ctx, user); err != nil {
+			return nil, fmt.Errorf("failed to provision user: %w", err)
+		}
+		s.audit.Record(ctx, newAuditEvent(models.AuditSignUp, models.AuditSuccess, user.ID, user.ID, models.JSONMap{
+			"provider": identity.Provider,
+		}))
+		s.linkIdentity(ctx, user.ID, identity)
+		return user, nil
+	}
+
+	// Matching emails are not proof of ownership: an external identity never takes over an account
+	// that signs in another way
+	if identity.Provider != user.AuthProvider {
+		s.recordSignInFailure(ctx, identity.Email, user.ID, "provider_mismatch")
+		return nil, ErrProviderMismatch
+	}
+
+	updateFields := make(map[string]interface{})
+	if identity.Role != "" && identity.Role != user.Role {
+		updateFields["role"] = identity.Role
+		user.Role = identity.Role
+	}
+
+	if len(updateFields) > 0 {
+		if err := s.repo.Update(ctx, user.ID, updateFields); err != nil {
+			return nil, fmt.Errorf("failed to sync user: %w", err)
+		}
+		_ = s.cache.Delete(ctx, userCacheKey(user.ID))
+	}
+
+	s.linkIdentity(ctx, user.ID, identity)
+
+	return user, nil
+}
+
+// linkIdentity remembers which external account signed in, for the data export. A failure does not
+// block the sign-in.
+func (s *userService) linkIdentity(ctx context.Context, userID uuid.UUID, identity *auth.Identity) {
+	if identity.Subject == "" {
+		return
+	}
+
+	now := time.Now().UTC()
+	err := s.repo.LinkIdentity(ctx, &models.UserIdentity{
+		UserID:     userID,
+		Provider:   identity.Provider,
+		Issuer:     identity.Issuer,
+		Subject:    identity.Subject,
+		CreatedAt:  now,
+		LastUsedAt: now,
+	})
+	if err != nil {
+		logger.FromContext(ctx).Warn().Ctx(ctx).Err(err).Msg("Failed to link external identity")
+	}
+}
+
 func (s *userService) GetUser(ctx context.Context, userID, targetID uuid.UUID) (*models.User, error) {
 	if userID != targetID {
-		return nil, errors.New("unauthorized")
+		return nil, ErrUnauthorized
+	}
+
+	user, err := s.findUserCached(ctx, targetID)
+	if err != nil {
+		return nil, err
+	}
+
+	change, err := s.emailChanges.Pending(ctx, targetID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find email change: %w", err)
+	}
+	if change != nil {
+		user.PendingEmail = change.NewEmail
 	}
 
+	return user, nil
+}
+
+func (s *userService) findUserCached(ctx context.Context, id uuid.UUID) (*models.User, error) {
 	// Check cache
-	cacheKey := fmt.Sprintf("user:%s", targetID.String())
+	cacheKey := userCacheKey(id)
 	cachedUser, err := s.cache.Get(ctx, cacheKey)
 	if err == nil {
 		var user models.User
 		if err := json.Unmarshal([]byte(cachedUser), &user); err == nil {
+			metrics.UserCacheRequests.WithLabelValues("hit").Inc()
 			return &user, nil
 		}
 	}
+	metrics.UserCacheRequests.WithLabelValues("miss").Inc()
 
-	user, err := s.repo.FindByID(ctx, targetID)
+	user, err := s.repo.FindByID(ctx, id)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
 
 	if user == nil {
-		return nil, errors.New("user not found")
+		return nil, ErrUserNotFound
 	}
 
 	// Cache user for 15 minutes
@@ -106,6 +324,215 @@ func (s *userService) GetUser(ctx context.Context, userID, targetID uuid.UUID) (
 	return user, nil
 }
 
+// ValidateToken rejects tokens of accounts that are no longer active, including the impersonating admin,
+// and tokens whose role claim no longer matches the stored role
+func (s *userService) ValidateToken(ctx context.Context, claims *utils.Claims) error {
+	ids := []string{claims.UserID}
+	if claims.Impersonated() {
+		ids = append(ids, claims.Actor.Subject)
+	}
+
+	for i, rawID := range ids {
+		id, err := uuid.Parse(rawID)
+		if err != nil {
+			return ErrUserNotFound
+		}
+
+		user, err := s.findUserCached(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		if err := checkStatus(user); err != nil {
+			return err
+		}
+
+		// Tokens live for hours, so a demoted admin must not keep the old role until expiry
+		if i == 0 && claims.Role != user.Role {
+			return ErrRoleChanged
+		}
+	}
+
+	return nil
+}
+
+// ChangeStatus moves an account through its lifecycle on behalf of an admin
+func (s *userService) ChangeStatus(
+	ctx context.Context,
+	actorID, targetID uuid.UUID,
+	req *dtos.ChangeStatusRequest,
+) (*models.User, error) {
+	if actorID == targetID {
+		return nil, ErrCannotChangeOwnStatus
+	}
+
+	user, err := s.repo.FindByID(ctx, targetID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+
+	now := time.Now().UTC()
+	if !models.CanTransition(user.EffectiveStatus(now), req.Status) {
+		return nil, ErrInvalidStatusTransition
+	}
+
+	if req.Until != nil && !req.Until.After(now) {
+		return nil, ErrInvalidStatusTransition
+	}
+
+	updateFields := map[string]interface{}{
+		"status":            req.Status,
+		"status_reason":     nil,
+		"suspended_until":   nil,
+		"status_changed_at": now,
+	}
+	if req.Reason != "" {
+		updateFields["status_reason"] = req.Reason
+	}
+	if req.Status == models.StatusSuspended && req.Until != nil {
+		updateFields["suspended_until"] = req.Until.UTC()
+	}
+
+	if err := s.repo.Update(ctx, targetID, updateFields); err != nil {
+		return nil, fmt.Errorf("failed to update status: %w", err)
+	}
+
+	s.audit.Record(ctx, newAuditEvent(models.AuditStatusChanged, models.AuditSuccess, actorID, targetID, models.JSONMap{
+		"from":   user.EffectiveStatus(now),
+		"to":     req.Status,
+		"reason": req.Reason,
+		"until":  req.Until,
+	}))
+
+	// Invalidate cache so GetUser and token validation see the new status immediately
+	_ = s.cache.Delete(ctx, userCacheKey(targetID))
+
+	return s.repo.FindByID(ctx, targetID)
+}
+
+// DeleteUser soft-deletes the account, signs it out everywhere and schedules the purge after the grace period
+func (s *userService) DeleteUser(ctx context.Context, userID, targetID uuid.UUID) (*dtos.AccountDeletionResponse, error) {
+	if userID != targetID {
+		return nil, ErrUnauthorized
+	}
+
+	if reqctx.ImpersonatorFrom(ctx) != "" {
+		return nil, ErrImpersonationForbidden
+	}
+
+	deleted, err := s.repo.SoftDelete(ctx, targetID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete user: %w", err)
+	}
+	if !deleted {
+		return nil, ErrUserNotFound
+	}
+
+	s.audit.Record(ctx, newAuditEvent(models.AuditUserDeleted, models.AuditSuccess, userID, targetID, nil))
+
+	if err := s.sessionService.RevokeAll(ctx, targetID); err != nil {
+		return nil, err
+	}
+
+	_ = s.cache.Delete(ctx, userCacheKey(targetID))
+
+	deletedAt := time.Now().UTC()
+	return &dtos.AccountDeletionResponse{
+		DeletedAt: deletedAt,
+		PurgeAt:   deletedAt.Add(s.gracePeriod),
+	}, nil
+}
+
+// errRestoreDenied hides whether the account exists or the credentials were wrong
+var errRestoreDenied = ErrInvalidCredentials.WithMessage("Account cannot be restored")
+
+// RestoreAccount lets the owner undo a deletion during the grace period by proving their credentials
+func (s *userService) RestoreAccount(ctx context.Context, email, password string) (*models.User, error) {
+	user, err := s.repo.FindDeletedByCanonicalEmail(ctx, s.emails.Canonical(email), time.Now().Add(-s.gracePeriod))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+
+	if user == nil {
+		return nil, errRestoreDenied
+	}
+
+	if user.AuthProvider == models.AuthProviderLocal {
+		if err := s.passwordManager.Compare(user.Password, password); err != nil {
+			return nil, errRestoreDenied
+		}
+	} else {
+		// Directory users prove their identity against the directory, routed by the stored address
+		identity, err := s.authenticator.Authenticate(ctx, user.Email, password)
+		if err != nil || identity.Email != user.Email {
+			return nil, errRestoreDenied
+		}
+	}
+
+	restored, err := s.restore(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.audit.Record(ctx, newAuditEvent(models.AuditUserRestored, models.AuditSuccess, user.ID, user.ID, nil))
+	return restored, nil
+}
+
+// RestoreUser undoes a deletion during the grace period on behalf of an admin
+func (s *userService) RestoreUser(ctx context.Context, actorID, targetID uuid.UUID) (*models.User, error) {
+	user, err := s.repo.FindDeleted(ctx, targetID, time.Now().Add(-s.gracePeriod))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+
+	restored, err := s.restore(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.audit.Record(ctx, newAuditEvent(models.AuditAdminRestoredUser, models.AuditSuccess, actorID, targetID, nil))
+	return restored, nil
+}
+
+func (s *userService) restore(ctx context.Context, id uuid.UUID) (*models.User, error) {
+	restored, err := s.repo.Restore(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to restore user: %w", err)
+	}
+	if !restored {
+		return nil, ErrUserNotFound
+	}
+
+	_ = s.cache.Delete(ctx, userCacheKey(id))
+
+	return s.repo.FindByID(ctx, id)
+}
+
+func checkStatus(user *models.User) error {
+	switch user.EffectiveStatus(time.Now()) {
+	case models.StatusActive:
+		return nil
+	case models.StatusSuspended:
+		return ErrAccountSuspended
+	case models.StatusPending:
+		return ErrAccountPending
+	default:
+		return ErrAccountDisabled
+	}
+}
+
+func userCacheKey(id uuid.UUID) string {
+	return fmt.Sprintf("user:%s", id.String())
+}
+
 func (s *userService) UpdateUser(
 	ctx context.Context,
 	userID, targetID uuid.UUID,
@@ -113,7 +540,7 @@ func (s *userService) UpdateUser(
 ) (*models.User, error) {
 	// Strict privacy: only update self
 	if userID != targetID {
-		return nil, errors.New("unauthorized")
+		return nil, ErrUnauthorized
 	}
 
 	user, err := s.repo.FindByID(ctx, targetID)
@@ -122,22 +549,49 @@ func (s *userService) UpdateUser(
 	}
 
 	if user == nil {
-		return nil, errors.New("user not found")
+		return nil, ErrUserNotFound
+	}
+
+	// Impersonated tokens can never change credentials or sign-in identifiers, the phone number included
+	if (req.Email != "" || req.Password != "" || req.Username != nil || req.PhoneNumber != nil) &&
+		reqctx.ImpersonatorFrom(ctx) != "" {
+		return nil, ErrImpersonationForbidden
 	}
 
 	// Update fields
 	updateFields := make(map[string]interface{})
 
-	if req.Email != "" && req.Email != user.Email {
-		// Check email availability
-		existingUser, err := s.repo.FindByEmail(ctx, req.Email)
+	// The email only changes once the new address is confirmed; check availability up front
+	// so the other fields are not applied when the request is going to fail
+	newEmail := ""
+	if req.Email != "" {
+		addr, err := s.checkEmail(req.Email)
+		if err != nil {
+			return nil, err
+		}
+		newEmail = addr.Display
+	}
+	changeEmail := newEmail != "" && newEmail != user.Email
+	if changeEmail {
+		taken, err := s.repo.EmailTaken(ctx, newEmail, s.emails.Canonical(newEmail), targetID)
 		if err != nil {
 			return nil, fmt.Errorf("failed to check email: %w", err)
 		}
-		if existingUser != nil && existingUser.ID != targetID {
-			return nil, errors.New("email already in use")
+		if taken {
+			return nil, ErrEmailInUse
+		}
+	}
+
+	if req.Username != nil {
+		username := strings.TrimSpace(*req.Username)
+		if username == "" {
+			updateFields["username"] = nil
+		} else {
+			if err := s.checkUsername(ctx, username, targetID); err != nil {
+				return nil, err
+			}
+			updateFields["username"] = username
 		}
-		updateFields["email"] = req.Email
 	}
 
 	// Password update should ideally be separate, but if allowed here:
@@ -149,8 +603,23 @@ func (s *userService) UpdateUser(
 		updateFields["password"] = hashed
 	}
 
+	setProfileFields(updateFields, req)
+
+	// A new number has to be verified again before it can be used to sign in
+	if req.PhoneNumber != nil && strings.TrimSpace(*req.PhoneNumber) != user.PhoneNumber {
+		updateFields["phone_verified_at"] = nil
+	}
+
+	// A manually set avatar URL replaces any uploaded avatar
+	replacedAvatar := ""
+	if req.AvatarURL != nil && user.AvatarKey != "" {
+		replacedAvatar = user.AvatarKey
+		updateFields["avatar_key"] = ""
+		updateFields["avatar_variants"] = models.JSONMap{}
+	}
+
 	if len(updateFields) == 0 {
-		return user, nil
+		return s.requestEmailChange(ctx, user, newEmail, changeEmail)
 	}
 
 	// Perform update
@@ -160,8 +629,11 @@ func (s *userService) UpdateUser(
 	}
 
 	// Invalidate cache
-	cacheKey := fmt.Sprintf("user:%s", targetID.String())
-	_ = s.cache.Delete(ctx, cacheKey)
+	_ = s.cache.Delete(ctx, userCacheKey(targetID))
+
+	if replacedAvatar != "" {
+		_ = s.avatars.DeleteObjects(ctx, replacedAvatar)
+	}
 
 	// Get updated user
 	updatedUser, err := s.repo.FindByID(ctx, targetID)
@@ -169,26 +641,67 @@ func (s *userService) UpdateUser(
 		return nil, err
 	}
 
-	return updatedUser, nil
+	if changes := profileChanges(user, updatedUser, s.audit.Pseudonym); len(changes) > 0 {
+		s.audit.Record(ctx, newAuditEvent(models.AuditUserUpdated, models.AuditSuccess, userID, targetID, models.JSONMap{
+			"changes": changes,
+		}))
+	}
+	if req.Password != "" {
+		s.audit.Record(ctx, newAuditEvent(models.AuditPasswordChanged, models.AuditSuccess, userID, targetID, nil))
+	}
+
+	return s.requestEmailChange(ctx, updatedUser, newEmail, changeEmail)
+}
+
+// requestEmailChange starts the confirmation flow when requested and reports the pending address on the user
+func (s *userService) requestEmailChange(ctx context.Context, user *models.User, email string, requested bool) (*models.User, error) {
+	if !requested {
+		return user, nil
+	}
+
+	change, err := s.emailChanges.Request(ctx, user, email)
+	if err != nil {
+		return nil, err
+	}
+
+	s.audit.Record(ctx, newAuditEvent(models.AuditEmailChangeRequested, models.AuditSuccess, user.ID, user.ID, models.JSONMap{
+		"new_email": s.audit.Pseudonym(change.NewEmail),
+	}))
+
+	user.PendingEmail = change.NewEmail
+	return user, nil
 }
 
 func (s *userService) ListUsers(
 	ctx context.Context,
 	lastID uuid.UUID,
-	searchEmail string,
+	filter repository.UserFilter,
 	limit int,
 ) ([]models.User, error) {
-	return s.repo.List(ctx, lastID, searchEmail, limit)
+	return s.repo.List(ctx, lastID, filter, limit)
 }
 
 func (s *userService) CreateUser(ctx context.Context, req *dtos.SignUpRequest) (*models.User, error) {
-	// Check if user already exists
-	existingUser, err := s.repo.FindByEmail(ctx, req.Email)
+	addr, err := s.checkEmail(req.Email)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check if user already exists, including accounts still in their deletion grace period
+	taken, err := s.repo.EmailTaken(ctx, addr.Display, addr.Canonical, uuid.Nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to check existing user: %w", err)
 	}
-	if existingUser != nil {
-		return nil, errors.New("user with this email already exists")
+	if taken {
+		return nil, ErrEmailInUse
+	}
+
+	var username *string
+	if req.Username != "" {
+		if err := s.checkUsername(ctx, req.Username, uuid.Nil); err != nil {
+			return nil, err
+		}
+		username = &req.Username
 	}
 
 	// Hash password
@@ -198,14 +711,189 @@ func (s *userService) CreateUser(ctx context.Context, req *dtos.SignUpRequest) (
 	}
 
 	user := &models.User{
-		Email:    req.Email,
-		Password: hashedPassword,
+		Email:          addr.Display,
+		CanonicalEmail: addr.Canonical,
+		Username:       username,
+		Password:       hashedPassword,
+		Role:           models.RoleUser,
+		AuthProvider:   models.AuthProviderLocal,
+		Status:         models.StatusActive,
 	}
 
 	// Create user
 	if err := s.repo.Create(ctx, user); err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, ErrEmailInUse
+		}
 		return nil, fmt.Errorf("failed to create user: %w", err)
 	}
 
+	s.audit.Record(ctx, newAuditEvent(models.AuditSignUp, models.AuditSuccess, user.ID, user.ID, models.JSONMap{
+		"provider": models.AuthProviderLocal,
+	}))
+
 	return user, nil
 }
+
+// checkEmail normalizes an address a user wants to sign up or switch to and applies the domain rules
+func (s *userService) checkEmail(email string) (emailaddr.Address, error) {
+	addr, err := s.emails.Normalize(email)
+	if err != nil {
+		return emailaddr.Address{}, err
+	}
+	if err := s.emails.CheckDomain(addr); err != nil {
+		return emailaddr.Address{}, err
+	}
+	// Directory and SSO accounts are provisioned on first sign-in, never claimed with a local password
+	if s.directories != nil && s.directories.Managed(addr.Display) {
+		return emailaddr.Address{}, ErrDirectoryManagedEmail
+	}
+	return addr, nil
+}
+
+func (s *userService) CheckUsername(ctx context.Context, username string) error {
+	return s.checkUsername(ctx, username, uuid.Nil)
+}
+
+// checkUsername validates the username and makes sure no account other than excludeID holds it
+func (s *userService) checkUsername(ctx context.Context, username string, excludeID uuid.UUID) error {
+	if err := validateUsername(username); err != nil {
+		return err
+	}
+
+	taken, err := s.repo.UsernameTaken(ctx, username, excludeID)
+	if err != nil {
+		return fmt.Errorf("failed to check username: %w", err)
+	}
+	if taken {
+		return ErrUsernameTaken
+	}
+	return nil
+}
+
+// Impersonate issues a short-lived access token for the target user on behalf of an admin.
+// The token is bound to the admin's session, so signing the admin out also ends the impersonation.
+func (s *userService) Impersonate(
+	ctx context.Context,
+	adminID, targetID uuid.UUID,
+	adminSessionID string,
+) (*dtos.ImpersonationResponse, error) {
+	if reqctx.ImpersonatorFrom(ctx) != "" {
+		return nil, ErrImpersonationForbidden
+	}
+	if adminID == targetID {
+		return nil, ErrCannotImpersonate
+	}
+
+	admin, err := s.repo.FindByID(ctx, adminID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find admin: %w", err)
+	}
+	if admin == nil || admin.Role != models.RoleAdmin {
+		return nil, ErrUnauthorized
+	}
+
+	target, err := s.repo.FindByID(ctx, targetID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+	if target == nil {
+		return nil, ErrUserNotFound
+	}
+
+	// Admins cannot be impersonated, so an impersonation token never carries admin privileges
+	if target.Role == models.RoleAdmin {
+		return nil, ErrCannotImpersonate
+	}
+
+	accessToken, expiresAt, err := s.jwtManager.GenerateImpersonationToken(
+		utils.TokenSubject{
+			UserID:    target.ID.String(),
+			Email:     target.Email,
+			Role:      target.Role,
+			SessionID: adminSessionID,
+		},
+		utils.ActorClaim{
+			Subject: admin.ID.String(),
+			Email:   admin.Email,
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+
+	s.audit.Record(ctx, newAuditEvent(models.AuditImpersonation, models.AuditSuccess, admin.ID, target.ID, models.JSONMap{
+		"expires_at": expiresAt,
+	}))
+
+	return &dtos.ImpersonationResponse{
+		AccessToken:    accessToken,
+		ExpiresAt:      expiresAt,
+		UserID:         target.ID,
+		ImpersonatorID: admin.ID,
+	}, nil
+}
+
+// setProfileFields adds the profile fields present in the request to the update
+func setProfileFields(updateFields map[string]interface{}, req *dtos.UpdateUserRequest) {
+	fields := map[string]*string{
+		"display_name": req.DisplayName,
+		"given_name":   req.GivenName,
+		"family_name":  req.FamilyName,
+		"avatar_url":   req.AvatarURL,
+		"timezone":     req.Timezone,
+		"phone_number": req.PhoneNumber,
+	}
+	for column, value := range fields {
+		if value != nil {
+			updateFields[column] = strings.TrimSpace(*value)
+		}
+	}
+
+	if req.Locale != nil {
+		locale := strings.TrimSpace(*req.Locale)
+		// Store the canonical form, e.g. "en-us" becomes "en-US"
+		if tag, err := language.Parse(locale); err == nil {
+			locale = tag.String()
+		}
+		updateFields["locale"] = locale
+	}
+}
+
+// profileChanges lists the profile fields that differ between two versions of a user as {field: {old, new}}.
+// Values that identify the person are passed through pseudonym; locale and timezone are kept as they are.
+func profileChanges(before, after *models.User, pseudonym func(string) string) models.JSONMap {
+	fields := []struct {
+		name     string
+		old, new string
+		personal bool
+	}{
+		{"username", stringValue(before.Username), stringValue(after.Username), true},
+		{"display_name", before.DisplayName, after.DisplayName, true},
+		{"given_name", before.GivenName, after.GivenName, true},
+		{"family_name", before.FamilyName, after.FamilyName, true},
+		{"avatar_url", before.AvatarURL, after.AvatarURL, true},
+		{"locale", before.Locale, after.Locale, false},
+		{"timezone", before.Timezone, after.Timezone, false},
+		{"phone_number", before.PhoneNumber, after.PhoneNumber, true},
+	}
+
+	changes := models.JSONMap{}
+	for _, field := range fields {
+		if field.old == field.new {
+			continue
+		}
+		if field.personal {
+			field.old, field.new = pseudonym(field.old), pseudonym(field.new)
+		}
+		changes[field.name] = map[string]string{"old": field.old, "new": field.new}
+	}
+	return changes
+}
+
+func stringValue(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
@@ -0,0 +1,174 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+	"user-management/internal/config"
+	"user-management/internal/dtos"
+	"user-management/internal/models"
+	"user-management/internal/repository"
+	"user-management/internal/reqctx"
+	"user-management/internal/utils"
+
+	"github.com/google/uuid"
+)
+
+// memoryCache is a minimal cache.Cache for tests
+type memoryCache struct {
+	mu     sync.Mutex
+	values map[string]string
+}
+
+func newMemoryCache() *memoryCache {
+	return &memoryCache{values: make(map[string]string)}
+}
+
+func (m *memoryCache) Get(_ context.Context, key string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	value, ok := m.values[key]
+	if !ok {
+		return "", errors.New("cache miss")
+	}
+	return value, nil
+}
+
+func (m *memoryCache) Set(_ context.Context, key string, value interface{}, _ time.Duration) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.values[key] = string(data)
+	return nil
+}
+
+func (m *memoryCache) Delete(_ context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.values, key)
+	return nil
+}
+
+func (m *memoryCache) GetDel(ctx context.Context, key string) (string, error) {
+	value, err := m.Get(ctx, key)
+	if err == nil {
+		_ = m.Delete(ctx, key)
+	}
+	return value, err
+}
+
+func (m *memoryCache) Increment(context.Context, string, time.Duration) (int64, error) {
+	return 0, errors.New("not implemented")
+}
+
+func (m *memoryCache) Close() error { return nil }
+
+func TestValidateTokenRejectsStaleRole(t *testing.T) {
+	ctx := context.Background()
+	cache := newMemoryCache()
+	svc := &userService{cache: cache}
+
+	admin := &models.User{ID: uuid.New(), Role: models.RoleAdmin, Status: models.StatusActive}
+	user := &models.User{ID: uuid.New(), Role: models.RoleUser, Status: models.StatusActive}
+	for _, u := range []*models.User{admin, user} {
+		_ = cache.Set(ctx, userCacheKey(u.ID), u, time.Minute)
+	}
+
+	tests := []struct {
+		name   string
+		claims *utils.Claims
+		want   error
+	}{
+		{"matching role", &utils.Claims{UserID: admin.ID.String(), Role: models.RoleAdmin}, nil},
+		{"escalated claim", &utils.Claims{UserID: user.ID.String(), Role: models.RoleAdmin}, ErrRoleChanged},
+		{"demoted account", &utils.Claims{UserID: admin.ID.String(), Role: models.RoleUser}, ErrRoleChanged},
+		{"impersonation carries the target role", &utils.Claims{
+			UserID: user.ID.String(),
+			Role:   models.RoleUser,
+			Actor:  &utils.ActorClaim{Subject: admin.ID.String()},
+		}, nil},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := svc.ValidateToken(ctx, tc.claims); !errors.Is(err, tc.want) {
+				t.Fatalf("ValidateToken = %v, want %v", err, tc.want)
+			}
+		})
+	}
+
+}
+
+func TestProfileChangesPseudonymizesPersonalFields(t *testing.T) {
+	audit := NewAuditService(nil, &config.AuditConfig{PseudonymKey: "audit-key"})
+	before := &models.User{DisplayName: "Ada", PhoneNumber: "+15550100", Locale: "en"}
+	after := &models.User{DisplayName: "Ada L", PhoneNumber: "", Locale: "fr"}
+
+	changes := profileChanges(before, after, audit.Pseudonym)
+
+	name := changes["display_name"].(map[string]string)
+	if name["old"] != audit.Pseudonym("Ada") || name["new"] != audit.Pseudonym("Ada L") || name["old"] == "Ada" {
+		t.Errorf("display_name = %v", name)
+	}
+	if phone := changes["phone_number"].(map[string]string); phone["new"] != "" {
+		t.Errorf("cleared phone number = %q, want empty", phone["new"])
+	}
+	if locale := changes["locale"].(map[string]string); locale["old"] != "en" || locale["new"] != "fr" {
+		t.Errorf("locale = %v", locale)
+	}
+
+	other := NewAuditService(nil, &config.AuditConfig{PseudonymKey: "other-key"})
+	if other.Pseudonym("Ada") == audit.Pseudonym("Ada") {
+		t.Error("pseudonym does not depend on the key")
+	}
+}
+
+func TestUpdateUserRefusesSignInIdentifiersWhileImpersonating(t *testing.T) {
+	user := &models.User{ID: uuid.New(), Email: "ada@example.com", PhoneNumber: "+447700900123"}
+	svc := &userService{repo: &attributeUserRepository{users: map[uuid.UUID]*models.User{user.ID: user}}}
+	ctx := reqctx.WithImpersonator(context.Background(), uuid.NewString())
+
+	empty, other, name := "", "+447700900999", "ada"
+	requests := map[string]*dtos.UpdateUserRequest{
+		"email":              {Email: "mallory@example.com"},
+		"password":           {Password: "a-new-password"},
+		"username":           {Username: &name},
+		"phone number":       {PhoneNumber: &other},
+		"clear phone number": {PhoneNumber: &empty},
+	}
+	for field, req := range requests {
+		t.Run(field, func(t *testing.T) {
+			if _, err := svc.UpdateUser(ctx, user.ID, user.ID, req); !errors.Is(err, ErrImpersonationForbidden) {
+				t.Fatalf("UpdateUser = %v, want ErrImpersonationForbidden", err)
+			}
+		})
+	}
+}
+
+// deletedLookupRepository records the canonical address deleted accounts are looked up by
+type deletedLookupRepository struct {
+	repository.UserRepository
+	canonical string
+}
+
+func (r *deletedLookupRepository) FindDeletedByCanonicalEmail(_ context.Context, canonical string, _ time.Time) (*models.User, error) {
+	r.canonical = canonical
+	return nil, nil
+}
+
+func TestRestoreAccountLooksUpTheCanonicalAddress(t *testing.T) {
+	repo := &deletedLookupRepository{}
+	svc := &userService{repo: repo, emails: gmailPolicy(t), gracePeriod: time.Hour}
+
+	if _, err := svc.RestoreAccount(context.Background(), "J.Doe+old@googlemail.com", "secret"); !errors.Is(err, errRestoreDenied) {
+		t.Fatalf("RestoreAccount = %v, want errRestoreDenied", err)
+	}
+	if repo.canonical != "jdoe@gmail.com" {
+		t.Fatalf("looked up %q, want jdoe@gmail.com", repo.canonical)
+	}
+}
@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"user-management/internal/auth"
+	"user-management/internal/dtos"
+	"user-management/internal/models"
+	"user-management/internal/repository"
+	"user-management/internal/utils"
+	"user-management/pkg/tracing"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = tracing.Tracer("user-management/internal/service")
+
+// tracedUserService wraps every UserService call in a span
+type tracedUserService struct {
+	next UserService
+}
+
+func NewTracedUserService(next UserService) UserService {
+	return &tracedUserService{next: next}
+}
+
+func startUserSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracer.Start(ctx, "UserService."+name, trace.WithAttributes(attrs...))
+}
+
+func targetAttr(id uuid.UUID) attribute.KeyValue {
+	return attribute.String("user.target_id", id.String())
+}
+
+func (t *tracedUserService) SignIn(ctx context.Context, identifier, password string) (_ *dtos.SignInResponse, err error) {
+	ctx, span := startUserSpan(ctx, "SignIn")
+	defer func() { tracing.End(span, err) }()
+	return t.next.SignIn(ctx, identifier, password)
+}
+
+func (t *tracedUserService) SignInWithIdentity(ctx context.Context, identity *auth.Identity) (_ *dtos.SignInResponse, err error) {
+	ctx, span := startUserSpan(ctx, "SignInWithIdentity", attribute.String("auth.provider", identity.Provider))
+	defer func() { tracing.End(span, err) }()
+	return t.next.SignInWithIdentity(ctx, identity)
+}
+
+func (t *tracedUserService) GetUser(ctx context.Context, userID, targetID uuid.UUID) (_ *models.User, err error) {
+	ctx, span := startUserSpan(ctx, "GetUser", targetAttr(targetID))
+	defer func() { tracing.End(span, err) }()
+	return t.next.GetUser(ctx, userID, targetID)
+}
+
+func (t *tracedUserService) UpdateUser(
+	ctx context.Context,
+	userID, targetID uuid.UUID,
+	req *dtos.UpdateUserRequest,
+) (_ *models.User, err error) {
+	ctx, span := startUserSpan(ctx, "UpdateUser", targetAttr(targetID))
+	defer func() { tracing.End(span, err) }()
+	return t.next.UpdateUser(ctx, userID, targetID, req)
+}
+
+func (t *tracedUserService) ListUsers(
+	ctx context.Context,
+	lastID uuid.UUID,
+	filter repository.UserFilter,
+	limit int,
+) (_ []models.User, err error) {
+	ctx, span := startUserSpan(ctx, "ListUsers", attribute.Int("page.limit", limit))
+	defer func() { tracing.End(span, err) }()
+	return t.next.ListUsers(ctx, lastID, filter, limit)
+}
+
+func (t *tracedUserService) CreateUser(ctx context.Context, req *dtos.SignUpRequest) (_ *models.User, err error) {
+	ctx, span := startUserSpan(ctx, "CreateUser")
+	defer func() { tracing.End(span, err) }()
+	return t.next.CreateUser(ctx, req)
+}
+
+func (t *tracedUserService) CheckUsername(ctx context.Context, username string) (err error) {
+	ctx, span := startUserSpan(ctx, "CheckUsername")
+	defer func() { tracing.End(span, err) }()
+	return t.next.CheckUsername(ctx, username)
+}
+
+func (t *tracedUserService) Impersonate(
+	ctx context.Context,
+	adminID, targetID uuid.UUID,
+	adminSessionID string,
+) (_ *dtos.ImpersonationResponse, err error) {
+	ctx, span := startUserSpan(ctx, "Impersonate", targetAttr(targetID))
+	defer func() { tracing.End(span, err) }()
+	return t.next.Impersonate(ctx, adminID, targetID, adminSessionID)
+}
+
+func (t *tracedUserService) ChangeStatus(
+	ctx context.Context,
+	actorID, targetID uuid.UUID,
+	req *dtos.ChangeStatusRequest,
+) (_ *models.User, err error) {
+	ctx, span := startUserSpan(ctx, "ChangeStatus", targetAttr(targetID), attribute.String("user.status", req.Status))
+	defer func() { tracing.End(span, err) }()
+	return t.next.ChangeStatus(ctx, actorID, targetID, req)
+}
+
+func (t *tracedUserService) ValidateToken(ctx context.Context, claims *utils.Claims) (err error) {
+	ctx, span := startUserSpan(ctx, "ValidateToken")
+	defer func() { tracing.End(span, err) }()
+	return t.next.ValidateToken(ctx, claims)
+}
+
+func (t *tracedUserService) DeleteUser(ctx context.Context, userID, targetID uuid.UUID) (_ *dtos.AccountDeletionResponse, err error) {
+	ctx, span := startUserSpan(ctx, "DeleteUser", targetAttr(targetID))
+	defer func() { tracing.End(span, err) }()
+	return t.next.DeleteUser(ctx, userID, targetID)
+}
+
+func (t *tracedUserService) RestoreAccount(ctx context.Context, email, password string) (_ *models.User, err error) {
+	ctx, span := startUserSpan(ctx, "RestoreAccount")
+	defer func() { tracing.End(span, err) }()
+	return t.next.RestoreAccount(ctx, email, password)
+}
+
+func (t *tracedUserService) RestoreUser(ctx context.Context, actorID, targetID uuid.UUID) (_ *models.User, err error) {
+	ctx, span := startUserSpan(ctx, "RestoreUser", targetAttr(targetID))
+	defer func() { tracing.End(span, err) }()
+	return t.next.RestoreUser(ctx, actorID, targetID)
+}
@@ -0,0 +1,39 @@
+package service
+
+import (
+	"regexp"
+	"strings"
+	"user-management/internal/apperr"
+)
+
+var (
+	ErrInvalidUsername  = apperr.ErrValidation.WithMessage("Invalid username")
+	ErrUsernameReserved = apperr.ErrValidation.WithMessage("Username is reserved")
+	ErrUsernameTaken    = apperr.ErrConflict.WithMessage("Username already in use")
+)
+
+// Letters, digits, "_", "." and "-", starting and ending with a letter or digit. Excluding "@" and
+// a leading "+" keeps usernames distinguishable from emails and phone numbers at sign-in.
+var usernamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]{1,30}[A-Za-z0-9]$`)
+
+// reservedUsernames could be mistaken for the service itself or collide with routes
+var reservedUsernames = map[string]bool{
+	"abuse": true, "account": true, "admin": true, "administrator": true, "api": true,
+	"auth": true, "billing": true, "help": true, "hostmaster": true, "info": true,
+	"login": true, "logout": true, "me": true, "moderator": true, "noreply": true,
+	"no-reply": true, "null": true, "official": true, "postmaster": true, "root": true,
+	"security": true, "self": true, "settings": true, "signin": true, "signup": true,
+	"staff": true, "support": true, "sysadmin": true, "system": true, "undefined": true,
+	"user": true, "users": true, "webmaster": true,
+}
+
+// validateUsername checks the charset and reserved words, case-insensitively
+func validateUsername(username string) error {
+	if !usernamePattern.MatchString(username) {
+		return ErrInvalidUsername
+	}
+	if reservedUsernames[strings.ToLower(username)] {
+		return ErrUsernameReserved
+	}
+	return nil
+}
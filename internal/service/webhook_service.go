@@ -0,0 +1,382 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"user-management/internal/apperr"
+	"user-management/internal/config"
+	"user-management/internal/dtos"
+	"user-management/internal/models"
+	"user-management/internal/repository"
+	"user-management/internal/utils"
+	"user-management/pkg/logger"
+	"user-management/pkg/webhook"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrWebhookNotFound     = apperr.ErrNotFound.WithMessage("Webhook subscription not found")
+	ErrWebhookURLNotPublic = apperr.ErrValidation.WithMessage("Webhook URL must point to a public address")
+)
+
+type WebhookService interface {
+	// CreateSubscription returns the subscription with its generated secret
+	CreateSubscription(ctx context.Context, actorID uuid.UUID, req *dtos.WebhookSubscriptionRequest) (*models.WebhookSubscription, error)
+	ListSubscriptions(ctx context.Context) ([]models.WebhookSubscription, error)
+	GetSubscription(ctx context.Context, id uuid.UUID) (*models.WebhookSubscription, error)
+	UpdateSubscription(ctx context.Context, actorID, id uuid.UUID, req *dtos.WebhookSubscriptionRequest) (*models.WebhookSubscription, error)
+	DeleteSubscription(ctx context.Context, actorID, id uuid.UUID) error
+	// RotateSecret replaces the signing secret and returns the subscription with the new one
+	RotateSecret(ctx context.Context, actorID, id uuid.UUID) (*models.WebhookSubscription, error)
+	ListDeliveries(ctx context.Context, subscriptionID uuid.UUID, status string, cursor uuid.UUID, limit int) ([]models.WebhookDelivery, error)
+	// Replay queues delivered or dead-lettered deliveries again and returns how many were queued
+	Replay(ctx context.Context, actorID, subscriptionID uuid.UUID, req *dtos.WebhookReplayRequest) (int64, error)
+	// Run dispatches outbox events and delivers due webhooks until the context is cancelled
+	Run(ctx context.Context)
+}
+
+type webhookService struct {
+	repo   repository.WebhookRepository
+	client *webhook.Client
+	audit  AuditService
+	cfg    *config.WebhookConfig
+	logger *logger.Logger
+}
+
+func NewWebhookService(
+	repo repository.WebhookRepository,
+	audit AuditService,
+	cfg *config.WebhookConfig,
+	logger *logger.Logger,
+) WebhookService {
+	// Subscription URLs are entered through the API, so deliveries stay off internal networks
+	client := webhook.NewPublicClient(cfg.Timeout)
+	if cfg.AllowPrivateTargets {
+		client = webhook.NewClient(cfg.Timeout)
+	}
+
+	return &webhookService{
+		repo:   repo,
+		client: client,
+		audit:  audit,
+		cfg:    cfg,
+		logger: logger,
+	}
+}
+
+func (s *webhookService) CreateSubscription(
+	ctx context.Context,
+	actorID uuid.UUID,
+	req *dtos.WebhookSubscriptionRequest,
+) (*models.WebhookSubscription, error) {
+	if err := s.checkURL(req.URL); err != nil {
+		return nil, err
+	}
+
+	secret, err := utils.GenerateToken()
+	if err != nil {
+		return nil, err
+	}
+
+	subscription := &models.WebhookSubscription{
+		URL:         req.URL,
+		Secret:      secret,
+		EventTypes:  models.StringList(req.EventTypes),
+		Description: req.Description,
+		Active:      req.Active == nil || *req.Active,
+	}
+	if err := s.repo.CreateSubscription(ctx, subscription); err != nil {
+		return nil, fmt.Errorf("failed to create webhook subscription: %w", err)
+	}
+
+	s.audit.Record(ctx, newAuditEvent(models.AuditWebhookCreated, models.AuditSuccess, actorID, uuid.Nil, models.JSONMap{
+		"subscription_id": subscription.ID,
+		"url":             subscription.URL,
+		"event_types":     subscription.EventTypes,
+	}))
+
+	return subscription, nil
+}
+
+func (s *webhookService) ListSubscriptions(ctx context.Context) ([]models.WebhookSubscription, error) {
+	return s.repo.ListSubscriptions(ctx)
+}
+
+func (s *webhookService) GetSubscription(ctx context.Context, id uuid.UUID) (*models.WebhookSubscription, error) {
+	subscription, err := s.repo.FindSubscription(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find webhook subscription: %w", err)
+	}
+	if subscription == nil {
+		return nil, ErrWebhookNotFound
+	}
+	return subscription, nil
+}
+
+func (s *webhookService) UpdateSubscription(
+	ctx context.Context,
+	actorID, id uuid.UUID,
+	req *dtos.WebhookSubscriptionRequest,
+) (*models.WebhookSubscription, error) {
+	if err := s.checkURL(req.URL); err != nil {
+		return nil, err
+	}
+
+	updated, err := s.repo.UpdateSubscription(ctx, id, map[string]interface{}{
+		"url":         req.URL,
+		"event_types": models.StringList(req.EventTypes),
+		"description": req.Description,
+		"active":      req.Active == nil || *req.Active,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to update webhook subscription: %w", err)
+	}
+	if !updated {
+		return nil, ErrWebhookNotFound
+	}
+
+	s.audit.Record(ctx, newAuditEvent(models.AuditWebhookUpdated, models.AuditSuccess, actorID, uuid.Nil, models.JSONMap{
+		"subscription_id": id,
+		"url":             req.URL,
+		"event_types":     req.EventTypes,
+		"active":          req.Active == nil || *req.Active,
+	}))
+
+	return s.GetSubscription(ctx, id)
+}
+
+// checkURL refuses hosts that are obviously internal. Names resolving to internal addresses are
+// only caught when a delivery connects, since DNS can change after the subscription is saved.
+func (s *webhookService) checkURL(raw string) error {
+	if s.cfg.AllowPrivateTargets {
+		return nil
+	}
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return ErrWebhookURLNotPublic
+	}
+
+	host := strings.ToLower(parsed.Hostname())
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return ErrWebhookURLNotPublic
+	}
+	if ip, err := netip.ParseAddr(host); err == nil && !webhook.PublicAddress(ip) {
+		return ErrWebhookURLNotPublic
+	}
+	return nil
+}
+
+func (s *webhookService) DeleteSubscription(ctx context.Context, actorID, id uuid.UUID) error {
+	deleted, err := s.repo.DeleteSubscription(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook subscription: %w", err)
+	}
+	if !deleted {
+		return ErrWebhookNotFound
+	}
+
+	s.audit.Record(ctx, newAuditEvent(models.AuditWebhookDeleted, models.AuditSuccess, actorID, uuid.Nil, models.JSONMap{
+		"subscription_id": id,
+	}))
+	return nil
+}
+
+func (s *webhookService) RotateSecret(ctx context.Context, actorID, id uuid.UUID) (*models.WebhookSubscription, error) {
+	secret, err := utils.GenerateToken()
+	if err != nil {
+		return nil, err
+	}
+
+	updated, err := s.repo.UpdateSubscription(ctx, id, map[string]interface{}{"secret": secret})
+	if err != nil {
+		return nil, fmt.Errorf("failed to rotate webhook secret: %w", err)
+	}
+	if !updated {
+		return nil, ErrWebhookNotFound
+	}
+
+	s.audit.Record(ctx, newAuditEvent(models.AuditWebhookSecretRotated, models.AuditSuccess, actorID, uuid.Nil, models.JSONMap{
+		"subscription_id": id,
+	}))
+
+	return s.GetSubscription(ctx, id)
+}
+
+func (s *webhookService) ListDeliveries(
+	ctx context.Context,
+	subscriptionID uuid.UUID,
+	status string,
+	cursor uuid.UUID,
+	limit int,
+) ([]models.WebhookDelivery, error) {
+	if _, err := s.GetSubscription(ctx, subscriptionID); err != nil {
+		return nil, err
+	}
+	return s.repo.ListDeliveries(ctx, subscriptionID, status, cursor, limit)
+}
+
+func (s *webhookService) Replay(ctx context.Context, actorID, subscriptionID uuid.UUID, req *dtos.WebhookReplayRequest) (int64, error) {
+	if _, err := s.GetSubscription(ctx, subscriptionID); err != nil {
+		return 0, err
+	}
+
+	queued, err := s.repo.Replay(ctx, subscriptionID, req.DeliveryIDs, req.Status)
+	if err != nil {
+		return 0, fmt.Errorf("failed to replay webhook deliveries: %w", err)
+	}
+
+	s.audit.Record(ctx, newAuditEvent(models.AuditWebhookReplayed, models.AuditSuccess, actorID, uuid.Nil, models.JSONMap{
+		"subscription_id": subscriptionID,
+		"status":          req.Status,
+		"queued":          queued,
+	}))
+	return queued, nil
+}
+
+func (s *webhookService) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		s.fanOut(ctx)
+		s.deliverDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// fanOut creates deliveries for every undispatched outbox event
+func (s *webhookService) fanOut(ctx context.Context) {
+	for ctx.Err() == nil {
+		dispatched, err := s.repo.FanOut(ctx, s.cfg.BatchSize)
+		if err != nil {
+			if ctx.Err() == nil {
+				s.logger.Error().Err(err).Msg("Failed to dispatch outbox events")
+			}
+			return
+		}
+		if dispatched < s.cfg.BatchSize {
+			return
+		}
+	}
+}
+
+// deliverDue sends due deliveries, one batch at a time with the requests of a batch in parallel.
+// Deliveries to the same endpoint are therefore not guaranteed to arrive in order.
+func (s *webhookService) deliverDue(ctx context.Context) {
+	// Claimed deliveries stay hidden from other instances for longer than an attempt can take
+	lease := s.cfg.Timeout + time.Minute
+
+	for ctx.Err() == nil {
+		deliveries, err := s.repo.ClaimDue(ctx, s.cfg.BatchSize, lease)
+		if err != nil {
+			if ctx.Err() == nil {
+				s.logger.Error().Err(err).Msg("Failed to claim webhook deliveries")
+			}
+			return
+		}
+
+		var wg sync.WaitGroup
+		for i := range deliveries {
+			wg.Add(1)
+			go func(delivery *models.WebhookDelivery) {
+				defer wg.Done()
+				s.deliver(ctx, delivery)
+			}(&deliveries[i])
+		}
+		wg.Wait()
+
+		if len(deliveries) < s.cfg.BatchSize {
+			return
+		}
+	}
+}
+
+func (s *webhookService) deliver(ctx context.Context, delivery *models.WebhookDelivery) {
+	log := s.logger.With().
+		Str("delivery_id", delivery.ID.String()).
+		Str("subscription_id", delivery.SubscriptionID.String()).
+		Logger()
+
+	statusCode, err := s.send(ctx, delivery)
+	if err == nil {
+		if err := s.repo.MarkDelivered(ctx, delivery.ID, statusCode); err != nil {
+			log.Error().Err(err).Msg("Failed to mark webhook delivered")
+		}
+		return
+	}
+	if ctx.Err() != nil {
+		// Shutting down; the lease expires and the attempt is repeated later
+		return
+	}
+
+	var code *int
+	if statusCode != 0 {
+		code = &statusCode
+	}
+
+	attempts := delivery.Attempts + 1
+	var next *time.Time
+	if attempts < s.cfg.MaxAttempts {
+		at := time.Now().Add(s.backoff(attempts))
+		next = &at
+	}
+
+	if err := s.repo.MarkFailed(ctx, delivery.ID, code, truncate(err.Error(), 1000), next); err != nil {
+		log.Error().Err(err).Msg("Failed to record webhook failure")
+		return
+	}
+
+	if next == nil {
+		log.Warn().Err(err).Int("attempts", attempts).Msg("Webhook delivery moved to dead letter")
+	} else {
+		log.Debug().Err(err).Int("attempts", attempts).Time("next_attempt_at", *next).Msg("Webhook delivery failed")
+	}
+}
+
+func (s *webhookService) send(ctx context.Context, delivery *models.WebhookDelivery) (int, error) {
+	if delivery.Subscription == nil || delivery.Event == nil {
+		return 0, errors.New("subscription or event no longer exists")
+	}
+	if !delivery.Subscription.Active {
+		return 0, errors.New("subscription is inactive")
+	}
+
+	body, err := json.Marshal(delivery.Event)
+	if err != nil {
+		return 0, err
+	}
+
+	header := http.Header{}
+	header.Set("X-Webhook-ID", delivery.ID.String())
+	header.Set("X-Webhook-Event", delivery.Event.Type)
+	header.Set("X-Webhook-Attempt", strconv.Itoa(delivery.Attempts+1))
+
+	return s.client.Post(ctx, delivery.Subscription.URL, []byte(delivery.Subscription.Secret), body, header)
+}
+
+// backoff doubles the delay after every failed attempt, up to the configured maximum, with up to 10% jitter
+func (s *webhookService) backoff(attempts int) time.Duration {
+	delay := s.cfg.BackoffMax
+	if attempts < 32 {
+		if d := s.cfg.BackoffBase << (attempts - 1); d > 0 && d < delay {
+			delay = d
+		}
+	}
+	return delay + rand.N(delay/10+1)
+}
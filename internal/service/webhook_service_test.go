@@ -0,0 +1,64 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+	"user-management/internal/config"
+	"user-management/internal/dtos"
+	"user-management/internal/models"
+	"user-management/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+type fakeWebhookRepository struct {
+	repository.WebhookRepository
+	created int
+}
+
+func (r *fakeWebhookRepository) CreateSubscription(context.Context, *models.WebhookSubscription) error {
+	r.created++
+	return nil
+}
+
+func TestCreateSubscriptionRefusesInternalURLs(t *testing.T) {
+	tests := []struct {
+		url  string
+		want error
+	}{
+		{"https://crm.example.com/hooks", nil},
+		{"https://93.184.216.34/hooks", nil},
+		{"http://localhost:8080/hooks", ErrWebhookURLNotPublic},
+		{"http://api.localhost/hooks", ErrWebhookURLNotPublic},
+		{"http://127.0.0.1/hooks", ErrWebhookURLNotPublic},
+		{"http://169.254.169.254/latest/meta-data/", ErrWebhookURLNotPublic},
+		{"http://10.0.0.5/hooks", ErrWebhookURLNotPublic},
+		{"http://[::1]:9000/hooks", ErrWebhookURLNotPublic},
+		{"http://[fd00:ec2::254]/hooks", ErrWebhookURLNotPublic},
+	}
+	for _, tc := range tests {
+		t.Run(tc.url, func(t *testing.T) {
+			repo := &fakeWebhookRepository{}
+			svc := NewWebhookService(repo, &recordingAudit{}, &config.WebhookConfig{Timeout: time.Second}, nopLogger())
+
+			_, err := svc.CreateSubscription(context.Background(), uuid.New(), &dtos.WebhookSubscriptionRequest{URL: tc.url})
+			if !errors.Is(err, tc.want) {
+				t.Fatalf("CreateSubscription = %v, want %v", err, tc.want)
+			}
+			if created := repo.created == 1; created != (tc.want == nil) {
+				t.Fatalf("subscription saved = %v", created)
+			}
+		})
+	}
+}
+
+func TestCreateSubscriptionAllowsPrivateTargetsWhenConfigured(t *testing.T) {
+	repo := &fakeWebhookRepository{}
+	svc := NewWebhookService(repo, &recordingAudit{}, &config.WebhookConfig{Timeout: time.Second, AllowPrivateTargets: true}, nopLogger())
+
+	if _, err := svc.CreateSubscription(context.Background(), uuid.New(), &dtos.WebhookSubscriptionRequest{URL: "http://localhost:8080/hooks"}); err != nil {
+		t.Fatal(err)
+	}
+}
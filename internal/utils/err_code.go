@@ -1,14 +1,17 @@
 package utils
 
 const (
-	ErrCodeInternalServerError = "INTERNAL_SERVER_ERROR"
-	ErrCodeUnauthorized        = "UNAUTHORIZED"
-	ErrCodeForbidden           = "FORBIDDEN"
-	ErrCodeNotFound            = "NOT_FOUND"
-	ErrCodeConflict            = "CONFLICT"
-	ErrCodeValidationError     = "VALIDATION_ERROR"
-	ErrCodeInvalidID           = "INVALID_ID"
-	ErrCodeInvalidCursor       = "INVALID_CURSOR"
-	ErrCodeAuthFailed          = "AUTHENTICATION_FAILED"
-	ErrCodeSignupFailed        = "SIGNUP_FAILED"
+	ErrCodeInternalServerError     = "INTERNAL_SERVER_ERROR"
+	ErrCodeUnauthorized            = "UNAUTHORIZED"
+	ErrCodeForbidden               = "FORBIDDEN"
+	ErrCodeNotFound                = "NOT_FOUND"
+	ErrCodeConflict                = "CONFLICT"
+	ErrCodeValidationError         = "VALIDATION_ERROR"
+	ErrCodeInvalidID               = "INVALID_ID"
+	ErrCodeInvalidCursor           = "INVALID_CURSOR"
+	ErrCodeAuthFailed              = "AUTHENTICATION_FAILED"
+	ErrCodeRateLimitExceeded       = "RATE_LIMIT_EXCEEDED"
+	ErrCodeInvalidToken            = "INVALID_TOKEN"
+	ErrCodeStepUpRequired          = "STEP_UP_REQUIRED"
+	ErrCodeInvalidStatusTransition = "INVALID_STATUS_TRANSITION"
 )
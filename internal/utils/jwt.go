@@ -10,57 +10,115 @@ import (
 )
 
 type JWTManager struct {
-	secretKey            string
-	accessTokenDuration  time.Duration
-	refreshTokenDuration time.Duration
-	issuer               string
+	secretKey                  string
+	accessTokenDuration        time.Duration
+	refreshTokenDuration       time.Duration
+	impersonationTokenDuration time.Duration
+	issuer                     string
 }
 
 func NewJWTManager(cfg *config.JWTConfig) *JWTManager {
 	return &JWTManager{
-		secretKey:            cfg.Secret,
-		accessTokenDuration:  cfg.AccessExpiration,
-		refreshTokenDuration: cfg.RefreshExpiration,
-		issuer:               cfg.Issuer,
+		secretKey:                  cfg.Secret,
+		accessTokenDuration:        cfg.AccessExpiration,
+		refreshTokenDuration:       cfg.RefreshExpiration,
+		impersonationTokenDuration: cfg.ImpersonationExpiration,
+		issuer:                     cfg.Issuer,
 	}
 }
 
+// Authentication method references for the "amr" claim (RFC 8176)
+const (
+	AMRPassword  = "pwd"
+	AMRMFA       = "mfa"
+	AMRFederated = "fed"
+	AMREmailLink = "email"
+)
+
+// TokenSubject identifies the user and session a token is issued for
+type TokenSubject struct {
+	UserID    string
+	Email     string
+	Role      string
+	SessionID string
+	AuthTime  time.Time
+	AMR       []string
+}
+
+// ActorClaim identifies the party acting on behalf of the subject (RFC 8693 "act")
+type ActorClaim struct {
+	Subject string `json:"sub"`
+	Email   string `json:"email,omitempty"`
+}
+
 type Claims struct {
-	UserID string `json:"user_id"`
-	Email  string `json:"email"`
+	UserID    string           `json:"user_id"`
+	Email     string           `json:"email"`
+	Role      string           `json:"role,omitempty"`
+	SessionID string           `json:"sid,omitempty"`
+	Actor     *ActorClaim      `json:"act,omitempty"`
+	AuthTime  *jwt.NumericDate `json:"auth_time,omitempty"`
+	AMR       []string         `json:"amr,omitempty"`
 	jwt.RegisteredClaims
 }
 
-func (m *JWTManager) GenerateAccessToken(userID string, email string) (string, error) {
-	claims := Claims{
-		UserID: userID,
-		Email:  email,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(m.accessTokenDuration)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			NotBefore: jwt.NewNumericDate(time.Now()),
-			Issuer:    m.issuer,
-			Subject:   userID,
-		},
+// AuthenticatedWithin reports whether the user authenticated no longer than maxAge ago
+func (c *Claims) AuthenticatedWithin(maxAge time.Duration) bool {
+	return c.AuthTime != nil && time.Since(c.AuthTime.Time) <= maxAge
+}
+
+// HasAMR reports whether the user authenticated with the given method
+func (c *Claims) HasAMR(method string) bool {
+	for _, amr := range c.AMR {
+		if amr == method {
+			return true
+		}
 	}
+	return false
+}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(m.secretKey))
+// Impersonated reports whether the token was issued to an actor on behalf of the subject
+func (c *Claims) Impersonated() bool {
+	return c.Actor != nil
+}
+
+func (m *JWTManager) GenerateAccessToken(subject TokenSubject) (string, error) {
+	return m.generate(subject, nil, m.accessTokenDuration)
 }
 
-func (m *JWTManager) GenerateRefreshToken(userID string, email string) (string, error) {
+func (m *JWTManager) GenerateRefreshToken(subject TokenSubject) (string, error) {
+	return m.generate(subject, nil, m.refreshTokenDuration)
+}
+
+// GenerateImpersonationToken issues a short-lived access token for subject carrying the actor in the "act" claim
+func (m *JWTManager) GenerateImpersonationToken(subject TokenSubject, actor ActorClaim) (string, time.Time, error) {
+	expiresAt := time.Now().Add(m.impersonationTokenDuration)
+	token, err := m.generate(subject, &actor, m.impersonationTokenDuration)
+	return token, expiresAt, err
+}
+
+func (m *JWTManager) generate(subject TokenSubject, actor *ActorClaim, duration time.Duration) (string, error) {
+	now := time.Now()
 	claims := Claims{
-		UserID: userID,
-		Email:  email,
+		UserID:    subject.UserID,
+		Email:     subject.Email,
+		Role:      subject.Role,
+		SessionID: subject.SessionID,
+		Actor:     actor,
+		AMR:       subject.AMR,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(m.refreshTokenDuration)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			NotBefore: jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(now.Add(duration)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
 			Issuer:    m.issuer,
-			Subject:   userID,
+			Subject:   subject.UserID,
 		},
 	}
 
+	if !subject.AuthTime.IsZero() {
+		claims.AuthTime = jwt.NewNumericDate(subject.AuthTime)
+	}
+
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	return token.SignedString([]byte(m.secretKey))
 }
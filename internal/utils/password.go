@@ -2,6 +2,8 @@ package utils
 
 import (
 	"errors"
+	"time"
+	"user-management/pkg/metrics"
 
 	"golang.org/x/crypto/bcrypt"
 )
@@ -22,6 +24,7 @@ func NewPasswordManager(cost int) *PasswordManager {
 }
 
 func (pm *PasswordManager) Hash(password string) (string, error) {
+	defer observeBcrypt("hash", time.Now())
 	hashedBytes, err := bcrypt.GenerateFromPassword([]byte(password), pm.cost)
 	if err != nil {
 		return "", err
@@ -30,9 +33,14 @@ func (pm *PasswordManager) Hash(password string) (string, error) {
 }
 
 func (pm *PasswordManager) Compare(hashedPassword, password string) error {
+	defer observeBcrypt("compare", time.Now())
 	err := bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
 	if err != nil {
 		return ErrInvalidPassword
 	}
 	return nil
 }
+
+func observeBcrypt(operation string, start time.Time) {
+	metrics.PasswordHashDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+}
@@ -0,0 +1,28 @@
+// Package blobstore stores binary objects such as uploaded images.
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"io"
+	"path"
+	"strings"
+)
+
+var ErrInvalidKey = errors.New("invalid object key")
+
+// BlobStore writes and deletes objects addressed by slash-separated keys
+type BlobStore interface {
+	Put(ctx context.Context, key, contentType string, body io.Reader, size int64) error
+	Delete(ctx context.Context, key string) error
+	// URL returns the public URL an object is served from
+	URL(key string) string
+}
+
+// validKey rejects keys that could escape the store's namespace
+func validKey(key string) bool {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return false
+	}
+	return path.Clean(key) == key && !strings.HasPrefix(key, "../") && key != ".."
+}
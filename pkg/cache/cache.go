@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/redis/go-redis/extra/redisotel/v9"
 	"github.com/redis/go-redis/v9"
 )
 
@@ -13,6 +14,10 @@ type Cache interface {
 	Get(ctx context.Context, key string) (string, error)
 	Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error
 	Delete(ctx context.Context, key string) error
+	// GetDel returns the value and deletes the key in one step, so only one caller can consume it
+	GetDel(ctx context.Context, key string) (string, error)
+	// Increment atomically increments a counter, starting its expiration on first use
+	Increment(ctx context.Context, key string, expiration time.Duration) (int64, error)
 	Close() error
 }
 
@@ -40,6 +45,11 @@ func NewRedisCache(url string) (*RedisCache, error) {
 	}, nil
 }
 
+// InstrumentTracing adds a span for every Redis command. Arguments are left out as they may hold cached user data.
+func (r *RedisCache) InstrumentTracing() error {
+	return redisotel.InstrumentTracing(r.client, redisotel.WithDBStatement(false))
+}
+
 func (r *RedisCache) Get(ctx context.Context, key string) (string, error) {
 	return r.client.Get(ctx, key).Result()
 }
@@ -56,6 +66,30 @@ func (r *RedisCache) Delete(ctx context.Context, key string) error {
 	return r.client.Del(ctx, key).Err()
 }
 
+func (r *RedisCache) GetDel(ctx context.Context, key string) (string, error) {
+	return r.client.GetDel(ctx, key).Result()
+}
+
+func (r *RedisCache) Increment(ctx context.Context, key string, expiration time.Duration) (int64, error) {
+	pipe := r.client.TxPipeline()
+	incr := pipe.Incr(ctx, key)
+	pipe.ExpireNX(ctx, key, expiration)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+	return incr.Val(), nil
+}
+
+// Ping checks that Redis answers commands
+func (r *RedisCache) Ping(ctx context.Context) error {
+	return r.client.Ping(ctx).Err()
+}
+
 func (r *RedisCache) Close() error {
 	return r.client.Close()
 }
+
+// Client returns the underlying connection, for components that share it
+func (r *RedisCache) Client() *redis.Client {
+	return r.client
+}
@@ -8,8 +8,11 @@ import (
 	"user-management/internal/config"
 
 	"github.com/pressly/goose/v3"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+	"gorm.io/plugin/opentelemetry/tracing"
 )
 
 type Database struct {
@@ -21,7 +24,10 @@ func NewDatabase(cfg *config.DatabaseConfig) (*Database, error) {
 	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=%s",
 		cfg.Host, cfg.User, cfg.Password, cfg.Name, cfg.Port, cfg.SSLMode)
 
-	gormConfig := &gorm.Config{}
+	gormConfig := &gorm.Config{
+		// Surface constraint violations as gorm.ErrDuplicatedKey and friends
+		TranslateError: true,
+	}
 
 	db, err := gorm.Open(postgres.Open(dsn), gormConfig)
 	if err != nil {
@@ -80,10 +86,47 @@ func (d *Database) RunMigrations(migrationsDir string) error {
 	return nil
 }
 
-func (d *Database) HealthCheck() error {
+func (d *Database) HealthCheck(ctx context.Context) error {
 	sqlDB, err := d.DB.DB()
 	if err != nil {
 		return err
 	}
-	return sqlDB.Ping()
+	return sqlDB.PingContext(ctx)
+}
+
+// MigrationVersion returns the goose version applied to the database
+func (d *Database) MigrationVersion(ctx context.Context) (int64, error) {
+	sqlDB, err := d.DB.DB()
+	if err != nil {
+		return 0, err
+	}
+	return goose.GetDBVersionContext(ctx, sqlDB)
+}
+
+// LatestMigration returns the highest migration version in migrationsDir
+func LatestMigration(migrationsDir string) (int64, error) {
+	migrations, err := goose.CollectMigrations(migrationsDir, 0, goose.MaxVersion)
+	if err != nil {
+		return 0, fmt.Errorf("failed to collect migrations: %w", err)
+	}
+
+	last, err := migrations.Last()
+	if err != nil {
+		return 0, fmt.Errorf("failed to find latest migration: %w", err)
+	}
+	return last.Version, nil
+}
+
+// RegisterMetrics exposes the connection pool statistics from sql.DB.Stats()
+func (d *Database) RegisterMetrics(registerer prometheus.Registerer) error {
+	sqlDB, err := d.DB.DB()
+	if err != nil {
+		return err
+	}
+	return registerer.Register(collectors.NewDBStatsCollector(sqlDB, d.cfg.Name))
+}
+
+// InstrumentTracing adds a span for every GORM query. Query parameters are left out of the spans.
+func (d *Database) InstrumentTracing() error {
+	return d.DB.Use(tracing.NewPlugin(tracing.WithoutQueryVariables(), tracing.WithoutMetrics()))
 }
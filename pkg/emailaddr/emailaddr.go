@@ -0,0 +1,174 @@
+// Package emailaddr normalizes email addresses and matches their domains against lists.
+package emailaddr
+
+import (
+	"bufio"
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/net/idna"
+	"golang.org/x/text/unicode/norm"
+)
+
+var ErrInvalidAddress = errors.New("invalid email address")
+
+// Address is an email address in two forms
+type Address struct {
+	// Display is what the user typed, NFC-normalized and lowercased, with the domain in Unicode
+	Display string
+	// Canonical identifies the mailbox: provider rules applied and the domain in ASCII (punycode).
+	// Two addresses that deliver to the same mailbox share a canonical form.
+	Canonical string
+	// Domain is the ASCII domain of the address
+	Domain string
+}
+
+// Rule describes how a mail provider treats the local part of its addresses
+type Rule struct {
+	// IgnoreDots drops "." from the local part, e.g. j.doe@gmail.com is jdoe@gmail.com
+	IgnoreDots bool
+	// StripPlus drops everything from the first "+", e.g. jdoe+news@gmail.com is jdoe@gmail.com
+	StripPlus bool
+	// Domain replaces the domain in the canonical form, e.g. googlemail.com is gmail.com
+	Domain string
+}
+
+// ParseRule reads a comma-separated rule such as "dots,plus,domain=gmail.com"
+func ParseRule(spec string) (Rule, error) {
+	var rule Rule
+	for _, option := range strings.Split(spec, ",") {
+		option = strings.TrimSpace(option)
+		switch {
+		case option == "":
+		case option == "dots":
+			rule.IgnoreDots = true
+		case option == "plus":
+			rule.StripPlus = true
+		case strings.HasPrefix(option, "domain="):
+			domain, err := idna.Lookup.ToASCII(strings.TrimPrefix(option, "domain="))
+			if err != nil || domain == "" {
+				return Rule{}, fmt.Errorf("invalid domain in rule %q", spec)
+			}
+			rule.Domain = domain
+		default:
+			return Rule{}, fmt.Errorf("unknown option %q in rule %q", option, spec)
+		}
+	}
+	return rule, nil
+}
+
+// Normalizer turns addresses into their display and canonical forms
+type Normalizer struct {
+	rules map[string]Rule
+}
+
+// NewNormalizer applies rules keyed by the ASCII domain they belong to
+func NewNormalizer(rules map[string]Rule) *Normalizer {
+	normalized := make(map[string]Rule, len(rules))
+	for domain, rule := range rules {
+		if ascii, err := idna.Lookup.ToASCII(domain); err == nil {
+			normalized[ascii] = rule
+		}
+	}
+	return &Normalizer{rules: normalized}
+}
+
+func (n *Normalizer) Normalize(email string) (Address, error) {
+	email = norm.NFC.String(strings.TrimSpace(email))
+
+	at := strings.LastIndex(email, "@")
+	if at <= 0 || at == len(email)-1 {
+		return Address{}, ErrInvalidAddress
+	}
+	local, domain := strings.ToLower(email[:at]), email[at+1:]
+
+	asciiDomain, err := idna.Lookup.ToASCII(domain)
+	if err != nil || !strings.Contains(asciiDomain, ".") {
+		return Address{}, ErrInvalidAddress
+	}
+	unicodeDomain, err := idna.Lookup.ToUnicode(asciiDomain)
+	if err != nil {
+		return Address{}, ErrInvalidAddress
+	}
+
+	canonicalLocal, canonicalDomain := local, asciiDomain
+	if rule, ok := n.rules[asciiDomain]; ok {
+		if rule.StripPlus {
+			if plus := strings.Index(canonicalLocal, "+"); plus > 0 {
+				canonicalLocal = canonicalLocal[:plus]
+			}
+		}
+		if rule.IgnoreDots {
+			canonicalLocal = strings.ReplaceAll(canonicalLocal, ".", "")
+		}
+		if rule.Domain != "" {
+			canonicalDomain = rule.Domain
+		}
+		if canonicalLocal == "" {
+			return Address{}, ErrInvalidAddress
+		}
+	}
+
+	return Address{
+		Display:   local + "@" + unicodeDomain,
+		Canonical: canonicalLocal + "@" + canonicalDomain,
+		Domain:    asciiDomain,
+	}, nil
+}
+
+// DomainSet matches domains and their subdomains
+type DomainSet map[string]bool
+
+// NewDomainSet builds a set from domain names, skipping invalid ones
+func NewDomainSet(domains []string) DomainSet {
+	set := make(DomainSet, len(domains))
+	for _, domain := range domains {
+		if ascii, err := idna.Lookup.ToASCII(strings.TrimSpace(domain)); err == nil && ascii != "" {
+			set[ascii] = true
+		}
+	}
+	return set
+}
+
+// ReadDomainSet reads one domain per line; blank lines and lines starting with "#" are ignored
+func ReadDomainSet(r io.Reader) (DomainSet, error) {
+	var domains []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		domains = append(domains, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return NewDomainSet(domains), nil
+}
+
+// Contains reports whether the ASCII domain or one of its parent domains is in the set
+func (s DomainSet) Contains(domain string) bool {
+	for {
+		if s[domain] {
+			return true
+		}
+		dot := strings.Index(domain, ".")
+		if dot < 0 {
+			return false
+		}
+		domain = domain[dot+1:]
+	}
+}
+
+//go:embed disposable_domains.txt
+var bundledDisposableDomains string
+
+// DisposableDomains returns the bundled list of disposable email providers
+func DisposableDomains() DomainSet {
+	set, _ := ReadDomainSet(strings.NewReader(bundledDisposableDomains))
+	return set
+}
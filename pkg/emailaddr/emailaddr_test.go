@@ -0,0 +1,162 @@
+package emailaddr
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseRule(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    Rule
+		wantErr bool
+	}{
+		{"", Rule{}, false},
+		{"dots", Rule{IgnoreDots: true}, false},
+		{"plus", Rule{StripPlus: true}, false},
+		{" dots , plus ", Rule{IgnoreDots: true, StripPlus: true}, false},
+		{"dots,plus,domain=gmail.com", Rule{IgnoreDots: true, StripPlus: true, Domain: "gmail.com"}, false},
+		{"domain=Bücher.Example", Rule{Domain: "xn--bcher-kva.example"}, false},
+		{"domain=", Rule{}, true},
+		{"alias=gmail.com", Rule{}, true},
+		{"dots,hyphens", Rule{}, true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.spec, func(t *testing.T) {
+			rule, err := ParseRule(tc.spec)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("ParseRule = %+v, want an error", rule)
+				}
+				return
+			}
+			if err != nil || rule != tc.want {
+				t.Fatalf("ParseRule = %+v, %v, want %+v", rule, err, tc.want)
+			}
+		})
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	normalizer := NewNormalizer(map[string]Rule{
+		"gmail.com":      {IgnoreDots: true, StripPlus: true},
+		"googlemail.com": {IgnoreDots: true, StripPlus: true, Domain: "gmail.com"},
+		"fastmail.com":   {StripPlus: true},
+		"bücher.example": {IgnoreDots: true},
+	})
+
+	tests := []struct {
+		name      string
+		email     string
+		display   string
+		canonical string
+		domain    string
+	}{
+		{"plain address", "ada@example.com", "ada@example.com", "ada@example.com", "example.com"},
+		{"surrounding space and case", "  Ada.Lovelace@Example.COM ", "ada.lovelace@example.com", "ada.lovelace@example.com", "example.com"},
+		{"no rule keeps dots and plus", "a.b+tag@example.com", "a.b+tag@example.com", "a.b+tag@example.com", "example.com"},
+		{"gmail dots and plus", "J.Doe+news@gmail.com", "j.doe+news@gmail.com", "jdoe@gmail.com", "gmail.com"},
+		{"gmail plus at the start is kept", "+jdoe@gmail.com", "+jdoe@gmail.com", "+jdoe@gmail.com", "gmail.com"},
+		{"domain alias", "j.doe+x@GoogleMail.com", "j.doe+x@googlemail.com", "jdoe@gmail.com", "googlemail.com"},
+		{"plus only provider keeps dots", "j.doe+x@fastmail.com", "j.doe+x@fastmail.com", "j.doe@fastmail.com", "fastmail.com"},
+		{"unicode domain", "ada@Bücher.Example", "ada@bücher.example", "ada@xn--bcher-kva.example", "xn--bcher-kva.example"},
+		{"punycode domain displays as unicode", "ada@xn--bcher-kva.example", "ada@bücher.example", "ada@xn--bcher-kva.example", "xn--bcher-kva.example"},
+		{"rule keyed by unicode domain", "a.da@xn--bcher-kva.example", "a.da@bücher.example", "ada@xn--bcher-kva.example", "xn--bcher-kva.example"},
+		{"NFC local part", "josé@example.com", "josé@example.com", "josé@example.com", "example.com"},
+		{"NFD input is composed", "jose\u0301@example.com", "josé@example.com", "josé@example.com", "example.com"},
+		{"last at sign splits", `"a@b"@example.com`, `"a@b"@example.com`, `"a@b"@example.com`, "example.com"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			addr, err := normalizer.Normalize(tc.email)
+			if err != nil {
+				t.Fatalf("Normalize(%q): %v", tc.email, err)
+			}
+			if addr.Display != tc.display || addr.Canonical != tc.canonical || addr.Domain != tc.domain {
+				t.Fatalf("Normalize(%q) = %+v, want display %q, canonical %q, domain %q",
+					tc.email, addr, tc.display, tc.canonical, tc.domain)
+			}
+		})
+	}
+}
+
+func TestNormalizeRejects(t *testing.T) {
+	normalizer := NewNormalizer(map[string]Rule{"gmail.com": {IgnoreDots: true, StripPlus: true}})
+
+	for _, email := range []string{
+		"",
+		"ada",
+		"@example.com",
+		"ada@",
+		"ada@localhost",
+		"ada@exa mple.com",
+		"ada@-example.com",
+		"...@gmail.com",
+	} {
+		t.Run(email, func(t *testing.T) {
+			if addr, err := normalizer.Normalize(email); !errors.Is(err, ErrInvalidAddress) {
+				t.Fatalf("Normalize(%q) = %+v, %v, want ErrInvalidAddress", email, addr, err)
+			}
+		})
+	}
+}
+
+func TestDomainSet(t *testing.T) {
+	set := NewDomainSet([]string{" Example.com ", "bücher.example", "", "bad domain..com"})
+
+	tests := []struct {
+		domain string
+		want   bool
+	}{
+		{"example.com", true},
+		{"mail.example.com", true},
+		{"deep.mail.example.com", true},
+		{"notexample.com", false},
+		{"example.org", false},
+		{"com", false},
+		{"xn--bcher-kva.example", true},
+		{"shop.xn--bcher-kva.example", true},
+	}
+	for _, tc := range tests {
+		if got := set.Contains(tc.domain); got != tc.want {
+			t.Errorf("Contains(%q) = %v, want %v", tc.domain, got, tc.want)
+		}
+	}
+	if len(set) != 2 {
+		t.Errorf("set = %v, want the invalid and empty entries skipped", set)
+	}
+}
+
+func TestReadDomainSet(t *testing.T) {
+	set, err := ReadDomainSet(strings.NewReader("# providers\n\nmailinator.com\n  yopmail.com  \n# example.org\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(set) != 2 || !set.Contains("mailinator.com") || !set.Contains("yopmail.com") || set.Contains("example.org") {
+		t.Fatalf("set = %v", set)
+	}
+}
+
+func TestDisposableDomains(t *testing.T) {
+	set := DisposableDomains()
+
+	if len(set) < 50 {
+		t.Fatalf("bundled list has %d domains", len(set))
+	}
+	for _, domain := range []string{"10minutemail.com", "mailinator.com", "eu.mailinator.com"} {
+		if !set.Contains(domain) {
+			t.Errorf("%s not listed as disposable", domain)
+		}
+	}
+	for _, domain := range []string{"gmail.com", "example.com"} {
+		if set.Contains(domain) {
+			t.Errorf("%s listed as disposable", domain)
+		}
+	}
+	for domain := range set {
+		if domain != strings.ToLower(domain) || strings.HasPrefix(domain, "#") || strings.ContainsAny(domain, " \t") {
+			t.Errorf("malformed entry %q", domain)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+// Package events publishes domain events to message brokers as CloudEvents.
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// SpecVersion is the CloudEvents specification version of the envelope
+const SpecVersion = "1.0"
+
+// ContentType is the media type of a structured-mode CloudEvents JSON message
+const ContentType = "application/cloudevents+json"
+
+// Event is a CloudEvents 1.0 envelope in JSON structured mode
+type Event struct {
+	SpecVersion     string          `json:"specversion"`
+	ID              string          `json:"id"`
+	Source          string          `json:"source"`
+	Type            string          `json:"type"`
+	Subject         string          `json:"subject,omitempty"`
+	Time            time.Time       `json:"time"`
+	DataContentType string          `json:"datacontenttype"`
+	Data            json.RawMessage `json:"data"`
+}
+
+// EventPublisher hands events to a broker. Publish returns once the broker has accepted the event,
+// so callers can retry on error; consumers must therefore tolerate duplicates, using the event ID.
+type EventPublisher interface {
+	Publish(ctx context.Context, event Event) error
+	Close() error
+}
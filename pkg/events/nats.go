@@ -0,0 +1,61 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// NATSPublisher publishes each event to "<prefix>.<event type>". With JetStream the server
+// acknowledges every message and drops duplicates by event ID within the stream's window;
+// without it, delivery is only as reliable as core NATS.
+type NATSPublisher struct {
+	conn   *nats.Conn
+	js     jetstream.JetStream
+	prefix string
+}
+
+func NewNATSPublisher(url, prefix string, useJetStream bool) (*NATSPublisher, error) {
+	conn, err := nats.Connect(url, nats.Name("user-management"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to nats: %w", err)
+	}
+
+	publisher := &NATSPublisher{conn: conn, prefix: prefix}
+	if useJetStream {
+		if publisher.js, err = jetstream.New(conn); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("failed to initialize jetstream: %w", err)
+		}
+	}
+	return publisher, nil
+}
+
+func (p *NATSPublisher) Publish(ctx context.Context, event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	msg := nats.NewMsg(p.prefix + "." + event.Type)
+	msg.Data = body
+	msg.Header.Set("Content-Type", ContentType)
+
+	if p.js != nil {
+		_, err = p.js.PublishMsg(ctx, msg, jetstream.WithMsgID(event.ID))
+		return err
+	}
+
+	if err := p.conn.PublishMsg(msg); err != nil {
+		return err
+	}
+	// Wait for the server to have received the message
+	return p.conn.FlushWithContext(ctx)
+}
+
+func (p *NATSPublisher) Close() error {
+	return p.conn.Drain()
+}
@@ -0,0 +1,144 @@
+// Package health runs the dependency checks behind the liveness and readiness probes.
+package health
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	StatusUp           = "up"
+	StatusDown         = "down"
+	StatusShuttingDown = "shutting_down"
+)
+
+// Checker verifies that one dependency is usable. Check must respect ctx cancellation.
+type Checker interface {
+	Name() string
+	Check(ctx context.Context) error
+}
+
+type checkerFunc struct {
+	name  string
+	check func(ctx context.Context) error
+}
+
+func (c checkerFunc) Name() string                    { return c.name }
+func (c checkerFunc) Check(ctx context.Context) error { return c.check(ctx) }
+
+// CheckerFunc adapts a function to the Checker interface
+func CheckerFunc(name string, check func(ctx context.Context) error) Checker {
+	return checkerFunc{name: name, check: check}
+}
+
+// Component is the result of a single check
+type Component struct {
+	Status     string `json:"status"`
+	DurationMS int64  `json:"duration_ms"`
+	Error      string `json:"error,omitempty"`
+}
+
+// Report is the readiness of the service and each of its dependencies
+type Report struct {
+	Status     string               `json:"status"`
+	Components map[string]Component `json:"components,omitempty"`
+}
+
+// Ready reports whether the service should receive traffic
+func (r Report) Ready() bool {
+	return r.Status == StatusUp
+}
+
+type registration struct {
+	checker Checker
+	timeout time.Duration
+}
+
+// Registry holds the readiness checks and the shutdown state
+type Registry struct {
+	mu             sync.RWMutex
+	checks         []registration
+	defaultTimeout time.Duration
+	shuttingDown   atomic.Bool
+	// OnFailure is called for every failed check, e.g. to log the full error
+	OnFailure func(name string, err error)
+}
+
+func NewRegistry(defaultTimeout time.Duration) *Registry {
+	return &Registry{defaultTimeout: defaultTimeout}
+}
+
+// Register adds a readiness check that runs under the default timeout
+func (r *Registry) Register(checker Checker) {
+	r.RegisterWithTimeout(checker, r.defaultTimeout)
+}
+
+// RegisterWithTimeout adds a readiness check with its own timeout
+func (r *Registry) RegisterWithTimeout(checker Checker, timeout time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checks = append(r.checks, registration{checker: checker, timeout: timeout})
+}
+
+// SetShuttingDown makes every following readiness check fail without running the checks
+func (r *Registry) SetShuttingDown() {
+	r.shuttingDown.Store(true)
+}
+
+// Check runs all checks concurrently, each under its own timeout
+func (r *Registry) Check(ctx context.Context) Report {
+	if r.shuttingDown.Load() {
+		return Report{Status: StatusShuttingDown}
+	}
+
+	r.mu.RLock()
+	checks := append([]registration(nil), r.checks...)
+	r.mu.RUnlock()
+
+	results := make([]Component, len(checks))
+	var wg sync.WaitGroup
+	for i, reg := range checks {
+		wg.Add(1)
+		go func(i int, reg registration) {
+			defer wg.Done()
+			results[i] = r.run(ctx, reg)
+		}(i, reg)
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusUp, Components: make(map[string]Component, len(checks))}
+	for i, reg := range checks {
+		report.Components[reg.checker.Name()] = results[i]
+		if results[i].Status != StatusUp {
+			report.Status = StatusDown
+		}
+	}
+	return report
+}
+
+func (r *Registry) run(ctx context.Context, reg registration) Component {
+	ctx, cancel := context.WithTimeout(ctx, reg.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := reg.checker.Check(ctx)
+	component := Component{Status: StatusUp, DurationMS: time.Since(start).Milliseconds()}
+	if err == nil {
+		return component
+	}
+
+	if r.OnFailure != nil {
+		r.OnFailure(reg.checker.Name(), err)
+	}
+
+	// Errors may name hosts or credentials, so only the kind of failure is reported
+	component.Status = StatusDown
+	component.Error = "check failed"
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		component.Error = "timed out"
+	}
+	return component
+}
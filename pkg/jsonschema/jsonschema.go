@@ -0,0 +1,423 @@
+// Package jsonschema validates JSON values against a subset of JSON Schema
+// (draft 2020-12). Supported keywords:
+//
+//	type, enum, const,
+//	minLength, maxLength, pattern, format (email, uri, uuid, date, date-time),
+//	minimum, maximum, exclusiveMinimum, exclusiveMaximum,
+//	items, minItems, maxItems, uniqueItems,
+//	properties, required, additionalProperties,
+//	title, description, default, examples (annotations only)
+//
+// Unsupported keywords are rejected when compiling so a schema never silently
+// accepts more than its author intended.
+package jsonschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var validTypes = map[string]bool{
+	"string": true, "number": true, "integer": true, "boolean": true,
+	"array": true, "object": true, "null": true,
+}
+
+var annotations = map[string]bool{
+	"$schema": true, "title": true, "description": true, "default": true, "examples": true,
+}
+
+// Schema is a compiled schema
+type Schema struct {
+	types                []string
+	enum                 []interface{}
+	constant             interface{}
+	hasConst             bool
+	minLength, maxLength *int
+	pattern              *regexp.Regexp
+	format               string
+	minimum, maximum     *float64
+	exclusiveMinimum     *float64
+	exclusiveMaximum     *float64
+	items                *Schema
+	minItems, maxItems   *int
+	uniqueItems          bool
+	properties           map[string]*Schema
+	required             []string
+	additionalProperties *Schema
+	noAdditional         bool
+}
+
+// ValidationError describes why a value does not match the schema
+type ValidationError struct {
+	Path    string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	if e.Path == "" {
+		return e.Message
+	}
+	return e.Path + ": " + e.Message
+}
+
+// Compile parses a decoded JSON schema document
+func Compile(doc map[string]interface{}) (*Schema, error) {
+	return compile(doc, "")
+}
+
+func compile(doc map[string]interface{}, path string) (*Schema, error) {
+	s := &Schema{}
+
+	for key, raw := range doc {
+		var err error
+
+		switch key {
+		case "type":
+			s.types, err = compileTypes(raw)
+		case "enum":
+			values, ok := raw.([]interface{})
+			if !ok || len(values) == 0 {
+				err = fmt.Errorf("must be a non-empty array")
+			}
+			s.enum = values
+		case "const":
+			s.constant, s.hasConst = raw, true
+		case "minLength":
+			s.minLength, err = nonNegativeInt(raw)
+		case "maxLength":
+			s.maxLength, err = nonNegativeInt(raw)
+		case "pattern":
+			str, ok := raw.(string)
+			if !ok {
+				err = fmt.Errorf("must be a string")
+				break
+			}
+			s.pattern, err = regexp.Compile(str)
+		case "format":
+			str, _ := raw.(string)
+			switch str {
+			case "email", "uri", "uuid", "date", "date-time":
+				s.format = str
+			default:
+				err = fmt.Errorf("unsupported format %q", str)
+			}
+		case "minimum":
+			s.minimum, err = number(raw)
+		case "maximum":
+			s.maximum, err = number(raw)
+		case "exclusiveMinimum":
+			s.exclusiveMinimum, err = number(raw)
+		case "exclusiveMaximum":
+			s.exclusiveMaximum, err = number(raw)
+		case "items":
+			s.items, err = subschema(raw, path+"/items")
+		case "minItems":
+			s.minItems, err = nonNegativeInt(raw)
+		case "maxItems":
+			s.maxItems, err = nonNegativeInt(raw)
+		case "uniqueItems":
+			unique, ok := raw.(bool)
+			if !ok {
+				err = fmt.Errorf("must be a boolean")
+			}
+			s.uniqueItems = unique
+		case "properties":
+			props, ok := raw.(map[string]interface{})
+			if !ok {
+				err = fmt.Errorf("must be an object")
+				break
+			}
+			s.properties = make(map[string]*Schema, len(props))
+			for name, prop := range props {
+				if s.properties[name], err = subschema(prop, path+"/properties/"+name); err != nil {
+					return nil, err
+				}
+			}
+		case "required":
+			s.required, err = stringList(raw)
+		case "additionalProperties":
+			if allowed, ok := raw.(bool); ok {
+				s.noAdditional = !allowed
+				break
+			}
+			s.additionalProperties, err = subschema(raw, path+"/additionalProperties")
+		default:
+			if !annotations[key] {
+				err = fmt.Errorf("unsupported keyword")
+			}
+		}
+
+		if err != nil {
+			if _, nested := err.(*compileError); nested {
+				return nil, err
+			}
+			return nil, &compileError{path: path + "/" + key, err: err}
+		}
+	}
+
+	return s, nil
+}
+
+type compileError struct {
+	path string
+	err  error
+}
+
+func (e *compileError) Error() string {
+	return fmt.Sprintf("invalid schema at %s: %v", e.path, e.err)
+}
+
+// Validate checks a value decoded with encoding/json against the schema
+func (s *Schema) Validate(value interface{}) error {
+	return s.validate(value, "")
+}
+
+func (s *Schema) validate(value interface{}, path string) error {
+	fail := func(format string, args ...interface{}) error {
+		return &ValidationError{Path: path, Message: fmt.Sprintf(format, args...)}
+	}
+
+	if len(s.types) > 0 && !s.matchesType(value) {
+		return fail("expected %s", strings.Join(s.types, " or "))
+	}
+
+	if s.hasConst && !reflect.DeepEqual(value, s.constant) {
+		return fail("must be %v", s.constant)
+	}
+
+	if len(s.enum) > 0 {
+		found := false
+		for _, allowed := range s.enum {
+			if reflect.DeepEqual(value, allowed) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fail("must be one of %v", s.enum)
+		}
+	}
+
+	switch v := value.(type) {
+	case string:
+		length := len([]rune(v))
+		if s.minLength != nil && length < *s.minLength {
+			return fail("must be at least %d characters", *s.minLength)
+		}
+		if s.maxLength != nil && length > *s.maxLength {
+			return fail("must be at most %d characters", *s.maxLength)
+		}
+		if s.pattern != nil && !s.pattern.MatchString(v) {
+			return fail("must match %s", s.pattern.String())
+		}
+		if s.format != "" && !validFormat(s.format, v) {
+			return fail("must be a valid %s", s.format)
+		}
+
+	case float64:
+		if s.minimum != nil && v < *s.minimum {
+			return fail("must be >= %v", *s.minimum)
+		}
+		if s.maximum != nil && v > *s.maximum {
+			return fail("must be <= %v", *s.maximum)
+		}
+		if s.exclusiveMinimum != nil && v <= *s.exclusiveMinimum {
+			return fail("must be > %v", *s.exclusiveMinimum)
+		}
+		if s.exclusiveMaximum != nil && v >= *s.exclusiveMaximum {
+			return fail("must be < %v", *s.exclusiveMaximum)
+		}
+
+	case []interface{}:
+		if s.minItems != nil && len(v) < *s.minItems {
+			return fail("must have at least %d items", *s.minItems)
+		}
+		if s.maxItems != nil && len(v) > *s.maxItems {
+			return fail("must have at most %d items", *s.maxItems)
+		}
+		for i, item := range v {
+			if s.uniqueItems {
+				for _, other := range v[:i] {
+					if reflect.DeepEqual(item, other) {
+						return fail("items must be unique")
+					}
+				}
+			}
+			if s.items != nil {
+				if err := s.items.validate(item, fmt.Sprintf("%s/%d", path, i)); err != nil {
+					return err
+				}
+			}
+		}
+
+	case map[string]interface{}:
+		for _, name := range s.required {
+			if _, ok := v[name]; !ok {
+				return fail("missing required property %q", name)
+			}
+		}
+
+		// Sorted so the reported error is deterministic
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			propPath := path + "/" + name
+			if prop, ok := s.properties[name]; ok {
+				if err := prop.validate(v[name], propPath); err != nil {
+					return err
+				}
+				continue
+			}
+			if s.noAdditional {
+				return &ValidationError{Path: propPath, Message: "property is not allowed"}
+			}
+			if s.additionalProperties != nil {
+				if err := s.additionalProperties.validate(v[name], propPath); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *Schema) matchesType(value interface{}) bool {
+	for _, t := range s.types {
+		switch v := value.(type) {
+		case nil:
+			if t == "null" {
+				return true
+			}
+		case bool:
+			if t == "boolean" {
+				return true
+			}
+		case string:
+			if t == "string" {
+				return true
+			}
+		case float64:
+			if t == "number" || (t == "integer" && v == math.Trunc(v)) {
+				return true
+			}
+		case []interface{}:
+			if t == "array" {
+				return true
+			}
+		case map[string]interface{}:
+			if t == "object" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Types returns the declared types of the schema, if any
+func (s *Schema) Types() []string {
+	return s.types
+}
+
+func validFormat(format, value string) bool {
+	switch format {
+	case "email":
+		addr, err := mail.ParseAddress(value)
+		return err == nil && addr.Address == value
+	case "uri":
+		u, err := url.Parse(value)
+		return err == nil && u.Scheme != ""
+	case "uuid":
+		_, err := uuid.Parse(value)
+		return err == nil
+	case "date":
+		_, err := time.Parse("2006-01-02", value)
+		return err == nil
+	case "date-time":
+		_, err := time.Parse(time.RFC3339, value)
+		return err == nil
+	}
+	return true
+}
+
+func compileTypes(raw interface{}) ([]string, error) {
+	var types []string
+	switch v := raw.(type) {
+	case string:
+		types = []string{v}
+	case []interface{}:
+		list, err := stringList(v)
+		if err != nil {
+			return nil, err
+		}
+		types = list
+	default:
+		return nil, fmt.Errorf("must be a string or an array of strings")
+	}
+
+	for _, t := range types {
+		if !validTypes[t] {
+			return nil, fmt.Errorf("unknown type %q", t)
+		}
+	}
+	return types, nil
+}
+
+func subschema(raw interface{}, path string) (*Schema, error) {
+	doc, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, &compileError{path: path, err: fmt.Errorf("must be an object")}
+	}
+	return compile(doc, path)
+}
+
+func number(raw interface{}) (*float64, error) {
+	switch v := raw.(type) {
+	case float64:
+		return &v, nil
+	case json.Number:
+		f, err := v.Float64()
+		return &f, err
+	}
+	return nil, fmt.Errorf("must be a number")
+}
+
+func nonNegativeInt(raw interface{}) (*int, error) {
+	f, err := number(raw)
+	if err != nil || *f < 0 || *f != math.Trunc(*f) {
+		return nil, fmt.Errorf("must be a non-negative integer")
+	}
+	n := int(*f)
+	return &n, nil
+}
+
+func stringList(raw interface{}) ([]string, error) {
+	values, ok := raw.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("must be an array of strings")
+	}
+
+	list := make([]string, 0, len(values))
+	for _, value := range values {
+		str, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("must be an array of strings")
+		}
+		list = append(list, str)
+	}
+	return list, nil
+}
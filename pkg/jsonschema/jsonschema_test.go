@@ -0,0 +1,180 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func decode(t *testing.T, raw string) interface{} {
+	t.Helper()
+	var value interface{}
+	if err := json.Unmarshal([]byte(raw), &value); err != nil {
+		t.Fatalf("decode %s: %v", raw, err)
+	}
+	return value
+}
+
+func compileJSON(t *testing.T, raw string) (*Schema, error) {
+	t.Helper()
+	doc, ok := decode(t, raw).(map[string]interface{})
+	if !ok {
+		t.Fatalf("schema %s is not an object", raw)
+	}
+	return Compile(doc)
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+		value  string
+		valid  bool
+	}{
+		{"type string", `{"type": "string"}`, `"a"`, true},
+		{"type string rejects number", `{"type": "string"}`, `1`, false},
+		{"type integer", `{"type": "integer"}`, `3`, true},
+		{"type integer rejects fraction", `{"type": "integer"}`, `3.5`, false},
+		{"type number accepts integer", `{"type": "number"}`, `3`, true},
+		{"type boolean", `{"type": "boolean"}`, `false`, true},
+		{"type null", `{"type": "null"}`, `null`, true},
+		{"type array rejects object", `{"type": "array"}`, `{}`, false},
+		{"type list", `{"type": ["string", "null"]}`, `null`, true},
+		{"type list rejects other", `{"type": ["string", "null"]}`, `true`, false},
+		{"no type accepts anything", `{}`, `{"a": [1]}`, true},
+
+		{"enum", `{"enum": ["red", "green", 1]}`, `"green"`, true},
+		{"enum number", `{"enum": ["red", "green", 1]}`, `1`, true},
+		{"enum rejects other", `{"enum": ["red", "green"]}`, `"blue"`, false},
+		{"enum is case sensitive", `{"enum": ["red"]}`, `"Red"`, false},
+		{"const", `{"const": {"a": 1}}`, `{"a": 1}`, true},
+		{"const rejects other", `{"const": {"a": 1}}`, `{"a": 2}`, false},
+
+		{"minLength", `{"minLength": 2}`, `"ab"`, true},
+		{"minLength rejects short", `{"minLength": 2}`, `"a"`, false},
+		{"maxLength counts runes", `{"maxLength": 2}`, `"äö"`, true},
+		{"maxLength rejects long", `{"maxLength": 2}`, `"abc"`, false},
+		{"pattern", `{"pattern": "^[A-Z]{2}$"}`, `"GB"`, true},
+		{"pattern rejects mismatch", `{"pattern": "^[A-Z]{2}$"}`, `"GBR"`, false},
+		{"pattern is unanchored", `{"pattern": "[0-9]"}`, `"ab1"`, true},
+		{"length keywords ignore other types", `{"minLength": 5}`, `1`, true},
+
+		{"format email", `{"format": "email"}`, `"ada@example.com"`, true},
+		{"format email rejects display name", `{"format": "email"}`, `"Ada <ada@example.com>"`, false},
+		{"format uri", `{"format": "uri"}`, `"https://example.com/x"`, true},
+		{"format uri requires scheme", `{"format": "uri"}`, `"example.com/x"`, false},
+		{"format uuid", `{"format": "uuid"}`, `"6ba7b810-9dad-11d1-80b4-00c04fd430c8"`, true},
+		{"format uuid rejects other", `{"format": "uuid"}`, `"not-a-uuid"`, false},
+		{"format date", `{"format": "date"}`, `"2024-02-29"`, true},
+		{"format date rejects invalid day", `{"format": "date"}`, `"2023-02-29"`, false},
+		{"format date-time", `{"format": "date-time"}`, `"2024-01-02T03:04:05Z"`, true},
+		{"format date-time requires zone", `{"format": "date-time"}`, `"2024-01-02T03:04:05"`, false},
+
+		{"minimum inclusive", `{"minimum": 1}`, `1`, true},
+		{"minimum rejects below", `{"minimum": 1}`, `0.5`, false},
+		{"maximum inclusive", `{"maximum": 10}`, `10`, true},
+		{"maximum rejects above", `{"maximum": 10}`, `10.1`, false},
+		{"exclusiveMinimum rejects bound", `{"exclusiveMinimum": 0}`, `0`, false},
+		{"exclusiveMinimum", `{"exclusiveMinimum": 0}`, `0.1`, true},
+		{"exclusiveMaximum rejects bound", `{"exclusiveMaximum": 5}`, `5`, false},
+		{"exclusiveMaximum", `{"exclusiveMaximum": 5}`, `4.9`, true},
+
+		{"items", `{"items": {"type": "string"}}`, `["a", "b"]`, true},
+		{"items rejects bad item", `{"items": {"type": "string"}}`, `["a", 2]`, false},
+		{"minItems", `{"minItems": 1}`, `[]`, false},
+		{"maxItems", `{"maxItems": 2}`, `[1, 2, 3]`, false},
+		{"uniqueItems", `{"uniqueItems": true}`, `[1, 2, 3]`, true},
+		{"uniqueItems rejects duplicates", `{"uniqueItems": true}`, `[{"a": 1}, {"a": 1}]`, false},
+		{"uniqueItems false allows duplicates", `{"uniqueItems": false}`, `[1, 1]`, true},
+
+		{"required", `{"required": ["a"]}`, `{"a": null}`, true},
+		{"required rejects missing", `{"required": ["a", "b"]}`, `{"a": 1}`, false},
+		{"properties", `{"properties": {"a": {"type": "integer"}}}`, `{"a": 1, "b": "x"}`, true},
+		{"properties rejects bad property", `{"properties": {"a": {"type": "integer"}}}`, `{"a": "1"}`, false},
+		{"additionalProperties false", `{"properties": {"a": {}}, "additionalProperties": false}`, `{"a": 1, "b": 2}`, false},
+		{"additionalProperties false allows declared", `{"properties": {"a": {}}, "additionalProperties": false}`, `{"a": 1}`, true},
+		{"additionalProperties schema", `{"additionalProperties": {"type": "number"}}`, `{"x": 1, "y": 2}`, true},
+		{"additionalProperties schema rejects", `{"additionalProperties": {"type": "number"}}`, `{"x": "1"}`, false},
+
+		{"nested object in array", `{"type": "array", "items": {"type": "object", "required": ["id"],
+			"properties": {"id": {"type": "integer", "minimum": 1}}}}`, `[{"id": 1}, {"id": 2}]`, true},
+		{"nested object in array rejects", `{"type": "array", "items": {"type": "object", "required": ["id"],
+			"properties": {"id": {"type": "integer", "minimum": 1}}}}`, `[{"id": 1}, {"id": 0}]`, false},
+		{"nested array in object", `{"properties": {"tags": {"type": "array", "items": {"enum": ["a", "b"]}}}}`,
+			`{"tags": ["a", "c"]}`, false},
+		{"annotations are ignored", `{"title": "T", "description": "D", "default": 1, "examples": [1], "type": "integer"}`,
+			`1`, true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			schema, err := compileJSON(t, tc.schema)
+			if err != nil {
+				t.Fatalf("Compile: %v", err)
+			}
+			err = schema.Validate(decode(t, tc.value))
+			if tc.valid && err != nil {
+				t.Fatalf("Validate(%s) = %v, want valid", tc.value, err)
+			}
+			if !tc.valid {
+				var validationErr *ValidationError
+				if !errors.As(err, &validationErr) {
+					t.Fatalf("Validate(%s) = %v, want a ValidationError", tc.value, err)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateReportsPath(t *testing.T) {
+	schema, err := compileJSON(t, `{"properties": {"addresses": {"items": {"properties": {"zip": {"type": "string"}}}}}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = schema.Validate(decode(t, `{"addresses": [{"zip": "1"}, {"zip": 2}]}`))
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) || validationErr.Path != "/addresses/1/zip" {
+		t.Fatalf("err = %v, want a ValidationError at /addresses/1/zip", err)
+	}
+}
+
+func TestCompileRejectsInvalidSchemas(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+		path   string
+	}{
+		{"unknown keyword", `{"oneOf": []}`, "/oneOf"},
+		{"unknown type", `{"type": "date"}`, "/type"},
+		{"type of wrong kind", `{"type": 1}`, "/type"},
+		{"empty enum", `{"enum": []}`, "/enum"},
+		{"negative minLength", `{"minLength": -1}`, "/minLength"},
+		{"fractional maxItems", `{"maxItems": 1.5}`, "/maxItems"},
+		{"invalid pattern", `{"pattern": "("}`, "/pattern"},
+		{"unsupported format", `{"format": "ipv4"}`, "/format"},
+		{"non-numeric minimum", `{"minimum": "1"}`, "/minimum"},
+		{"non-boolean uniqueItems", `{"uniqueItems": "yes"}`, "/uniqueItems"},
+		{"required of wrong kind", `{"required": [1]}`, "/required"},
+		{"items not an object", `{"items": true}`, "/items"},
+		{"nested unknown keyword", `{"properties": {"a": {"items": {"anyOf": []}}}}`, "/properties/a/items/anyOf"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := compileJSON(t, tc.schema)
+			if err == nil || !strings.Contains(err.Error(), "at "+tc.path+":") {
+				t.Fatalf("Compile = %v, want an error at %s", err, tc.path)
+			}
+		})
+	}
+}
+
+func TestTypes(t *testing.T) {
+	schema, err := compileJSON(t, `{"type": ["integer", "null"]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if types := schema.Types(); len(types) != 2 || types[0] != "integer" || types[1] != "null" {
+		t.Fatalf("Types = %v", types)
+	}
+}
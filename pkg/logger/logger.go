@@ -1,9 +1,11 @@
 package logger
 
 import (
+	"context"
 	"os"
 
 	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type Logger struct {
@@ -37,5 +39,27 @@ func NewLogger(level, env string) *Logger {
 			Logger()
 	}
 
+	log = log.Hook(traceHook{})
+
+	// Returned by FromContext for contexts without a request-scoped logger
+	zerolog.DefaultContextLogger = &log
+
 	return &Logger{&log}
 }
+
+// FromContext returns the logger stored in ctx with WithContext, falling back to the application logger.
+// Within a request it carries the request ID, route and user ID.
+func FromContext(ctx context.Context) *Logger {
+	return &Logger{zerolog.Ctx(ctx)}
+}
+
+// traceHook adds the trace and span IDs to events logged with a context carrying a span, e.g. log.Info().Ctx(ctx)
+type traceHook struct{}
+
+func (traceHook) Run(e *zerolog.Event, _ zerolog.Level, _ string) {
+	spanContext := trace.SpanContextFromContext(e.GetCtx())
+	if !spanContext.IsValid() {
+		return
+	}
+	e.Str("trace_id", spanContext.TraceID().String()).Str("span_id", spanContext.SpanID().String())
+}
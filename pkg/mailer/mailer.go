@@ -0,0 +1,115 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+	"sync"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Sender delivers transactional email
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// SMTPSender delivers mail through an SMTP relay
+type SMTPSender struct {
+	addr string
+	auth smtp.Auth
+	from string
+}
+
+func NewSMTPSender(host, port, username, password, from string) *SMTPSender {
+	var auth smtp.Auth
+	if username != "" {
+		auth = smtp.PlainAuth("", username, password, host)
+	}
+
+	return &SMTPSender{
+		addr: net.JoinHostPort(host, port),
+		auth: auth,
+		from: from,
+	}
+}
+
+func (s *SMTPSender) Send(ctx context.Context, msg Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	// Reject header injection through the recipient or subject
+	if strings.ContainsAny(msg.To, "\r\n") || strings.ContainsAny(msg.Subject, "\r\n") {
+		return fmt.Errorf("invalid mail header")
+	}
+
+	body := fmt.Sprintf(
+		"From: %s\r\nTo: %s\r\nSubject: %s\r\nMIME-Version: 1.0\r\nContent-Type: text/plain; charset=UTF-8\r\n\r\n%s",
+		s.from, msg.To, msg.Subject, msg.Body,
+	)
+
+	if err := smtp.SendMail(s.addr, s.auth, s.from, []string{msg.To}, []byte(body)); err != nil {
+		return fmt.Errorf("failed to send mail: %w", err)
+	}
+	return nil
+}
+
+// DefaultMemoryCapacity is how many messages a MemorySender keeps
+const DefaultMemoryCapacity = 100
+
+// MemorySender keeps the most recent messages in memory, for tests and local development
+type MemorySender struct {
+	mu       sync.Mutex
+	capacity int
+	messages []Message
+}
+
+func NewMemorySender() *MemorySender {
+	return NewMemorySenderWithCapacity(DefaultMemoryCapacity)
+}
+
+// NewMemorySenderWithCapacity keeps at most capacity messages, dropping the oldest first
+func NewMemorySenderWithCapacity(capacity int) *MemorySender {
+	if capacity <= 0 {
+		capacity = DefaultMemoryCapacity
+	}
+	return &MemorySender{capacity: capacity}
+}
+
+func (s *MemorySender) Send(_ context.Context, msg Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.messages) >= s.capacity {
+		// Shift in place so a long-running process does not grow without bound
+		copy(s.messages, s.messages[1:])
+		s.messages = s.messages[:len(s.messages)-1]
+	}
+	s.messages = append(s.messages, msg)
+	return nil
+}
+
+// Messages returns a copy of the retained messages, oldest first
+func (s *MemorySender) Messages() []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Message(nil), s.messages...)
+}
+
+// Last returns the most recent message sent to the recipient
+func (s *MemorySender) Last(to string) (Message, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := len(s.messages) - 1; i >= 0; i-- {
+		if strings.EqualFold(s.messages[i].To, to) {
+			return s.messages[i], true
+		}
+	}
+	return Message{}, false
+}
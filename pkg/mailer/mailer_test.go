@@ -0,0 +1,71 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+func TestMemorySenderKeepsMostRecentMessages(t *testing.T) {
+	sender := NewMemorySenderWithCapacity(3)
+	ctx := context.Background()
+
+	for i := 1; i <= 5; i++ {
+		if err := sender.Send(ctx, Message{To: fmt.Sprintf("user%d@example.com", i), Subject: "hello"}); err != nil {
+			t.Fatalf("Send: %v", err)
+		}
+	}
+
+	messages := sender.Messages()
+	if len(messages) != 3 {
+		t.Fatalf("kept %d messages, want 3", len(messages))
+	}
+	for i, want := range []string{"user3@example.com", "user4@example.com", "user5@example.com"} {
+		if messages[i].To != want {
+			t.Errorf("messages[%d].To = %q, want %q", i, messages[i].To, want)
+		}
+	}
+	if _, ok := sender.Last("user1@example.com"); ok {
+		t.Error("evicted message still returned by Last")
+	}
+}
+
+func TestMemorySenderLast(t *testing.T) {
+	sender := NewMemorySender()
+	ctx := context.Background()
+
+	_ = sender.Send(ctx, Message{To: "ada@example.com", Subject: "first"})
+	_ = sender.Send(ctx, Message{To: "bob@example.com", Subject: "other"})
+	_ = sender.Send(ctx, Message{To: "ada@example.com", Subject: "second"})
+
+	msg, ok := sender.Last("ADA@example.com")
+	if !ok || msg.Subject != "second" {
+		t.Fatalf("Last = %+v, %v; want the second message", msg, ok)
+	}
+	if _, ok := sender.Last("carol@example.com"); ok {
+		t.Error("Last found a message for an unknown recipient")
+	}
+}
+
+func TestMemorySenderMessagesIsACopy(t *testing.T) {
+	sender := NewMemorySender()
+	_ = sender.Send(context.Background(), Message{To: "ada@example.com", Subject: "hello"})
+
+	sender.Messages()[0].Subject = "changed"
+	if got := sender.Messages()[0].Subject; got != "hello" {
+		t.Fatalf("stored message modified through Messages: %q", got)
+	}
+}
+
+func TestSMTPSenderRejectsHeaderInjection(t *testing.T) {
+	sender := NewSMTPSender("127.0.0.1", "1", "", "", "no-reply@example.com")
+
+	for _, msg := range []Message{
+		{To: "ada@example.com\r\nBcc: eve@example.com", Subject: "hello"},
+		{To: "ada@example.com", Subject: "hello\r\nBcc: eve@example.com"},
+	} {
+		if err := sender.Send(context.Background(), msg); err == nil || err.Error() != "invalid mail header" {
+			t.Errorf("Send(%q, %q) = %v, want invalid mail header", msg.To, msg.Subject, err)
+		}
+	}
+}
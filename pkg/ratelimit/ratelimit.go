@@ -0,0 +1,179 @@
+// Package ratelimit implements a sliding-window rate limiter.
+//
+// Requests are counted in fixed windows; the count of the previous window is weighted by how much of it
+// still overlaps the sliding window ending now. This approximates a true sliding log in two counters per key.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+	"user-management/pkg/cache"
+)
+
+// Rule allows Limit requests per Window
+type Rule struct {
+	Limit  int
+	Window time.Duration
+}
+
+// Decision is the outcome of a request against a rule
+type Decision struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is when the current window ends
+	Reset time.Duration
+	// RetryAfter is how long a denied client should wait
+	RetryAfter time.Duration
+}
+
+type Limiter interface {
+	// Allow counts a request for key and decides whether it is within the rule
+	Allow(ctx context.Context, key string, rule Rule) (Decision, error)
+}
+
+// decide turns the counters of the previous and current window into a decision
+func decide(rule Rule, prev, curr int64, elapsed time.Duration) Decision {
+	remainingWindow := rule.Window - elapsed
+	weight := float64(remainingWindow) / float64(rule.Window)
+	estimate := float64(prev)*weight + float64(curr)
+	limit := float64(rule.Limit)
+
+	decision := Decision{
+		Allowed:   estimate <= limit,
+		Limit:     rule.Limit,
+		Remaining: max(0, rule.Limit-int(math.Ceil(estimate))),
+		Reset:     remainingWindow,
+	}
+	if decision.Allowed {
+		return decision
+	}
+
+	// Find when the estimate drops back to the limit
+	if float64(curr) < limit && prev > 0 {
+		// Within the current window, as the previous one slides out
+		wait := float64(rule.Window)*(1-(limit-float64(curr))/float64(prev)) - float64(elapsed)
+		decision.RetryAfter = time.Duration(math.Max(wait, 0))
+	} else {
+		// In the next window, as the current one slides out
+		wait := float64(rule.Window) * (1 - (limit-1)/float64(curr))
+		decision.RetryAfter = remainingWindow + time.Duration(math.Max(wait, 0))
+	}
+	return decision
+}
+
+// CacheLimiter keeps its counters in the shared cache, so every instance enforces the same limits
+type CacheLimiter struct {
+	cache  cache.Cache
+	prefix string
+}
+
+func NewCacheLimiter(c cache.Cache) *CacheLimiter {
+	return &CacheLimiter{cache: c, prefix: "ratelimit"}
+}
+
+func (l *CacheLimiter) Allow(ctx context.Context, key string, rule Rule) (Decision, error) {
+	now := time.Now()
+	start := now.Truncate(rule.Window)
+
+	curr, err := l.cache.Increment(ctx, l.windowKey(key, start), 2*rule.Window)
+	if err != nil {
+		return Decision{}, fmt.Errorf("failed to count request: %w", err)
+	}
+
+	// A missing previous window simply counts as empty
+	var prev int64
+	if value, err := l.cache.Get(ctx, l.windowKey(key, start.Add(-rule.Window))); err == nil {
+		prev, _ = strconv.ParseInt(value, 10, 64)
+	}
+
+	return decide(rule, prev, curr, now.Sub(start)), nil
+}
+
+func (l *CacheLimiter) windowKey(key string, start time.Time) string {
+	return fmt.Sprintf("%s:%s:%d", l.prefix, key, start.Unix())
+}
+
+// MemoryLimiter keeps its counters in process. Limits apply per instance.
+type MemoryLimiter struct {
+	mu        sync.Mutex
+	windows   map[string]*memoryWindow
+	lastSweep time.Time
+}
+
+type memoryWindow struct {
+	start  time.Time
+	window time.Duration
+	prev   int64
+	curr   int64
+}
+
+func NewMemoryLimiter() *MemoryLimiter {
+	return &MemoryLimiter{windows: make(map[string]*memoryWindow)}
+}
+
+func (l *MemoryLimiter) Allow(_ context.Context, key string, rule Rule) (Decision, error) {
+	now := time.Now()
+	start := now.Truncate(rule.Window)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweep(now)
+
+	w, ok := l.windows[key]
+	switch {
+	case !ok || w.window != rule.Window || !start.Before(w.start.Add(2*rule.Window)):
+		w = &memoryWindow{start: start, window: rule.Window}
+		l.windows[key] = w
+	case start.After(w.start):
+		// The current window became the previous one
+		w.prev, w.curr, w.start = w.curr, 0, start
+	}
+	w.curr++
+
+	return decide(rule, w.prev, w.curr, now.Sub(start)), nil
+}
+
+// sweep drops counters that no longer affect any decision, at most once a minute
+func (l *MemoryLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < time.Minute {
+		return
+	}
+	l.lastSweep = now
+
+	for key, w := range l.windows {
+		if now.After(w.start.Add(2 * w.window)) {
+			delete(l.windows, key)
+		}
+	}
+}
+
+// FallbackLimiter uses the primary limiter and switches to the fallback for requests the primary
+// cannot decide, e.g. while Redis is unreachable
+type FallbackLimiter struct {
+	primary  Limiter
+	fallback Limiter
+	onError  func(err error)
+}
+
+// NewFallbackLimiter calls onError, which may be nil, whenever the primary limiter fails
+func NewFallbackLimiter(primary, fallback Limiter, onError func(err error)) *FallbackLimiter {
+	return &FallbackLimiter{primary: primary, fallback: fallback, onError: onError}
+}
+
+func (l *FallbackLimiter) Allow(ctx context.Context, key string, rule Rule) (Decision, error) {
+	decision, err := l.primary.Allow(ctx, key, rule)
+	if err == nil {
+		return decision, nil
+	}
+
+	if l.onError != nil {
+		l.onError(err)
+	}
+	return l.fallback.Allow(ctx, key, rule)
+}
@@ -0,0 +1,69 @@
+// Package sms delivers text messages through pluggable providers.
+package sms
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+	"user-management/pkg/webhook"
+)
+
+type Message struct {
+	// To is the recipient in E.164 format, e.g. +14155550100
+	To   string `json:"to"`
+	Body string `json:"body"`
+}
+
+// SMSSender delivers text messages
+type SMSSender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// ConsoleSender prints messages instead of delivering them, for local development
+type ConsoleSender struct {
+	mu  sync.Mutex
+	out io.Writer
+}
+
+func NewConsoleSender(out io.Writer) *ConsoleSender {
+	return &ConsoleSender{out: out}
+}
+
+func (s *ConsoleSender) Send(_ context.Context, msg Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err := fmt.Fprintf(s.out, "SMS to %s: %s\n", msg.To, msg.Body)
+	return err
+}
+
+// WebhookSender posts each message as JSON to an HTTP endpoint, which relays it to
+// the actual provider. When a secret is configured the request is signed as described
+// in package webhook.
+type WebhookSender struct {
+	url    string
+	secret []byte
+	client *webhook.Client
+}
+
+func NewWebhookSender(url, secret string, timeout time.Duration) *WebhookSender {
+	return &WebhookSender{
+		url:    url,
+		secret: []byte(secret),
+		client: webhook.NewClient(timeout),
+	}
+}
+
+func (s *WebhookSender) Send(ctx context.Context, msg Message) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.client.Post(ctx, s.url, s.secret, body, nil); err != nil {
+		return fmt.Errorf("failed to send sms: %w", err)
+	}
+	return nil
+}
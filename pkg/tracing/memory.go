@@ -0,0 +1,39 @@
+package tracing
+
+import (
+	"context"
+	"sync"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// MemoryCapacity is how many finished spans the memory exporter keeps
+const MemoryCapacity = 1000
+
+// memoryExporter keeps the most recent finished spans, for tests and local development
+type memoryExporter struct {
+	mu    sync.Mutex
+	spans []sdktrace.ReadOnlySpan
+}
+
+func (e *memoryExporter) ExportSpans(_ context.Context, spans []sdktrace.ReadOnlySpan) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = append(e.spans, spans...)
+	if overflow := len(e.spans) - MemoryCapacity; overflow > 0 {
+		// Shift in place so a long-running process does not grow without bound
+		copy(e.spans, e.spans[overflow:])
+		e.spans = e.spans[:MemoryCapacity]
+	}
+	return nil
+}
+
+func (e *memoryExporter) Shutdown(context.Context) error {
+	return nil
+}
+
+func (e *memoryExporter) finished() []sdktrace.ReadOnlySpan {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]sdktrace.ReadOnlySpan(nil), e.spans...)
+}
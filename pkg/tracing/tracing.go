@@ -0,0 +1,122 @@
+// Package tracing configures OpenTelemetry tracing with W3C trace context propagation.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Exporters supported by NewProvider
+const (
+	ExporterOTLP   = "otlp"
+	ExporterStdout = "stdout"
+	ExporterMemory = "memory"
+)
+
+type Options struct {
+	ServiceName    string
+	ServiceVersion string
+	Environment    string
+	// Exporter is otlp, stdout or memory
+	Exporter string
+	// Endpoint is the OTLP/HTTP collector URL; empty uses the OTEL_EXPORTER_OTLP_* environment variables
+	Endpoint string
+	// SampleRatio is the share of new traces recorded; traces started upstream follow the parent's decision
+	SampleRatio float64
+}
+
+type Provider struct {
+	provider *sdktrace.TracerProvider
+	memory   *memoryExporter
+}
+
+// NewProvider installs a global tracer provider and the W3C traceparent and baggage propagators
+func NewProvider(ctx context.Context, opts Options) (*Provider, error) {
+	p := &Provider{}
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch opts.Exporter {
+	case ExporterOTLP:
+		var clientOpts []otlptracehttp.Option
+		if opts.Endpoint != "" {
+			clientOpts = append(clientOpts, otlptracehttp.WithEndpointURL(opts.Endpoint))
+		}
+		exporter, err = otlptracehttp.New(ctx, clientOpts...)
+	case ExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout), stdouttrace.WithPrettyPrint())
+	case ExporterMemory:
+		p.memory = &memoryExporter{}
+		exporter = p.memory
+	default:
+		return nil, fmt.Errorf("unsupported trace exporter: %s", opts.Exporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s trace exporter: %w", opts.Exporter, err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL,
+		semconv.ServiceName(opts.ServiceName),
+		semconv.ServiceVersion(opts.ServiceVersion),
+		semconv.DeploymentEnvironmentNameKey.String(opts.Environment),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace resource: %w", err)
+	}
+
+	spanProcessor := sdktrace.WithBatcher(exporter)
+	if opts.Exporter == ExporterMemory {
+		// Spans are visible as soon as they end
+		spanProcessor = sdktrace.WithSyncer(exporter)
+	}
+
+	p.provider = sdktrace.NewTracerProvider(
+		spanProcessor,
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(opts.SampleRatio))),
+	)
+
+	otel.SetTracerProvider(p.provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	return p, nil
+}
+
+// Shutdown flushes pending spans and stops the exporter
+func (p *Provider) Shutdown(ctx context.Context) error {
+	return p.provider.Shutdown(ctx)
+}
+
+// FinishedSpans returns the most recent ended spans, oldest first, when the memory exporter is used.
+// Other exporters keep nothing, so it returns nil.
+func (p *Provider) FinishedSpans() []sdktrace.ReadOnlySpan {
+	if p.memory == nil {
+		return nil
+	}
+	return p.memory.finished()
+}
+
+// Tracer returns a named tracer from the global provider, a no-op until NewProvider is called
+func Tracer(name string) trace.Tracer {
+	return otel.Tracer(name)
+}
+
+// End records err on the span, if any, and ends it
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
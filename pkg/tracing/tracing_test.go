@@ -0,0 +1,103 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+func newMemoryProvider(t *testing.T) *Provider {
+	t.Helper()
+	provider, err := NewProvider(context.Background(), Options{
+		ServiceName: "user-management-test",
+		Exporter:    ExporterMemory,
+		SampleRatio: 1,
+	})
+	if err != nil {
+		t.Fatalf("NewProvider: %v", err)
+	}
+	t.Cleanup(func() { _ = provider.Shutdown(context.Background()) })
+	return provider
+}
+
+func TestMemoryExporterRecordsSpans(t *testing.T) {
+	provider := newMemoryProvider(t)
+
+	ctx, parent := Tracer("test").Start(context.Background(), "parent")
+	_, child := Tracer("test").Start(ctx, "child")
+	End(child, errors.New("boom"))
+	End(parent, nil)
+
+	spans := provider.FinishedSpans()
+	if len(spans) != 2 || spans[0].Name() != "child" || spans[1].Name() != "parent" {
+		t.Fatalf("spans = %v", spans)
+	}
+	if spans[0].Parent().SpanID() != spans[1].SpanContext().SpanID() {
+		t.Error("child is not linked to its parent")
+	}
+	if spans[0].Status().Code != codes.Error || spans[0].Status().Description != "boom" || len(spans[0].Events()) != 1 {
+		t.Errorf("child status = %+v, events = %d", spans[0].Status(), len(spans[0].Events()))
+	}
+	if spans[1].Status().Code != codes.Unset {
+		t.Errorf("parent status = %+v", spans[1].Status())
+	}
+}
+
+func TestMemoryExporterIsBounded(t *testing.T) {
+	exporter := &memoryExporter{}
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	tracer := provider.Tracer("test")
+
+	for i := 0; i < MemoryCapacity+10; i++ {
+		name := "old"
+		if i == MemoryCapacity+9 {
+			name = "newest"
+		}
+		_, span := tracer.Start(context.Background(), name)
+		span.End()
+	}
+
+	spans := exporter.finished()
+	if len(spans) != MemoryCapacity || spans[len(spans)-1].Name() != "newest" {
+		t.Fatalf("kept %d spans, last %q", len(spans), spans[len(spans)-1].Name())
+	}
+}
+
+func TestNewProviderPropagatesTraceContext(t *testing.T) {
+	newMemoryProvider(t)
+
+	ctx, span := Tracer("test").Start(context.Background(), "request")
+	defer span.End()
+
+	header := http.Header{}
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(header))
+	traceparent := header.Get("traceparent")
+	if !strings.Contains(traceparent, span.SpanContext().TraceID().String()) {
+		t.Fatalf("traceparent = %q", traceparent)
+	}
+}
+
+func TestFinishedSpansWithoutMemoryExporter(t *testing.T) {
+	provider, err := NewProvider(context.Background(), Options{Exporter: ExporterStdout, SampleRatio: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = provider.Shutdown(context.Background()) }()
+
+	if spans := provider.FinishedSpans(); spans != nil {
+		t.Fatalf("FinishedSpans = %v, want nil", spans)
+	}
+}
+
+func TestNewProviderRejectsUnknownExporter(t *testing.T) {
+	if _, err := NewProvider(context.Background(), Options{Exporter: "jaeger"}); err == nil {
+		t.Fatal("unknown exporter accepted")
+	}
+}
@@ -0,0 +1,161 @@
+// Package webhook signs JSON payloads and posts them to HTTP endpoints.
+//
+// A signed request carries
+//
+//	X-Signature-Timestamp: <unix seconds>
+//	X-Signature: sha256=<hex HMAC-SHA256 of "<timestamp>.<body>">
+//
+// so the receiver can reject forged or replayed requests.
+//
+// Clients never follow redirects. A client built with NewPublicClient also refuses to
+// connect to loopback, private, link-local and other non-public addresses, so endpoints
+// entered by users cannot reach internal services or cloud metadata.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+const (
+	SignatureHeader = "X-Signature"
+	TimestampHeader = "X-Signature-Timestamp"
+)
+
+// ErrNonPublicAddress is returned when a public client is asked to connect to a non-public address
+var ErrNonPublicAddress = errors.New("webhook address is not public")
+
+// Ranges not covered by the netip predicates used in PublicAddress
+var reservedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+	netip.MustParsePrefix("2001:db8::/32"),
+}
+
+// Sign returns the signature header value for a body sent at timestamp
+func Sign(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks a signature produced by Sign in constant time
+func Verify(secret []byte, timestamp string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
+
+// StatusError is returned for responses outside the 2xx range
+type StatusError struct {
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("webhook returned status %d", e.StatusCode)
+}
+
+// PublicAddress reports whether ip is a globally routable unicast address
+func PublicAddress(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	if !ip.IsGlobalUnicast() || ip.IsPrivate() {
+		return false
+	}
+	for _, prefix := range reservedPrefixes {
+		if prefix.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// Client posts signed JSON bodies
+type Client struct {
+	httpClient *http.Client
+}
+
+// NewClient returns a client for endpoints set by the operator, which may be internal
+func NewClient(timeout time.Duration) *Client {
+	return newClient(timeout, &net.Dialer{Timeout: timeout})
+}
+
+// NewPublicClient returns a client that only connects to public addresses. The check runs on
+// the resolved address of every connection, so DNS names pointing inward are refused as well.
+// Environment proxy settings are ignored, since the proxy would make the connection instead.
+func NewPublicClient(timeout time.Duration) *Client {
+	return newClient(timeout, &net.Dialer{Timeout: timeout, Control: publicOnly})
+}
+
+func newClient(timeout time.Duration, dialer *net.Dialer) *Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	if dialer.Control != nil {
+		transport.Proxy = nil
+	}
+
+	return &Client{httpClient: &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		// A redirect is reported as a non-2xx status rather than followed
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}}
+}
+
+func publicOnly(_, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrNonPublicAddress, address)
+	}
+	if !PublicAddress(addrPort.Addr()) {
+		return fmt.Errorf("%w: %s", ErrNonPublicAddress, addrPort.Addr())
+	}
+	return nil
+}
+
+// Post sends body to url, signing it when secret is set, and returns the response status code.
+// Non-2xx responses are reported as *StatusError.
+func (c *Client) Post(ctx context.Context, url string, secret []byte, body []byte, header http.Header) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("failed to build webhook request: %w", err)
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	if len(secret) > 0 {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set(TimestampHeader, timestamp)
+		req.Header.Set(SignatureHeader, Sign(secret, timestamp, body))
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to send webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, &StatusError{StatusCode: resp.StatusCode}
+	}
+	return resp.StatusCode, nil
+}
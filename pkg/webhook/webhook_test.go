@@ -0,0 +1,93 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+func TestPublicAddress(t *testing.T) {
+	tests := []struct {
+		ip     string
+		public bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:2800:220:1:248:1893:25c8:1946", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"fe80::1", false},
+		{"fd00:ec2::254", false},
+		{"100.64.0.1", false},
+		{"0.0.0.0", false},
+		{"::", false},
+		{"224.0.0.1", false},
+		{"255.255.255.255", false},
+		{"::ffff:127.0.0.1", false},
+		{"::ffff:169.254.169.254", false},
+		{"64:ff9b::a9fe:a9fe", false},
+	}
+	for _, tc := range tests {
+		if got := PublicAddress(netip.MustParseAddr(tc.ip)); got != tc.public {
+			t.Errorf("PublicAddress(%s) = %v, want %v", tc.ip, got, tc.public)
+		}
+	}
+}
+
+func TestPublicClientRefusesLoopback(t *testing.T) {
+	var called bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { called = true }))
+	defer server.Close()
+
+	_, err := NewPublicClient(time.Second).Post(context.Background(), server.URL, nil, []byte(`{}`), nil)
+	if !errors.Is(err, ErrNonPublicAddress) {
+		t.Fatalf("Post = %v, want ErrNonPublicAddress", err)
+	}
+	if called {
+		t.Fatal("request reached the loopback server")
+	}
+}
+
+func TestClientDoesNotFollowRedirects(t *testing.T) {
+	var followed bool
+	mux := http.NewServeMux()
+	mux.HandleFunc("/hook", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/internal", http.StatusTemporaryRedirect)
+	})
+	mux.HandleFunc("/internal", func(w http.ResponseWriter, r *http.Request) { followed = true })
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	status, err := NewClient(time.Second).Post(context.Background(), server.URL+"/hook", nil, []byte(`{}`), nil)
+	var statusErr *StatusError
+	if status != http.StatusTemporaryRedirect || !errors.As(err, &statusErr) {
+		t.Fatalf("Post = %d, %v, want a 307 StatusError", status, err)
+	}
+	if followed {
+		t.Fatal("redirect was followed")
+	}
+}
+
+func TestClientSignsRequests(t *testing.T) {
+	secret := []byte("secret")
+	var verified bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		verified = Verify(secret, r.Header.Get(TimestampHeader), []byte(`{"a":1}`), r.Header.Get(SignatureHeader))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	if _, err := NewClient(time.Second).Post(context.Background(), server.URL, secret, []byte(`{"a":1}`), nil); err != nil {
+		t.Fatal(err)
+	}
+	if !verified {
+		t.Fatal("signature did not verify")
+	}
+}
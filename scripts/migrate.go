@@ -1,12 +1,15 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"log"
 	"os"
 
 	"user-management/internal/config"
+	"user-management/internal/repository"
+	"user-management/internal/service"
 	"user-management/pkg/database"
 
 	"github.com/pressly/goose/v3"
@@ -18,7 +21,7 @@ func main() {
 		step    int64
 	)
 
-	flag.StringVar(&command, "command", "up", "Migration command (up, down, status, create, reset)")
+	flag.StringVar(&command, "command", "up", "Migration command (up, down, status, create, reset, canonical-emails)")
 	flag.Int64Var(&step, "step", 0, "Number of migrations to apply/rollback")
 	flag.Parse()
 
@@ -114,8 +117,25 @@ func main() {
 		}
 		log.Println("✅ Database reset successfully")
 
+	case "canonical-emails":
+		emails, err := service.NewEmailPolicy(&cfg.Email)
+		if err != nil {
+			log.Fatal(err)
+		}
+		report, err := service.BackfillCanonicalEmails(context.Background(), repository.NewUserRepository(db.DB), emails)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if len(report.Collisions) > 0 {
+			for _, collision := range report.Collisions {
+				log.Printf("%s is shared by %v\n", collision.CanonicalEmail, collision.UserIDs)
+			}
+			log.Fatalf("❌ %d canonical emails are shared by several accounts; merge or rename them and run again", len(report.Collisions))
+		}
+		log.Printf("✅ Canonical emails updated for %d of %d accounts; uniqueness now uses canonical_email\n", report.Updated, report.Scanned)
+
 	default:
-		fmt.Println("Available commands: up, down, status, create, reset")
+		fmt.Println("Available commands: up, down, status, create, reset, canonical-emails")
 		os.Exit(1)
 	}
 }